@@ -1,11 +1,20 @@
+# Requests need an access token or API key (see POST /auth/keys), e.g. from POST /auth/login:
+# export TOKEN=$(curl -s -X POST http://localhost:8080/auth/login -d '{"email": "...", "password": "..."}' | jq -r .token)
+
+# Quizzes continue in sessions, whose history is kept by the server. Start one and keep its id:
+SESSION=$(curl -s -X POST http://localhost:8080/quiz/sessions \
+    -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
+    -d '{}' | jq -r .id)
+
 # incorrect
-curl -X POST http://localhost:8080/quiz/generate \
+curl -X POST http://localhost:8080/quiz/sessions/$SESSION/messages \
     -H "Content-Type: application/json" \
-    -d '{"messages": [{"role": "assistant", "content": "**Quiz Question:**\n\nWhich significant event in English history occurred in 1215 and is considered a foundational document limiting the powers of the monarchy?\n\nA) The signing of the Magna Carta  \nB) The execution of King Charles I  \nC) The establishment of the Commonwealth  \nD) The defeat of the Spanish Armada  \n\n**Correct Answer:** A) The signing of the Magna Carta"}, {"role": "user", "content": "B) The execution of King Charles I"}]}' | jq
+    -H "Authorization: Bearer $TOKEN" \
+    -d '{"content": "The execution of King Charles I"}' | jq
 
 # correct
-  curl -X POST http://localhost:8080/quiz/generate \
+curl -X POST http://localhost:8080/quiz/sessions/$SESSION/messages \
     -H "Content-Type: application/json" \
-    -d '{"messages": [{"role": "assistant", "content": "**Quiz Question:**\n\nWhich significant event in English history occurred in 1215 and is considered a foundational document limiting the powers of the monarchy?\n\nA) The signing of the Magna Carta  \nB) The execution of King Charles I  \nC) The establishment of the Commonwealth  \nD) The defeat of the Spanish Armada  \n\n**Correct Answer:** A) The signing of the Magna Carta"}, {"role": "user", "content": "A) The signing of the Magna Carta"}]}' | jq
-
-
+    -H "Authorization: Bearer $TOKEN" \
+    -d '{"content": "The signing of the Magna Carta"}' | jq
@@ -0,0 +1,94 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors the db and services packages report. Handlers check for
+// them with errors.Is to pick a response, instead of parsing messages.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrValidation    = errors.New("validation failed")
+	ErrConflict      = errors.New("conflict")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrPreconditionFailed is a conditional write of an item that has
+	// changed since the client read it.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUpstream is a failure of a service the server depends on, such as
+	// the LLM provider.
+	ErrUpstream = errors.New("upstream failure")
+)
+
+// FieldError describes what is wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error of a known kind. Message is meant for clients, while Err
+// holds the underlying cause, if any, for logs.
+type Error struct {
+	Kind    error
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind error, format string, args []any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...any) error {
+	return newError(ErrNotFound, format, args)
+}
+
+func Conflict(format string, args ...any) error {
+	return newError(ErrConflict, format, args)
+}
+
+func Unauthorized(format string, args ...any) error {
+	return newError(ErrUnauthorized, format, args)
+}
+
+func QuotaExceeded(format string, args ...any) error {
+	return newError(ErrQuotaExceeded, format, args)
+}
+
+func PreconditionFailed(format string, args ...any) error {
+	return newError(ErrPreconditionFailed, format, args)
+}
+
+// Validation reports an invalid request. field names the offending field as
+// it appears in the request, or is empty when the problem is not with one
+// field.
+func Validation(field, format string, args ...any) error {
+	err := newError(ErrValidation, format, args)
+	if field != "" {
+		err.Fields = []FieldError{{Field: field, Message: err.Message}}
+	}
+	return err
+}
+
+// Upstream wraps err, returned by a service the server depends on.
+func Upstream(err error, format string, args ...any) error {
+	wrapped := newError(ErrUpstream, format, args)
+	wrapped.Err = err
+	return wrapped
+}
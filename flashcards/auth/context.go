@@ -0,0 +1,37 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+type apiKeyContextKey struct{}
+
+// WithUserID returns a copy of ctx that carries the id of the signed in user.
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// UserID returns the id stored by WithUserID.
+func UserID(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(contextKey{}).(int)
+	return userID, ok
+}
+
+// apiKeyContext describes the API key a request was authenticated with.
+type apiKeyContext struct {
+	id     int
+	scopes []string
+}
+
+// WithAPIKey returns a copy of ctx for a request authenticated with the API
+// key id, which was granted scopes.
+func WithAPIKey(ctx context.Context, id int, scopes []string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKeyContext{id: id, scopes: scopes})
+}
+
+// APIKey returns the key stored by WithAPIKey. ok is false for requests
+// signed in with an access token, which may do anything their user may.
+func APIKey(ctx context.Context) (id int, scopes []string, ok bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(apiKeyContext)
+	return key.id, key.scopes, ok
+}
@@ -0,0 +1,76 @@
+package auth
+
+import "strings"
+
+// Scopes an API key can be granted. Each resource has a read and a write
+// scope, "<resource>:*" grants both. Quizzes are split by whether they call
+// the LLM.
+const (
+	ScopeNotesRead       = "notes:read"
+	ScopeNotesWrite      = "notes:write"
+	ScopeTodosRead       = "todos:read"
+	ScopeTodosWrite      = "todos:write"
+	ScopeTagsRead        = "tags:read"
+	ScopeTagsWrite       = "tags:write"
+	ScopeDecksRead       = "decks:read"
+	ScopeDecksWrite      = "decks:write"
+	ScopeFlashcardsRead  = "flashcards:read"
+	ScopeFlashcardsWrite = "flashcards:write"
+	ScopeReviewsRead     = "reviews:read"
+	ScopeReviewsWrite    = "reviews:write"
+	ScopeQuizRead        = "quiz:read"
+	ScopeQuizGenerate    = "quiz:generate"
+)
+
+const (
+	scopeSeparator = ":"
+	scopeWildcard  = scopeSeparator + "*"
+)
+
+var knownScopes = map[string]bool{
+	ScopeNotesRead:       true,
+	ScopeNotesWrite:      true,
+	ScopeTodosRead:       true,
+	ScopeTodosWrite:      true,
+	ScopeTagsRead:        true,
+	ScopeTagsWrite:       true,
+	ScopeDecksRead:       true,
+	ScopeDecksWrite:      true,
+	ScopeFlashcardsRead:  true,
+	ScopeFlashcardsWrite: true,
+	ScopeReviewsRead:     true,
+	ScopeReviewsWrite:    true,
+	ScopeQuizRead:        true,
+	ScopeQuizGenerate:    true,
+}
+
+// ValidScope reports whether scope is a known scope or a wildcard over a
+// known resource.
+func ValidScope(scope string) bool {
+	if knownScopes[scope] {
+		return true
+	}
+
+	resource, found := strings.CutSuffix(scope, scopeWildcard)
+	if !found {
+		return false
+	}
+	for known := range knownScopes {
+		if strings.HasPrefix(known, resource+scopeSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the granted scopes include required, directly or
+// through a wildcard.
+func HasScope(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, scopeSeparator)
+	for _, scope := range granted {
+		if scope == required || scope == resource+scopeWildcard {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test secret")
+
+// encode encodes one part of a token.
+func encode(part string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(part))
+}
+
+// signedToken returns a token with the encoded header and payload, signed
+// with secret like TokenManager signs its tokens.
+func signedToken(secret []byte, header, payload string) string {
+	unsigned := header + "." + payload
+	return unsigned + "." + NewTokenManager(secret, time.Hour).sign(unsigned)
+}
+
+func TestTokenManagerIssueAndParse(t *testing.T) {
+	manager := NewTokenManager(testSecret, time.Hour)
+
+	token, expiresAt, err := manager.Issue(42)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if until := time.Until(expiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("token expires in %v, want an hour", until)
+	}
+
+	userID, err := manager.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if userID != 42 {
+		t.Errorf("got user id %d, want 42", userID)
+	}
+}
+
+func TestTokenManagerParseRejects(t *testing.T) {
+	// The tokens are parsed at iat, an hour before exp.
+	const iat = 1750000000
+	header := encode(`{"alg":"HS256","typ":"JWT"}`)
+	payload := encode(`{"sub":"42","iat":1750000000,"exp":1750003600}`)
+
+	manager := NewTokenManager(testSecret, time.Hour)
+	manager.now = func() time.Time { return time.Unix(iat, 0) }
+	valid := signedToken(testSecret, header, payload)
+	if userID, err := manager.Parse(valid); err != nil || userID != 42 {
+		t.Fatalf("got user id %d and error %v for a valid token", userID, err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"signed with another secret", signedToken([]byte("other secret"), header, payload)},
+		{"tampered signature", valid[:len(valid)-2] + "xx"},
+		{"tampered payload", header + "." + encode(`{"sub":"1","iat":1750000000,"exp":1750003600}`) + valid[strings.LastIndex(valid, "."):]},
+		{"no signature", header + "." + payload + "."},
+		{"alg none", encode(`{"alg":"none","typ":"JWT"}`) + "." + payload + "."},
+		{"alg none signed", signedToken(testSecret, encode(`{"alg":"none","typ":"JWT"}`), payload)},
+		{"alg HS512", signedToken(testSecret, encode(`{"alg":"HS512","typ":"JWT"}`), payload)},
+		{"alg RS256", signedToken(testSecret, encode(`{"alg":"RS256","typ":"JWT"}`), payload)},
+		{"header spaced differently", signedToken(testSecret, encode(`{"alg": "HS256", "typ": "JWT"}`), payload)},
+		{"expired", signedToken(testSecret, header, encode(`{"sub":"42","iat":1749996400,"exp":1749999999}`))},
+		{"expiring now", signedToken(testSecret, header, encode(`{"sub":"42","iat":1749996400,"exp":1750000000}`))},
+		{"no expiry", signedToken(testSecret, header, encode(`{"sub":"42","iat":1750000000}`))},
+		{"empty", ""},
+		{"two parts", header + "." + payload},
+		{"four parts", valid + ".x"},
+		{"payload not base64", signedToken(testSecret, header, "!!!")},
+		{"payload not JSON", signedToken(testSecret, header, encode("not json"))},
+		{"non-numeric sub", signedToken(testSecret, header, encode(`{"sub":"alice","iat":1750000000,"exp":1750003600}`))},
+		{"sub as a number", signedToken(testSecret, header, encode(`{"sub":42,"iat":1750000000,"exp":1750003600}`))},
+		{"zero sub", signedToken(testSecret, header, encode(`{"sub":"0","iat":1750000000,"exp":1750003600}`))},
+		{"no sub", signedToken(testSecret, header, encode(`{"iat":1750000000,"exp":1750003600}`))},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userID, err := manager.Parse(tc.token)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("got user id %d and error %v, want %v", userID, err, ErrInvalidToken)
+			}
+		})
+	}
+}
@@ -1,66 +1,233 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"flashcards/auth"
 	"flashcards/config"
 	"flashcards/db"
 	"flashcards/handlers"
+	"flashcards/llm"
+	"flashcards/ratelimit"
 	"flashcards/services"
 
 	"github.com/gorilla/mux"
 )
 
+const shutdownTimeout = 10 * time.Second
+
+// publicPaths can be requested without an access token.
+var publicPaths = map[string]bool{
+	"/health":        true,
+	"/auth/register": true,
+	"/auth/login":    true,
+}
+
+// Rate limit groups, each with its own limit.
+const (
+	routeGroupAuth    = "auth"
+	routeGroupLLM     = "llm"
+	routeGroupDefault = "default"
+)
+
+// routeResources maps the first path segment of a route to the resource its
+// API key scopes are named after.
+var routeResources = map[string]string{
+	"notes":      "notes",
+	"todos":      "todos",
+	"tags":       "tags",
+	"decks":      "decks",
+	"flashcards": "flashcards",
+	"review":     "reviews",
+	"quiz":       "quiz",
+}
+
 func main() {
 	cfg := config.Load()
 
-	if cfg.DatabaseURL == "" {
+	if cfg.DatabaseURL == "" && cfg.StorageDriver != db.DriverMemory {
 		log.Fatal("DB_URL environment variable is required")
 	}
 
-	todoRepo, err := db.NewPostgresTodoRepository(cfg.DatabaseURL)
+	store, err := db.NewStore(cfg.StorageDriver, cfg.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		log.Fatalf("Failed to initialize %s storage: %v", cfg.StorageDriver, err)
 	}
-	defer todoRepo.Close()
+	defer store.Close()
 
-	noteRepo, err := db.NewPostgresNoteRepository(cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Failed to initialize note database: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), store, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	if cfg.AutoMigrate && cfg.StorageDriver != db.DriverMemory {
+		migrator, err := store.Migrator()
+		if err != nil {
+			log.Fatalf("Failed to load migrations: %v", err)
+		}
+		if err := migrateUp(context.Background(), migrator); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
 	}
-	defer noteRepo.Close()
 
-	todoService := services.NewTodoService(todoRepo)
+	secret := []byte(cfg.JWTSecret)
+	if len(secret) == 0 {
+		log.Println("JWT_SECRET is not set, using a random secret; tokens will not survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate JWT secret: %v", err)
+		}
+	}
+	tokens := auth.NewTokenManager(secret, cfg.AuthTokenTTL)
+
+	authService := services.NewAuthService(store.Users, tokens)
+	authHandler := handlers.NewAuthHandler(authService)
+	apiKeyService := services.NewAPIKeyService(store.APIKeys)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+
+	todoService := services.NewTodoService(store.Todos, store.Tags)
 	todoHandler := handlers.NewTodoHandler(todoService)
 
-	noteService := services.NewNoteService(noteRepo)
+	noteService := services.NewNoteService(store.Notes, store.Tags)
 	noteHandler := handlers.NewNoteHandler(noteService)
 
-	quizService := services.NewQuizService(noteService, cfg.OpenAIAPIKey)
-	quizHandler := handlers.NewQuizHandler(quizService)
+	tagService := services.NewTagService(store.Tags, noteService)
+	tagHandler := handlers.NewTagHandler(tagService)
+
+	trashService := services.NewTrashService(store.Notes, store.Todos, store.Tags, cfg.TrashRetention)
+	trashHandler := handlers.NewTrashHandler(trashService)
+
+	model, err := llm.New(cfg.LLMProvider, llm.ProviderConfig{
+		Model:          cfg.LLMModel,
+		EmbeddingModel: cfg.EmbeddingModel,
+		BaseURL:        cfg.LLMBaseURL,
+		APIKey:         cfg.LLMAPIKey,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM: %v", err)
+	}
+
+	embedder, err := llm.NewEmbedder(model)
+	if err != nil {
+		log.Printf("Embeddings unavailable, semantic note search is disabled and quiz context is ranked by keywords: %v", err)
+	}
+	tokenCounter := llm.NewTokenCounter(llm.DefaultTokenEncoding)
+	noteIndex := services.NewNoteIndex(embedder, tokenCounter.Count)
+	searchService := services.NewSearchService(store.Notes, store.Tags, noteIndex)
+	searchHandler := handlers.NewSearchHandler(searchService)
+
+	quotaService, err := services.NewQuotaService(store.LLMUsage, tokenCounter.Count, cfg.LLMTokenQuota, cfg.LLMQuotaPeriod)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM quota: %v", err)
+	}
+
+	retriever := services.NewNoteRetriever(noteService, noteIndex, cfg.QuizContextTokens)
+	quizService := services.NewQuizService(retriever, store.Quizzes, model, quotaService, cfg.LLMTemperature)
+	deckService := services.NewDeckService(store.Decks, noteService)
+	deckHandler := handlers.NewDeckHandler(deckService)
+
+	quizHandler := handlers.NewQuizHandler(quizService, deckService, tagService)
+	quizSocketHandler := handlers.NewQuizSocketHandler(quizService, deckService, tagService)
+
+	flashcardService := services.NewFlashcardService(store.Flashcards, noteService, quizService)
+	flashcardHandler := handlers.NewFlashcardHandler(flashcardService)
+
+	scheduler, err := services.NewScheduler(cfg.ReviewAlgorithm)
+	if err != nil {
+		log.Fatalf("Failed to initialize review scheduler: %v", err)
+	}
+	reviewService := services.NewReviewService(store.Reviews, scheduler, noteService, flashcardService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+
+	exportService := services.NewExportService(store.Notes, store.Todos, store.Tags, store.Decks, store.Flashcards, store.Quizzes)
+	exportHandler := handlers.NewExportHandler(exportService)
+
+	limiters := make(map[string]*ratelimit.Limiter)
+	for group, value := range map[string]string{
+		routeGroupAuth:    cfg.RateLimitAuth,
+		routeGroupLLM:     cfg.RateLimitLLM,
+		routeGroupDefault: cfg.RateLimitDefault,
+	} {
+		rule, err := ratelimit.ParseRule(value)
+		if err != nil {
+			log.Fatalf("Failed to configure the %s rate limit: %v", group, err)
+		}
+		limiters[group] = ratelimit.NewLimiter(rule)
+	}
 
 	router := mux.NewRouter()
 
 	router.Use(corsMiddleware)
+	router.Use(authMiddleware(tokens, apiKeyService))
+	router.Use(scopeMiddleware)
+	router.Use(rateLimitMiddleware(limiters))
+	router.Use(quotaMiddleware(quotaService))
 	router.Use(jsonMiddleware)
 
 	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}).Methods("OPTIONS")
 
+	authHandler.RegisterRoutes(router)
+	apiKeyHandler.RegisterRoutes(router)
 	todoHandler.RegisterRoutes(router)
 	noteHandler.RegisterRoutes(router)
+	tagHandler.RegisterRoutes(router)
+	trashHandler.RegisterRoutes(router)
+	searchHandler.RegisterRoutes(router)
+	deckHandler.RegisterRoutes(router)
 	quizHandler.RegisterRoutes(router)
+	quizSocketHandler.RegisterRoutes(router)
+	flashcardHandler.RegisterRoutes(router)
+	reviewHandler.RegisterRoutes(router)
+	exportHandler.RegisterRoutes(router)
 
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
 
 	addr := ":" + cfg.Port
 	fmt.Printf("Server starting on port %s\n", cfg.Port)
 
-	if err := http.ListenAndServe(addr, router); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	server := &http.Server{Addr: addr, Handler: router}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go trashService.RunPurgeJob(ctx, cfg.TrashPurgeInterval)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	case <-ctx.Done():
+		log.Println("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown failed: %v", err)
+		}
+		// Shutdown does not track hijacked WebSocket connections.
+		quizSocketHandler.Shutdown()
 	}
 }
 
@@ -82,6 +249,214 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// authMiddleware rejects requests without a valid access token or API key
+// and stores the id of the signed in user in the request context. Browsers
+// cannot set headers on WebSocket handshakes, so those may pass the token in
+// the access_token query parameter instead.
+func authMiddleware(tokens *auth.TokenManager, apiKeys *services.APIKeyService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" || publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				token = r.URL.Query().Get("access_token")
+			}
+			token = strings.TrimSpace(token)
+			if token == "" {
+				writeUnauthorized(w, r, "missing access token")
+				return
+			}
+
+			if auth.IsAPIKey(token) {
+				key, err := apiKeys.Authenticate(token)
+				if err != nil {
+					if errors.Is(err, auth.ErrInvalidToken) {
+						writeUnauthorized(w, r, "invalid or expired API key")
+					} else {
+						handlers.WriteProblem(w, r, http.StatusInternalServerError, "Failed to authenticate API key")
+					}
+					return
+				}
+
+				ctx := auth.WithAPIKey(auth.WithUserID(r.Context(), key.UserID), key.ID, key.Scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			userID, err := tokens.Parse(token)
+			if err != nil {
+				writeUnauthorized(w, r, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithUserID(r.Context(), userID)))
+		})
+	}
+}
+
+// scopeMiddleware rejects requests made with an API key that lacks the scope
+// of the route. Routes outside routeResources, such as managing API keys,
+// need an access token, except for looking up the user of the key.
+func scopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, scopes, ok := auth.APIKey(r.Context())
+		if !ok || r.URL.Path == "/auth/me" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		required := requiredScope(r)
+		if required == "" {
+			handlers.WriteProblem(w, r, http.StatusForbidden, "API keys cannot be used for this route")
+			return
+		}
+		if !auth.HasScope(scopes, required) {
+			handlers.WriteProblem(w, r, http.StatusForbidden, fmt.Sprintf("API key lacks the %s scope", required))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requiredScope returns the API key scope needed for r, or "" if API keys
+// cannot be used for it. Reads need the read scope of the resource and
+// everything else its write scope. Quizzes need quiz:generate for anything
+// but reading a session, since the other routes call the LLM.
+func requiredScope(r *http.Request) string {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	resource := routeResources[segments[0]]
+	// The flashcards of a note, /notes/{id}/flashcards.
+	if resource == "notes" && segments[len(segments)-1] == "flashcards" {
+		resource = "flashcards"
+	}
+
+	switch {
+	case resource == "":
+		return ""
+	case resource == "quiz":
+		if r.Method == "GET" && len(segments) > 1 && segments[1] == "sessions" {
+			return auth.ScopeQuizRead
+		}
+		return auth.ScopeQuizGenerate
+	case r.Method == "GET":
+		return resource + ":read"
+	default:
+		return resource + ":write"
+	}
+}
+
+// routeGroup returns the rate limit group of r. Signing in is limited per IP
+// to slow down password guessing, and routes that call the LLM get a
+// tighter limit than the rest.
+func routeGroup(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/auth/register" || r.URL.Path == "/auth/login":
+		return routeGroupAuth
+	case callsLLM(r):
+		return routeGroupLLM
+	default:
+		return routeGroupDefault
+	}
+}
+
+// callsLLM reports whether r is answered by the LLM, which are the quiz
+// routes and generating the flashcards of a note.
+func callsLLM(r *http.Request) bool {
+	required := requiredScope(r)
+	return required == auth.ScopeQuizGenerate ||
+		(required == auth.ScopeFlashcardsWrite && r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/notes/"))
+}
+
+// rateLimitKey identifies who r counts against: the API key, else the signed
+// in user, else the client IP.
+func rateLimitKey(r *http.Request) string {
+	if id, _, ok := auth.APIKey(r.Context()); ok {
+		return fmt.Sprintf("key:%d", id)
+	}
+	if userID, ok := auth.UserID(r.Context()); ok {
+		return fmt.Sprintf("user:%d", userID)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimitMiddleware keeps a token bucket per client and route group, and
+// rejects requests with 429 Too Many Requests once the bucket is empty.
+func rateLimitMiddleware(limiters map[string]*ratelimit.Limiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" || r.URL.Path == "/health" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result := limiters[routeGroup(r)].Allow(rateLimitKey(r))
+			if result.Limit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			}
+			if !result.Allowed {
+				w.Header().Set("Retry-After", retryAfter(result.RetryAfter))
+				handlers.WriteProblem(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// quotaMiddleware rejects requests that call the LLM once their user has
+// used up the LLM token quota, and reports the remaining budget on the
+// others.
+func quotaMiddleware(quota *services.QuotaService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !quota.Enabled() || !callsLLM(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, _ := auth.UserID(r.Context())
+			status, err := quota.GetQuota(userID)
+			if err != nil {
+				handlers.WriteProblem(w, r, http.StatusInternalServerError, "Failed to check LLM quota")
+				return
+			}
+
+			w.Header().Set("X-LLM-Quota-Limit", strconv.Itoa(status.Limit))
+			w.Header().Set("X-LLM-Quota-Remaining", strconv.Itoa(status.Remaining))
+			w.Header().Set("X-LLM-Quota-Reset", status.ResetsAt.Format(time.RFC3339))
+			if status.Remaining == 0 {
+				w.Header().Set("Retry-After", retryAfter(time.Until(status.ResetsAt)))
+				handlers.WriteProblem(w, r, http.StatusTooManyRequests, "LLM token quota exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// retryAfter formats d for the Retry-After header, in whole seconds.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(max(int(math.Ceil(d.Seconds())), 1))
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="flashcards"`)
+	handlers.WriteProblem(w, r, http.StatusUnauthorized, message)
+}
+
 func jsonMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"flashcards/db"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate runs the migrate subcommand: up applies the pending
+// migrations, down reverts the latest ones (one by default) and status
+// lists them.
+func runMigrate(ctx context.Context, store *db.Store, args []string) error {
+	migrator, err := store.Migrator()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch command := args[0]; {
+	case command == "up" && len(args) == 1:
+		return migrateUp(ctx, migrator)
+	case command == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of migrations to revert: %q", args[1])
+			}
+		}
+		return migrateDown(ctx, migrator, steps)
+	case command == "status" && len(args) == 1:
+		return printMigrationStatus(ctx, migrator)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func migrateUp(ctx context.Context, migrator *db.Migrator) error {
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		log.Println("Database schema is up to date")
+	}
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+func migrateDown(ctx context.Context, migrator *db.Migrator, steps int) error {
+	reverted, err := migrator.Down(ctx, steps)
+	if err != nil {
+		return err
+	}
+
+	if len(reverted) == 0 {
+		log.Println("No migrations to revert")
+	}
+	for _, migration := range reverted {
+		log.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *db.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		name := status.Name
+		if status.Unknown {
+			name += " (not in this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, name, appliedAt)
+	}
+	return w.Flush()
+}
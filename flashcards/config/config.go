@@ -3,14 +3,39 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DatabaseURL  string
-	Port         string
-	OpenAIAPIKey string
+	DatabaseURL     string
+	StorageDriver   string
+	AutoMigrate     bool
+	Port            string
+	ReviewAlgorithm string
+
+	LLMProvider    string
+	LLMModel       string
+	EmbeddingModel string
+	LLMBaseURL     string
+	LLMAPIKey      string
+	LLMTemperature float64
+
+	QuizContextTokens int
+
+	JWTSecret    string
+	AuthTokenTTL time.Duration
+
+	RateLimitAuth    string
+	RateLimitLLM     string
+	RateLimitDefault string
+	LLMTokenQuota    int
+	LLMQuotaPeriod   string
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
 }
 
 func Load() *Config {
@@ -19,25 +44,105 @@ func Load() *Config {
 	}
 
 	config := &Config{
-		DatabaseURL:  getEnv("DB_URL"),
-		Port:         getEnvWithDefault("PORT", "8080"),
-		OpenAIAPIKey: getEnv("OPENAI_API_KEY"),
+		DatabaseURL:     getEnvWithDefault("DB_URL", ""),
+		StorageDriver:   getEnvWithDefault("STORAGE_DRIVER", "postgres"),
+		AutoMigrate:     getEnvBoolWithDefault("DB_AUTO_MIGRATE", true),
+		Port:            getEnvWithDefault("PORT", "8080"),
+		ReviewAlgorithm: getEnvWithDefault("REVIEW_ALGORITHM", "sm2"),
+
+		LLMProvider:    getEnvWithDefault("LLM_PROVIDER", "openai"),
+		LLMModel:       getEnvWithDefault("LLM_MODEL", ""),
+		EmbeddingModel: getEnvWithDefault("EMBEDDING_MODEL", ""),
+		LLMBaseURL:     getEnvWithDefault("LLM_BASE_URL", ""),
+		LLMAPIKey:      getEnvWithDefault("LLM_API_KEY", os.Getenv("OPENAI_API_KEY")),
+		LLMTemperature: getEnvFloatWithDefault("LLM_TEMPERATURE", 0.7),
+
+		QuizContextTokens: getEnvIntWithDefault("QUIZ_CONTEXT_TOKENS", 3000),
+
+		JWTSecret:    getEnvWithDefault("JWT_SECRET", ""),
+		AuthTokenTTL: getEnvDurationWithDefault("AUTH_TOKEN_TTL", 24*time.Hour),
+
+		RateLimitAuth:    getEnvWithDefault("RATE_LIMIT_AUTH", "10/m"),
+		RateLimitLLM:     getEnvWithDefault("RATE_LIMIT_LLM", "20/m"),
+		RateLimitDefault: getEnvWithDefault("RATE_LIMIT_DEFAULT", "300/m"),
+		LLMTokenQuota:    getEnvNonNegativeIntWithDefault("LLM_TOKEN_QUOTA", 0),
+		LLMQuotaPeriod:   getEnvWithDefault("LLM_QUOTA_PERIOD", "day"),
+
+		TrashRetention:     getEnvDurationWithDefault("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getEnvDurationWithDefault("TRASH_PURGE_INTERVAL", time.Hour),
 	}
 
 	return config
 }
 
-func getEnv(key string) string {
+func getEnvWithDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBoolWithDefault(key string, defaultValue bool) bool {
 	value := os.Getenv(key)
 	if value == "" {
-		panic("Required environment variable not set: " + key)
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		panic("Invalid boolean in environment variable " + key + ": " + value)
 	}
-	return value
+	return parsed
 }
 
-func getEnvWithDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func getEnvFloatWithDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
 	}
-	return defaultValue
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic("Invalid number in environment variable " + key + ": " + value)
+	}
+	return parsed
+}
+
+func getEnvIntWithDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		panic("Invalid positive integer in environment variable " + key + ": " + value)
+	}
+	return parsed
+}
+
+func getEnvNonNegativeIntWithDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		panic("Invalid non-negative integer in environment variable " + key + ": " + value)
+	}
+	return parsed
+}
+
+func getEnvDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		panic("Invalid positive duration in environment variable " + key + ": " + value)
+	}
+	return parsed
 }
@@ -1,40 +1,53 @@
+# Requests need an access token or API key (see POST /auth/keys), e.g. from POST /auth/login:
+# export TOKEN=$(curl -s -X POST http://localhost:8080/auth/login -d '{"email": "...", "password": "..."}' | jq -r .token)
+
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "The Anglo-Saxon kingdoms were established in England from the 5th century onwards. The major kingdoms included Wessex, Mercia, Northumbria, and East Anglia, collectively known as the Heptarchy."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "King Alfred the Great (871-899) of Wessex successfully defended against Viking invasions and is considered the first King of the Anglo-Saxons. He established a system of burhs (fortified towns) for defense."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "The Norman Conquest occurred in 1066 when William the Conqueror defeated King Harold Godwinson at the Battle of Hastings. This marked the end of Anglo-Saxon rule and began Norman rule in England."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "The Domesday Book was commissioned by William the Conqueror in 1085-1086. It was a comprehensive survey of land ownership and resources in England, serving as a tool for taxation and administration."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "King John signed the Magna Carta in 1215 at Runnymede under pressure from rebellious barons. This document limited royal power and established the principle that even the king was subject to the law."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "The Hundred Years War (1337-1453) was a series of conflicts between England and France over territorial claims and succession to the French throne. Famous battles included Crécy, Poitiers, and Agincourt."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "The Wars of the Roses (1455-1487) were civil wars between the Houses of Lancaster and York for control of the English throne. The conflict ended when Henry Tudor defeated Richard III at Bosworth Field."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "Henry VIII broke with the Roman Catholic Church in the 1530s to divorce Catherine of Aragon and marry Anne Boleyn. This led to the English Reformation and the establishment of the Church of England."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "Elizabeth I ruled from 1558-1603 during the Elizabethan Age, a golden period of English culture and exploration. Her reign saw the defeat of the Spanish Armada in 1588 and flourishing of arts under Shakespeare."}'
 
   curl -X POST http://localhost:8080/notes \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer $TOKEN" \
     -d '{"content": "The English Civil War (1642-1651) resulted in the execution of King Charles I in 1649 and the establishment of the Commonwealth under Oliver Cromwell. The monarchy was restored in 1660 with Charles II."}'
 
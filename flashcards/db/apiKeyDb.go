@@ -0,0 +1,142 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyRepository stores API keys by the hash of the key, the key itself is
+// never stored.
+type APIKeyRepository interface {
+	CreateAPIKey(userID int, key *models.APIKey, keyHash string) error
+	GetAllAPIKeys(userID int) ([]*models.APIKey, error)
+	GetAPIKeyByHash(keyHash string) (*models.APIKey, error)
+	TouchAPIKey(id int, usedAt time.Time) error
+	DeleteAPIKey(userID, id int) error
+}
+
+type PostgresAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyRepository(db *sql.DB) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+const apiKeyColumns = "id, userId, name, prefix, scopes, expiresAt, lastUsedAt, createdAt"
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, pq.Array(&key.Scopes),
+		&expiresAt, &lastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	setAPIKeyTimes(key, expiresAt, lastUsedAt)
+	return key, nil
+}
+
+func setAPIKeyTimes(key *models.APIKey, expiresAt, lastUsedAt sql.NullTime) {
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+}
+
+func (r *PostgresAPIKeyRepository) CreateAPIKey(userID int, key *models.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO gocourse.api_keys (userId, name, prefix, keyHash, scopes, expiresAt) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRow(query, userID, key.Name, key.Prefix, keyHash, pq.Array(key.Scopes), key.ExpiresAt)
+	if err := row.Scan(&key.ID, &key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	key.UserID = userID
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) GetAllAPIKeys(userID int) ([]*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM gocourse.api_keys WHERE userId = $1 ORDER BY createdAt DESC, id DESC"
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *PostgresAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM gocourse.api_keys WHERE keyHash = $1"
+
+	key, err := scanAPIKey(r.db.QueryRow(query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("API key not found")
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *PostgresAPIKeyRepository) TouchAPIKey(id int, usedAt time.Time) error {
+	query := "UPDATE gocourse.api_keys SET lastUsedAt = $1 WHERE id = $2"
+
+	if _, err := r.db.Exec(query, usedAt, id); err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) DeleteAPIKey(userID, id int) error {
+	query := "DELETE FROM gocourse.api_keys WHERE id = $1 AND userId = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("API key with id %d not found", id)
+	}
+
+	return nil
+}
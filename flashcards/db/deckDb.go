@@ -0,0 +1,252 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type DeckRepository interface {
+	CreateDeck(userID int, deck *models.Deck) error
+	GetDeckByID(userID, id int) (*models.Deck, error)
+	GetAllDecks(userID int) ([]*models.Deck, error)
+	UpdateDeck(userID, id int, updates map[string]any) error
+	DeleteDeck(userID, id int) error
+	AddNoteToDeck(userID, deckID, noteID int) error
+	RemoveNoteFromDeck(userID, deckID, noteID int) error
+	GetDeckNoteIDs(userID int, deckIDs []int) ([]int, error)
+}
+
+type PostgresDeckRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDeckRepository(databaseURL string) (*PostgresDeckRepository, error) {
+	db, err := openPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresDeckRepository{db: db}, nil
+}
+
+const deckColumns = "id, name, description, parentId, createdAt, updatedAt"
+
+func scanDeck(row rowScanner) (*models.Deck, error) {
+	deck := &models.Deck{}
+	var parentID sql.NullInt64
+
+	err := row.Scan(&deck.ID, &deck.Name, &deck.Description, &parentID, &deck.CreatedAt, &deck.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		deck.ParentID = &id
+	}
+
+	return deck, nil
+}
+
+func scanDecks(rows *sql.Rows) ([]*models.Deck, error) {
+	decks := make([]*models.Deck, 0)
+	for rows.Next() {
+		deck, err := scanDeck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deck: %w", err)
+		}
+		decks = append(decks, deck)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over decks: %w", err)
+	}
+
+	return decks, nil
+}
+
+func scanNoteIDs(rows *sql.Rows) ([]int, error) {
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan note id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck notes: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *PostgresDeckRepository) CreateDeck(userID int, deck *models.Deck) error {
+	query := `
+		INSERT INTO gocourse.decks (userId, name, description, parentId) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := r.db.QueryRow(query, userID, deck.Name, deck.Description, deck.ParentID)
+
+	err := row.Scan(&deck.ID, &deck.CreatedAt, &deck.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create deck: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDeckRepository) GetDeckByID(userID, id int) (*models.Deck, error) {
+	query := "SELECT " + deckColumns + " FROM gocourse.decks WHERE id = $1 AND userId = $2"
+
+	deck, err := scanDeck(r.db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("deck with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get deck: %w", err)
+	}
+
+	return deck, nil
+}
+
+func (r *PostgresDeckRepository) GetAllDecks(userID int) ([]*models.Deck, error) {
+	query := "SELECT " + deckColumns + " FROM gocourse.decks WHERE userId = $1 ORDER BY name, id"
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query decks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDecks(rows)
+}
+
+func (r *PostgresDeckRepository) UpdateDeck(userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return apperr.Validation("", "no updates provided")
+	}
+
+	query := "UPDATE gocourse.decks SET "
+	args := []any{}
+	argIndex := 1
+
+	for field, value := range updates {
+		if argIndex > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d AND userId = $%d", argIndex, argIndex+1)
+	args = append(args, id, userID)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+// DeleteDeck deletes the deck and, through the foreign keys, its sub-decks
+// and note memberships. The notes themselves are kept.
+func (r *PostgresDeckRepository) DeleteDeck(userID, id int) error {
+	query := "DELETE FROM gocourse.decks WHERE id = $1 AND userId = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+// AddNoteToDeck only links the note when both the deck and the note belong
+// to userID.
+func (r *PostgresDeckRepository) AddNoteToDeck(userID, deckID, noteID int) error {
+	query := `
+		INSERT INTO gocourse.deck_notes (deckId, noteId) 
+		SELECT d.id, n.id 
+		FROM gocourse.decks d, gocourse.notes n 
+		WHERE d.id = $1 AND n.id = $2 AND d.userId = $3 AND n.userId = $3 
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.Exec(query, deckID, noteID, userID); err != nil {
+		return fmt.Errorf("failed to add note to deck: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDeckRepository) RemoveNoteFromDeck(userID, deckID, noteID int) error {
+	query := `
+		DELETE FROM gocourse.deck_notes 
+		WHERE deckId = $1 AND noteId = $2 
+		AND deckId IN (SELECT id FROM gocourse.decks WHERE userId = $3)`
+
+	result, err := r.db.Exec(query, deckID, noteID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove note from deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("note %d in deck %d not found", noteID, deckID)
+	}
+
+	return nil
+}
+
+// GetDeckNoteIDs returns the distinct ids of the notes in any of deckIDs.
+func (r *PostgresDeckRepository) GetDeckNoteIDs(userID int, deckIDs []int) ([]int, error) {
+	query := `
+		SELECT DISTINCT dn.noteId 
+		FROM gocourse.deck_notes dn 
+		JOIN gocourse.decks d ON d.id = dn.deckId 
+		WHERE dn.deckId = ANY($1) AND d.userId = $2 
+		ORDER BY dn.noteId`
+
+	rows, err := r.db.Query(query, pq.Array(deckIDs), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deck notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNoteIDs(rows)
+}
+
+func (r *PostgresDeckRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,252 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type FlashcardRepository interface {
+	CreateFlashcard(userID int, card *models.Flashcard) error
+	CreateFlashcards(userID int, cards []*models.Flashcard) error
+	GetFlashcardByID(userID, id int) (*models.Flashcard, error)
+	GetAllFlashcards(userID int) ([]*models.Flashcard, error)
+	ListFlashcards(userID int, opts models.ListOptions) (*models.Page[*models.Flashcard], error)
+	GetFlashcardsByNoteID(userID, noteID int) ([]*models.Flashcard, error)
+	UpdateFlashcard(userID, id int, updates map[string]any) error
+	DeleteFlashcard(userID, id int) error
+}
+
+type PostgresFlashcardRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresFlashcardRepository(databaseURL string) (*PostgresFlashcardRepository, error) {
+	db, err := openPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresFlashcardRepository{db: db}, nil
+}
+
+const postgresFlashcardColumns = "id, front, back, noteId, tags, createdAt, updatedAt"
+
+func scanPostgresFlashcard(row rowScanner) (*models.Flashcard, error) {
+	card := &models.Flashcard{}
+	var noteID sql.NullInt64
+
+	err := row.Scan(&card.ID, &card.Front, &card.Back, &noteID, pq.Array(&card.Tags), &card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if noteID.Valid {
+		id := int(noteID.Int64)
+		card.NoteID = &id
+	}
+	if card.Tags == nil {
+		card.Tags = []string{}
+	}
+
+	return card, nil
+}
+
+func (r *PostgresFlashcardRepository) CreateFlashcard(userID int, card *models.Flashcard) error {
+	query := `
+		INSERT INTO gocourse.flashcards (userId, front, back, noteId, tags) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := r.db.QueryRow(query, userID, card.Front, card.Back, card.NoteID, pq.Array(card.Tags))
+
+	err := row.Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create flashcard: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresFlashcardRepository) CreateFlashcards(userID int, cards []*models.Flashcard) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gocourse.flashcards (userId, front, back, noteId, tags) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, createdAt, updatedAt`
+
+	for _, card := range cards {
+		row := tx.QueryRow(query, userID, card.Front, card.Back, card.NoteID, pq.Array(card.Tags))
+		if err := row.Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to create flashcard: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit flashcards: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresFlashcardRepository) GetFlashcardByID(userID, id int) (*models.Flashcard, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM gocourse.flashcards 
+		WHERE id = $1 AND userId = $2`, postgresFlashcardColumns)
+
+	card, err := scanPostgresFlashcard(r.db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("flashcard with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get flashcard: %w", err)
+	}
+
+	return card, nil
+}
+
+func (r *PostgresFlashcardRepository) GetAllFlashcards(userID int) ([]*models.Flashcard, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM gocourse.flashcards 
+		WHERE userId = $1 
+		ORDER BY createdAt DESC, id DESC`, postgresFlashcardColumns)
+
+	return r.queryFlashcards(query, userID)
+}
+
+// ListFlashcards returns a page of flashcards. The total is only counted when
+// there is more than one page.
+func (r *PostgresFlashcardRepository) ListFlashcards(userID int, opts models.ListOptions) (*models.Page[*models.Flashcard], error) {
+	q := newListQuery(true)
+	q.ownedBy(userID)
+	q.filter(opts, "front", "back")
+	countQuery := "SELECT COUNT(*) FROM gocourse.flashcards" + q.whereClause()
+	countArgs := append([]any{}, q.args...)
+
+	q.after(opts)
+	query := "SELECT " + postgresFlashcardColumns + " FROM gocourse.flashcards" + q.whereClause() + q.orderAndLimit(opts)
+
+	cards, err := r.queryFlashcards(query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(cards, opts, flashcardListKey, func() (int, error) {
+		var total int
+		err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+		return total, err
+	})
+}
+
+func (r *PostgresFlashcardRepository) GetFlashcardsByNoteID(userID, noteID int) ([]*models.Flashcard, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM gocourse.flashcards 
+		WHERE noteId = $1 AND userId = $2 
+		ORDER BY createdAt DESC, id DESC`, postgresFlashcardColumns)
+
+	return r.queryFlashcards(query, noteID, userID)
+}
+
+func (r *PostgresFlashcardRepository) queryFlashcards(query string, args ...any) ([]*models.Flashcard, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := make([]*models.Flashcard, 0)
+	for rows.Next() {
+		card, err := scanPostgresFlashcard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+		}
+		cards = append(cards, card)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return cards, nil
+}
+
+func (r *PostgresFlashcardRepository) UpdateFlashcard(userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return apperr.Validation("", "no updates provided")
+	}
+
+	query := "UPDATE gocourse.flashcards SET "
+	args := []any{}
+	argIndex := 1
+
+	for field, value := range updates {
+		if argIndex > 1 {
+			query += ", "
+		}
+		if tags, ok := value.([]string); ok {
+			value = pq.Array(tags)
+		}
+		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d AND userId = $%d", argIndex, argIndex+1)
+	args = append(args, id, userID)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("flashcard with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *PostgresFlashcardRepository) DeleteFlashcard(userID, id int) error {
+	query := "DELETE FROM gocourse.flashcards WHERE id = $1 AND userId = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("flashcard with id %d not found", id)
+	}
+
+	return nil
+}
+
+func flashcardListKey(card *models.Flashcard) listKey {
+	return listKey{id: card.ID, createdAt: card.CreatedAt, updatedAt: card.UpdatedAt}
+}
+
+func (r *PostgresFlashcardRepository) Close() error {
+	return r.db.Close()
+}
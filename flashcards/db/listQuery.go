@@ -0,0 +1,264 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+// listQuery builds the WHERE, ORDER BY and LIMIT clauses of a paginated list
+// query for Postgres or SQLite. Only fixed column names are ever written into
+// the SQL, every value is passed as an argument.
+type listQuery struct {
+	postgres   bool
+	conditions []string
+	args       []any
+}
+
+func newListQuery(postgres bool) *listQuery {
+	return &listQuery{postgres: postgres}
+}
+
+func (q *listQuery) arg(value any) string {
+	if t, ok := value.(time.Time); ok && !q.postgres {
+		// SQLite compares timestamps as text, which only works in UTC.
+		value = t.UTC()
+	}
+	q.args = append(q.args, value)
+	if q.postgres {
+		return fmt.Sprintf("$%d", len(q.args))
+	}
+	return "?"
+}
+
+func (q *listQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// ownedBy restricts the list to the rows of one user.
+func (q *listQuery) ownedBy(userID int) {
+	q.where("userId = " + q.arg(userID))
+}
+
+// excludeDeleted leaves out the rows in the trash.
+func (q *listQuery) excludeDeleted() {
+	q.where("deletedAt IS NULL")
+}
+
+// filter adds the conditions of opts other than the cursor. Query is matched
+// against textColumns.
+func (q *listQuery) filter(opts models.ListOptions, textColumns ...string) {
+	if opts.IDs != nil {
+		if q.postgres {
+			q.where("id = ANY(" + q.arg(pq.Array(opts.IDs)) + ")")
+		} else {
+			placeholders := make([]string, len(opts.IDs))
+			for i, id := range opts.IDs {
+				placeholders[i] = q.arg(id)
+			}
+			q.where("id IN (" + strings.Join(placeholders, ", ") + ")")
+		}
+	}
+
+	if opts.Query != "" && len(textColumns) > 0 {
+		operator := "LIKE"
+		if q.postgres {
+			operator = "ILIKE"
+		}
+		pattern := "%" + escapeLike(opts.Query) + "%"
+		matches := make([]string, len(textColumns))
+		for i, column := range textColumns {
+			matches[i] = fmt.Sprintf(`%s %s %s ESCAPE '\'`, column, operator, q.arg(pattern))
+		}
+		q.where("(" + strings.Join(matches, " OR ") + ")")
+	}
+
+	if opts.CreatedAfter != nil {
+		q.where("createdAt >= " + q.arg(*opts.CreatedAfter))
+	}
+	if opts.CreatedBefore != nil {
+		q.where("createdAt < " + q.arg(*opts.CreatedBefore))
+	}
+	if opts.UpdatedAfter != nil {
+		q.where("updatedAt >= " + q.arg(*opts.UpdatedAfter))
+	}
+	if opts.UpdatedBefore != nil {
+		q.where("updatedAt < " + q.arg(*opts.UpdatedBefore))
+	}
+}
+
+// after skips everything up to and including the cursor.
+func (q *listQuery) after(opts models.ListOptions) {
+	if opts.After == nil {
+		return
+	}
+
+	operator := ">"
+	if listSortOrder(opts) == models.SortOrderDesc {
+		operator = "<"
+	}
+	q.where(fmt.Sprintf("(%s, id) %s (%s, %s)", listSortColumn(opts), operator, q.arg(opts.After.Time), q.arg(opts.After.ID)))
+}
+
+func (q *listQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// orderAndLimit fetches one row past the limit, which tells whether there is
+// a next page without counting.
+func (q *listQuery) orderAndLimit(opts models.ListOptions) string {
+	order := strings.ToUpper(listSortOrder(opts))
+	clause := fmt.Sprintf(" ORDER BY %s %s, id %s", listSortColumn(opts), order, order)
+	if opts.Limit > 0 {
+		clause += " LIMIT " + q.arg(opts.Limit+1)
+	}
+	return clause
+}
+
+func listSortColumn(opts models.ListOptions) string {
+	if opts.SortBy == models.SortByUpdatedAt {
+		return models.SortByUpdatedAt
+	}
+	return models.SortByCreatedAt
+}
+
+func listSortOrder(opts models.ListOptions) string {
+	if opts.SortOrder == models.SortOrderAsc {
+		return models.SortOrderAsc
+	}
+	return models.SortOrderDesc
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// listKey is the sort key of one listed item.
+type listKey struct {
+	id        int
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+func (k listKey) sortTime(opts models.ListOptions) time.Time {
+	if listSortColumn(opts) == models.SortByUpdatedAt {
+		return k.updatedAt
+	}
+	return k.createdAt
+}
+
+// newPage trims the row fetched past the limit and sets the next cursor.
+// The total is only counted when the page does not already show it.
+func newPage[T any](items []T, opts models.ListOptions, key func(T) listKey, count func() (int, error)) (*models.Page[T], error) {
+	page := &models.Page[T]{Items: items}
+
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		page.Items = items[:opts.Limit]
+		last := key(page.Items[opts.Limit-1])
+		page.NextCursor = models.Cursor{
+			SortBy:    listSortColumn(opts),
+			SortOrder: listSortOrder(opts),
+			Time:      last.sortTime(opts),
+			ID:        last.id,
+		}.Encode()
+	}
+
+	if opts.After == nil && page.NextCursor == "" {
+		page.Total = len(page.Items)
+		return page, nil
+	}
+
+	total, err := count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count items: %w", err)
+	}
+	page.Total = total
+
+	return page, nil
+}
+
+// matchesList reports whether an in-memory item passes the filters of opts
+// other than the cursor, the same way listQuery.filter does.
+func matchesList(opts models.ListOptions, key listKey, texts ...string) bool {
+	if opts.IDs != nil {
+		found := false
+		for _, id := range opts.IDs {
+			if id == key.id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if opts.Query != "" {
+		query := strings.ToLower(opts.Query)
+		found := false
+		for _, text := range texts {
+			if strings.Contains(strings.ToLower(text), query) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if opts.CreatedAfter != nil && key.createdAt.Before(*opts.CreatedAfter) {
+		return false
+	}
+	if opts.CreatedBefore != nil && !key.createdAt.Before(*opts.CreatedBefore) {
+		return false
+	}
+	if opts.UpdatedAfter != nil && key.updatedAt.Before(*opts.UpdatedAfter) {
+		return false
+	}
+	if opts.UpdatedBefore != nil && !key.updatedAt.Before(*opts.UpdatedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// listLess orders in-memory items like listQuery.orderAndLimit.
+func listLess(opts models.ListOptions, a, b listKey) bool {
+	ta, tb := a.sortTime(opts), b.sortTime(opts)
+	less := ta.Before(tb) || (ta.Equal(tb) && a.id < b.id)
+	if listSortOrder(opts) == models.SortOrderDesc {
+		return !less && !(ta.Equal(tb) && a.id == b.id)
+	}
+	return less
+}
+
+// pageMemory sorts and pages in-memory items that already passed matchesList.
+func pageMemory[T any](items []T, opts models.ListOptions, key func(T) listKey) *models.Page[T] {
+	sort.Slice(items, func(i, j int) bool {
+		return listLess(opts, key(items[i]), key(items[j]))
+	})
+	total := len(items)
+
+	if opts.After != nil {
+		cursor := listKey{id: opts.After.ID, createdAt: opts.After.Time, updatedAt: opts.After.Time}
+		start := sort.Search(len(items), func(i int) bool {
+			return listLess(opts, cursor, key(items[i]))
+		})
+		items = items[start:]
+	}
+	if opts.Limit > 0 && len(items) > opts.Limit+1 {
+		items = items[:opts.Limit+1]
+	}
+
+	page, _ := newPage(items, opts, key, func() (int, error) { return total, nil })
+	return page
+}
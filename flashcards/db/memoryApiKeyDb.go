@@ -0,0 +1,115 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type MemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[int]*models.APIKey
+	hashes map[string]int
+	nextID int
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys:   make(map[int]*models.APIKey),
+		hashes: make(map[string]int),
+		nextID: 1,
+	}
+}
+
+// copyAPIKey returns a copy that does not share the scopes or times with
+// the stored key.
+func copyAPIKey(stored *models.APIKey) *models.APIKey {
+	key := *stored
+	key.Scopes = append([]string{}, stored.Scopes...)
+	if stored.ExpiresAt != nil {
+		expiresAt := *stored.ExpiresAt
+		key.ExpiresAt = &expiresAt
+	}
+	if stored.LastUsedAt != nil {
+		lastUsedAt := *stored.LastUsedAt
+		key.LastUsedAt = &lastUsedAt
+	}
+	return &key
+}
+
+func (r *MemoryAPIKeyRepository) CreateAPIKey(userID int, key *models.APIKey, keyHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.ID = r.nextID
+	key.UserID = userID
+	key.CreatedAt = time.Now()
+	r.nextID++
+
+	r.keys[key.ID] = copyAPIKey(key)
+	r.hashes[keyHash] = key.ID
+
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAllAPIKeys(userID int) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID > keys[j].ID
+	})
+
+	return keys, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[r.hashes[keyHash]]
+	if !ok {
+		return nil, apperr.NotFound("API key not found")
+	}
+
+	return copyAPIKey(key), nil
+}
+
+func (r *MemoryAPIKeyRepository) TouchAPIKey(id int, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+	}
+
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) DeleteAPIKey(userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID {
+		return apperr.NotFound("API key with id %d not found", id)
+	}
+
+	delete(r.keys, id)
+	for hash, keyID := range r.hashes {
+		if keyID == id {
+			delete(r.hashes, hash)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type MemoryDeckRepository struct {
+	mu      sync.RWMutex
+	decks   map[int]*models.Deck
+	members map[int]map[int]bool
+	owners  map[int]int
+	nextID  int
+}
+
+func NewMemoryDeckRepository() *MemoryDeckRepository {
+	return &MemoryDeckRepository{
+		decks:   make(map[int]*models.Deck),
+		members: make(map[int]map[int]bool),
+		owners:  make(map[int]int),
+		nextID:  1,
+	}
+}
+
+func copyDeck(deck *models.Deck) *models.Deck {
+	copied := *deck
+	if deck.ParentID != nil {
+		parentID := *deck.ParentID
+		copied.ParentID = &parentID
+	}
+	return &copied
+}
+
+// get returns the stored deck if it belongs to userID. Callers must hold the
+// lock.
+func (r *MemoryDeckRepository) get(userID, id int) (*models.Deck, bool) {
+	deck, ok := r.decks[id]
+	if !ok || r.owners[id] != userID {
+		return nil, false
+	}
+	return deck, true
+}
+
+func (r *MemoryDeckRepository) CreateDeck(userID int, deck *models.Deck) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if deck.ParentID != nil {
+		if _, ok := r.get(userID, *deck.ParentID); !ok {
+			return fmt.Errorf("failed to create deck: parent deck %d does not exist", *deck.ParentID)
+		}
+	}
+
+	now := time.Now()
+	deck.ID = r.nextID
+	deck.CreatedAt = now
+	deck.UpdatedAt = now
+	r.nextID++
+
+	r.decks[deck.ID] = copyDeck(deck)
+	r.owners[deck.ID] = userID
+
+	return nil
+}
+
+func (r *MemoryDeckRepository) GetDeckByID(userID, id int) (*models.Deck, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deck, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("deck with id %d not found", id)
+	}
+
+	return copyDeck(deck), nil
+}
+
+func (r *MemoryDeckRepository) GetAllDecks(userID int) ([]*models.Deck, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decks := make([]*models.Deck, 0)
+	for id, deck := range r.decks {
+		if r.owners[id] != userID {
+			continue
+		}
+		decks = append(decks, copyDeck(deck))
+	}
+
+	sort.Slice(decks, func(i, j int) bool {
+		if decks[i].Name == decks[j].Name {
+			return decks[i].ID < decks[j].ID
+		}
+		return decks[i].Name < decks[j].Name
+	})
+
+	return decks, nil
+}
+
+func (r *MemoryDeckRepository) UpdateDeck(userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return apperr.Validation("", "no updates provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return apperr.NotFound("deck with id %d not found", id)
+	}
+
+	updated := copyDeck(stored)
+	for field, value := range updates {
+		var ok bool
+		switch field {
+		case "name":
+			updated.Name, ok = value.(string)
+		case "description":
+			updated.Description, ok = value.(string)
+		case "parentId":
+			ok = true
+			updated.ParentID = nil
+			if parentID, isInt := value.(int); isInt {
+				updated.ParentID = &parentID
+			} else if value != nil {
+				ok = false
+			}
+		default:
+			return fmt.Errorf("failed to update deck: unknown field %s", field)
+		}
+		if !ok {
+			return fmt.Errorf("failed to update deck: invalid value for %s", field)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	r.decks[id] = updated
+
+	return nil
+}
+
+// DeleteDeck deletes the deck, its sub-decks and their note memberships, the
+// same way the foreign keys do for the SQL drivers.
+func (r *MemoryDeckRepository) DeleteDeck(userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.get(userID, id); !ok {
+		return apperr.NotFound("deck with id %d not found", id)
+	}
+
+	pending := []int{id}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		for childID, deck := range r.decks {
+			if deck.ParentID != nil && *deck.ParentID == current {
+				pending = append(pending, childID)
+			}
+		}
+		delete(r.decks, current)
+		delete(r.members, current)
+		delete(r.owners, current)
+	}
+
+	return nil
+}
+
+// AddNoteToDeck does not check who owns the note, which lives in a separate
+// repository. The service looks the note up for the same user first.
+func (r *MemoryDeckRepository) AddNoteToDeck(userID, deckID, noteID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.get(userID, deckID); !ok {
+		return apperr.NotFound("deck with id %d not found", deckID)
+	}
+	if r.members[deckID] == nil {
+		r.members[deckID] = make(map[int]bool)
+	}
+	r.members[deckID][noteID] = true
+
+	return nil
+}
+
+func (r *MemoryDeckRepository) RemoveNoteFromDeck(userID, deckID, noteID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.get(userID, deckID); !ok || !r.members[deckID][noteID] {
+		return apperr.NotFound("note %d in deck %d not found", noteID, deckID)
+	}
+	delete(r.members[deckID], noteID)
+
+	return nil
+}
+
+// GetDeckNoteIDs returns the distinct ids of the notes in any of deckIDs.
+// Notes are stored in a separate repository, so ids of deleted notes are
+// only filtered out by the caller.
+func (r *MemoryDeckRepository) GetDeckNoteIDs(userID int, deckIDs []int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[int]bool)
+	ids := make([]int, 0)
+	for _, deckID := range deckIDs {
+		if _, ok := r.get(userID, deckID); !ok {
+			continue
+		}
+		for noteID := range r.members[deckID] {
+			if !seen[noteID] {
+				seen[noteID] = true
+				ids = append(ids, noteID)
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	return ids, nil
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type MemoryFlashcardRepository struct {
+	mu     sync.RWMutex
+	cards  map[int]*models.Flashcard
+	owners map[int]int
+	nextID int
+}
+
+func NewMemoryFlashcardRepository() *MemoryFlashcardRepository {
+	return &MemoryFlashcardRepository{
+		cards:  make(map[int]*models.Flashcard),
+		owners: make(map[int]int),
+		nextID: 1,
+	}
+}
+
+// copyFlashcard returns a deep copy so callers never share the stored tags slice.
+func copyFlashcard(card *models.Flashcard) *models.Flashcard {
+	copied := *card
+	copied.Tags = append([]string{}, card.Tags...)
+	if card.NoteID != nil {
+		noteID := *card.NoteID
+		copied.NoteID = &noteID
+	}
+	return &copied
+}
+
+func (r *MemoryFlashcardRepository) CreateFlashcard(userID int, card *models.Flashcard) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insertFlashcard(userID, card, time.Now())
+	return nil
+}
+
+func (r *MemoryFlashcardRepository) CreateFlashcards(userID int, cards []*models.Flashcard) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, card := range cards {
+		r.insertFlashcard(userID, card, now)
+	}
+	return nil
+}
+
+func (r *MemoryFlashcardRepository) insertFlashcard(userID int, card *models.Flashcard, now time.Time) {
+	card.ID = r.nextID
+	card.CreatedAt = now
+	card.UpdatedAt = now
+	if card.Tags == nil {
+		card.Tags = []string{}
+	}
+	r.nextID++
+
+	r.cards[card.ID] = copyFlashcard(card)
+	r.owners[card.ID] = userID
+}
+
+// get returns the stored card if it belongs to userID. Callers must hold
+// the lock.
+func (r *MemoryFlashcardRepository) get(userID, id int) (*models.Flashcard, bool) {
+	stored, ok := r.cards[id]
+	if !ok || r.owners[id] != userID {
+		return nil, false
+	}
+	return stored, true
+}
+
+func (r *MemoryFlashcardRepository) GetFlashcardByID(userID, id int) (*models.Flashcard, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("flashcard with id %d not found", id)
+	}
+
+	return copyFlashcard(stored), nil
+}
+
+func (r *MemoryFlashcardRepository) GetAllFlashcards(userID int) ([]*models.Flashcard, error) {
+	return r.filterFlashcards(userID, func(*models.Flashcard) bool { return true }), nil
+}
+
+func (r *MemoryFlashcardRepository) ListFlashcards(userID int, opts models.ListOptions) (*models.Page[*models.Flashcard], error) {
+	cards := r.filterFlashcards(userID, func(card *models.Flashcard) bool {
+		return matchesList(opts, flashcardListKey(card), card.Front, card.Back)
+	})
+	return pageMemory(cards, opts, flashcardListKey), nil
+}
+
+func (r *MemoryFlashcardRepository) GetFlashcardsByNoteID(userID, noteID int) ([]*models.Flashcard, error) {
+	return r.filterFlashcards(userID, func(card *models.Flashcard) bool {
+		return card.NoteID != nil && *card.NoteID == noteID
+	}), nil
+}
+
+func (r *MemoryFlashcardRepository) filterFlashcards(userID int, keep func(*models.Flashcard) bool) []*models.Flashcard {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cards := make([]*models.Flashcard, 0)
+	for _, stored := range r.cards {
+		if r.owners[stored.ID] == userID && keep(stored) {
+			cards = append(cards, copyFlashcard(stored))
+		}
+	}
+
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].CreatedAt.Equal(cards[j].CreatedAt) {
+			return cards[i].ID > cards[j].ID
+		}
+		return cards[i].CreatedAt.After(cards[j].CreatedAt)
+	})
+
+	return cards
+}
+
+func (r *MemoryFlashcardRepository) UpdateFlashcard(userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return apperr.Validation("", "no updates provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return apperr.NotFound("flashcard with id %d not found", id)
+	}
+
+	updated := copyFlashcard(stored)
+	for field, value := range updates {
+		var ok bool
+		switch field {
+		case "front":
+			updated.Front, ok = value.(string)
+		case "back":
+			updated.Back, ok = value.(string)
+		case "tags":
+			var tags []string
+			tags, ok = value.([]string)
+			updated.Tags = append([]string{}, tags...)
+		default:
+			return fmt.Errorf("failed to update flashcard: unknown field %s", field)
+		}
+		if !ok {
+			return fmt.Errorf("failed to update flashcard: invalid value for %s", field)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	r.cards[id] = updated
+
+	return nil
+}
+
+func (r *MemoryFlashcardRepository) DeleteFlashcard(userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.get(userID, id); !ok {
+		return apperr.NotFound("flashcard with id %d not found", id)
+	}
+	delete(r.cards, id)
+	delete(r.owners, id)
+
+	return nil
+}
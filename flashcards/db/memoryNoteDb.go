@@ -0,0 +1,423 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/search"
+)
+
+type MemoryNoteRepository struct {
+	mu        sync.RWMutex
+	notes     map[int]*models.Note
+	owners    map[int]int
+	revisions map[int][]models.NoteRevision
+	nextID    int
+	// tags is the tag repository of the same store, which new notes are
+	// tagged in.
+	tags *MemoryTagRepository
+}
+
+func NewMemoryNoteRepository() *MemoryNoteRepository {
+	return &MemoryNoteRepository{
+		notes:     make(map[int]*models.Note),
+		owners:    make(map[int]int),
+		revisions: make(map[int][]models.NoteRevision),
+		nextID:    1,
+	}
+}
+
+// CreateNote saves the note with its tags.
+func (r *MemoryNoteRepository) CreateNote(userID int, note *models.Note) error {
+	r.create(userID, note)
+
+	// The tag repository reads the notes under its own lock, so it is only
+	// called once the notes are unlocked. Neither step can fail.
+	if r.tags != nil {
+		r.tags.SetNoteTags(userID, note.ID, note.Tags)
+	}
+
+	return nil
+}
+
+func (r *MemoryNoteRepository) create(userID int, note *models.Note) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	note.ID = r.nextID
+	note.CreatedAt = now
+	note.UpdatedAt = now
+	note.Version = 1
+	r.nextID++
+
+	stored := *note
+	r.notes[note.ID] = &stored
+	r.owners[note.ID] = userID
+	r.addRevision(&stored)
+}
+
+// ImportNotes saves the notes with their tags at once. A note whose content
+// a note of the user outside the trash already has, including one saved
+// earlier in the same call, is not saved and gets the ID of that note
+// instead, whose tags are left as they are.
+func (r *MemoryNoteRepository) ImportNotes(userID int, notes []*models.Note) ([]bool, error) {
+	created := r.importNotes(userID, notes)
+
+	// The tag repository reads the notes under its own lock, so it is only
+	// called once the notes are unlocked. Neither step can fail.
+	if r.tags != nil {
+		for i, note := range notes {
+			if created[i] {
+				r.tags.SetNoteTags(userID, note.ID, note.Tags)
+			}
+		}
+	}
+
+	return created, nil
+}
+
+func (r *MemoryNoteRepository) importNotes(userID int, notes []*models.Note) []bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := make(map[string]int)
+	for id, stored := range r.notes {
+		if r.owners[id] == userID && stored.DeletedAt == nil {
+			existing[stored.Content] = id
+		}
+	}
+
+	now := time.Now()
+	created := make([]bool, len(notes))
+	for i, note := range notes {
+		if id, ok := existing[note.Content]; ok {
+			note.ID = id
+			continue
+		}
+
+		note.ID = r.nextID
+		note.CreatedAt = now
+		note.UpdatedAt = now
+		note.Version = 1
+		r.nextID++
+
+		stored := *note
+		r.notes[note.ID] = &stored
+		r.owners[note.ID] = userID
+		r.addRevision(&stored)
+		existing[note.Content] = note.ID
+		created[i] = true
+	}
+
+	return created
+}
+
+// addRevision saves the content of note as its next revision. Callers must
+// hold the lock.
+func (r *MemoryNoteRepository) addRevision(note *models.Note) {
+	r.revisions[note.ID] = append(r.revisions[note.ID], models.NoteRevision{
+		NoteID:    note.ID,
+		Revision:  len(r.revisions[note.ID]) + 1,
+		Content:   note.Content,
+		CreatedAt: note.UpdatedAt,
+	})
+}
+
+// get returns the stored note if it belongs to userID and is not in the
+// trash. Callers must hold the lock.
+func (r *MemoryNoteRepository) get(userID, id int) (*models.Note, bool) {
+	stored, ok := r.notes[id]
+	if !ok || r.owners[id] != userID || stored.DeletedAt != nil {
+		return nil, false
+	}
+	return stored, true
+}
+
+// getVersion is get for a write conditional on the version of the note,
+// where 0 matches any version. Callers must hold the lock.
+func (r *MemoryNoteRepository) getVersion(userID, id, version int) (*models.Note, error) {
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("note with id %d not found", id)
+	}
+	if version > 0 && stored.Version != version {
+		return nil, apperr.PreconditionFailed("note with id %d has been modified, it is at version %d", id, stored.Version)
+	}
+	return stored, nil
+}
+
+// live reports whether the note exists and is not in the trash.
+func (r *MemoryNoteRepository) live(id int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.notes[id]
+	return ok && stored.DeletedAt == nil
+}
+
+func (r *MemoryNoteRepository) GetNoteByID(userID, id int) (*models.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("note with id %d not found", id)
+	}
+
+	note := *stored
+	return &note, nil
+}
+
+func (r *MemoryNoteRepository) ListNotes(userID int, opts models.NoteListOptions) (*models.Page[*models.Note], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notes := make([]*models.Note, 0, len(r.notes))
+	for _, stored := range r.notes {
+		if r.owners[stored.ID] != userID || stored.DeletedAt != nil || !matchesList(opts.ListOptions, noteListKey(stored), stored.Content) {
+			continue
+		}
+		note := *stored
+		notes = append(notes, &note)
+	}
+
+	return pageMemory(notes, opts.ListOptions, noteListKey), nil
+}
+
+// UpdateNote applies the updates and bumps the version of the note. Unless
+// version is 0, the note is only updated while it is at version. New content
+// is saved as a new revision. A tags update replaces the tags of the note
+// at once.
+func (r *MemoryNoteRepository) UpdateNote(userID, id int, updates map[string]any, version int) error {
+	if err := r.update(userID, id, updates, version); err != nil {
+		return err
+	}
+
+	// Like in CreateNote, the tags are set once the notes are unlocked.
+	if tags, ok := updates[tagsUpdate].([]string); ok && r.tags != nil {
+		r.tags.SetNoteTags(userID, id, tags)
+	}
+
+	return nil
+}
+
+func (r *MemoryNoteRepository) update(userID, id int, updates map[string]any, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.getVersion(userID, id, version)
+	if err != nil {
+		return err
+	}
+
+	updated := *stored
+	for field, value := range updates {
+		var ok bool
+		switch field {
+		case "content":
+			updated.Content, ok = value.(string)
+		case tagsUpdate:
+			_, ok = value.([]string)
+		default:
+			return fmt.Errorf("failed to update note: unknown field %s", field)
+		}
+		if !ok {
+			return fmt.Errorf("failed to update note: invalid value for %s", field)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	updated.Version++
+	r.notes[id] = &updated
+	if _, ok := updates["content"]; ok {
+		r.addRevision(&updated)
+	}
+
+	return nil
+}
+
+// DeleteNote moves the note to the trash. Unless version is 0, only while
+// the note is at version.
+func (r *MemoryNoteRepository) DeleteNote(userID, id, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.getVersion(userID, id, version)
+	if err != nil {
+		return err
+	}
+
+	deleted := *stored
+	now := time.Now()
+	deleted.DeletedAt = &now
+	r.notes[id] = &deleted
+
+	return nil
+}
+
+func (r *MemoryNoteRepository) RestoreNote(userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.notes[id]
+	if !ok || r.owners[id] != userID || stored.DeletedAt == nil {
+		return apperr.NotFound("note with id %d not found in trash", id)
+	}
+
+	restored := *stored
+	restored.DeletedAt = nil
+	r.notes[id] = &restored
+
+	return nil
+}
+
+// ListDeletedNotes returns the notes in the trash, most recently deleted
+// first.
+func (r *MemoryNoteRepository) ListDeletedNotes(userID int) ([]*models.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notes := make([]*models.Note, 0)
+	for _, stored := range r.notes {
+		if r.owners[stored.ID] != userID || stored.DeletedAt == nil {
+			continue
+		}
+		note := *stored
+		notes = append(notes, &note)
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].DeletedAt.Equal(*notes[j].DeletedAt) {
+			return notes[i].DeletedAt.After(*notes[j].DeletedAt)
+		}
+		return notes[i].ID > notes[j].ID
+	})
+
+	return notes, nil
+}
+
+// PurgeNotes removes the notes of every user that have been in the trash
+// for longer than olderThan.
+func (r *MemoryNoteRepository) PurgeNotes(olderThan time.Duration) ([]PurgedItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	purged := make([]PurgedItem, 0)
+	for id, stored := range r.notes {
+		if stored.DeletedAt == nil || !stored.DeletedAt.Before(cutoff) {
+			continue
+		}
+		purged = append(purged, PurgedItem{UserID: r.owners[id], ID: id})
+		delete(r.notes, id)
+		delete(r.owners, id)
+		delete(r.revisions, id)
+	}
+
+	return purged, nil
+}
+
+// SearchNotes ranks notes in process with BM25. Every query word has to
+// match, either exactly or as a prefix of a word in the note.
+func (r *MemoryNoteRepository) SearchNotes(userID int, query string, limit int) ([]*models.NoteSearchResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	docs := make([]search.Document, 0, len(r.notes))
+	for _, stored := range r.notes {
+		if r.owners[stored.ID] != userID || stored.DeletedAt != nil {
+			continue
+		}
+		docs = append(docs, search.Document{ID: stored.ID, Content: stored.Content})
+	}
+	// Map iteration order is random, keep ties stable by newest note first.
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].ID > docs[j].ID
+	})
+
+	matches := search.RankDocuments(docs, query)
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := make([]*models.NoteSearchResult, 0, len(matches))
+	for _, match := range matches {
+		note := *r.notes[match.ID]
+		results = append(results, &models.NoteSearchResult{
+			Note:    &note,
+			Score:   match.Score,
+			Snippet: search.Snippet(note.Content, query),
+		})
+	}
+
+	return results, nil
+}
+
+// ListNoteRevisions returns the revisions of a note, newest first.
+func (r *MemoryNoteRepository) ListNoteRevisions(userID, noteID int) ([]*models.NoteRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.get(userID, noteID); !ok {
+		return nil, apperr.NotFound("note with id %d not found", noteID)
+	}
+
+	stored := r.revisions[noteID]
+	revisions := make([]*models.NoteRevision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		rev := stored[i]
+		revisions = append(revisions, &rev)
+	}
+
+	return revisions, nil
+}
+
+func (r *MemoryNoteRepository) GetNoteRevision(userID, noteID, revision int) (*models.NoteRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.revision(userID, noteID, revision)
+	if !ok {
+		return nil, apperr.NotFound("revision %d of note %d not found", revision, noteID)
+	}
+
+	rev := *stored
+	return &rev, nil
+}
+
+// RestoreNoteRevision sets the content of the note back to the one of
+// revision, saved as a new revision so that the history is never rewritten.
+func (r *MemoryNoteRepository) RestoreNoteRevision(userID, noteID, revision int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rev, ok := r.revision(userID, noteID, revision)
+	if !ok {
+		return apperr.NotFound("revision %d of note %d not found", revision, noteID)
+	}
+
+	restored := *r.notes[noteID]
+	restored.Content = rev.Content
+	restored.UpdatedAt = time.Now()
+	restored.Version++
+	r.notes[noteID] = &restored
+	r.addRevision(&restored)
+
+	return nil
+}
+
+// revision returns a stored revision of a note that belongs to userID and is
+// not in the trash. Callers must hold the lock.
+func (r *MemoryNoteRepository) revision(userID, noteID, revision int) (*models.NoteRevision, bool) {
+	if _, ok := r.get(userID, noteID); !ok {
+		return nil, false
+	}
+	revisions := r.revisions[noteID]
+	if revision <= 0 || revision > len(revisions) {
+		return nil, false
+	}
+	return &revisions[revision-1], true
+}
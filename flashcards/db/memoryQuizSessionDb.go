@@ -0,0 +1,145 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type MemoryQuizSessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]*models.QuizSession
+	owners   map[string]int
+}
+
+func NewMemoryQuizSessionRepository() *MemoryQuizSessionRepository {
+	return &MemoryQuizSessionRepository{
+		sessions: make(map[string]*models.QuizSession),
+		owners:   make(map[string]int),
+	}
+}
+
+func copyQuizSession(session *models.QuizSession) *models.QuizSession {
+	copied := *session
+	copied.NoteIDs = append([]int{}, session.NoteIDs...)
+	copied.Messages = make([]models.Message, len(session.Messages))
+	for i, message := range session.Messages {
+		copied.Messages[i] = message
+		if message.Grade != nil {
+			grade := *message.Grade
+			copied.Messages[i].Grade = &grade
+		}
+	}
+	return &copied
+}
+
+func (r *MemoryQuizSessionRepository) CreateQuizSession(userID int, session *models.QuizSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sessions[session.ID]; exists {
+		return fmt.Errorf("failed to create quiz session: id %s already exists", session.ID)
+	}
+
+	now := time.Now()
+	session.CreatedAt = now
+	session.UpdatedAt = now
+	r.sessions[session.ID] = copyQuizSession(session)
+	r.owners[session.ID] = userID
+
+	return nil
+}
+
+// get returns the stored session if it belongs to userID. Callers must hold
+// the lock.
+func (r *MemoryQuizSessionRepository) get(userID int, id string) (*models.QuizSession, bool) {
+	stored, ok := r.sessions[id]
+	if !ok || r.owners[id] != userID {
+		return nil, false
+	}
+	return stored, true
+}
+
+func (r *MemoryQuizSessionRepository) GetQuizSessionByID(userID int, id string) (*models.QuizSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	return copyQuizSession(stored), nil
+}
+
+// ListQuizSessions returns up to limit sessions, oldest first, starting after
+// the session with id after, or at the first session when after is empty.
+func (r *MemoryQuizSessionRepository) ListQuizSessions(userID int, after string, limit int) ([]*models.QuizSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := make([]*models.QuizSession, 0)
+	for id, stored := range r.sessions {
+		if r.owners[id] == userID {
+			sessions = append(sessions, stored)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return quizSessionLess(sessions[i], sessions[j])
+	})
+
+	start := 0
+	if last, ok := r.get(userID, after); ok {
+		start = sort.Search(len(sessions), func(i int) bool {
+			return quizSessionLess(last, sessions[i])
+		})
+	}
+
+	page := make([]*models.QuizSession, 0, limit)
+	for _, session := range sessions[start:min(start+limit, len(sessions))] {
+		page = append(page, copyQuizSession(session))
+	}
+	return page, nil
+}
+
+func quizSessionLess(a, b *models.QuizSession) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID < b.ID
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+func (r *MemoryQuizSessionRepository) AppendQuizMessages(userID int, id string, messages []models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	appended := copyQuizSession(&models.QuizSession{Messages: messages})
+	stored.Messages = append(stored.Messages, appended.Messages...)
+	stored.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (r *MemoryQuizSessionRepository) UpdateQuizSessionStatus(userID int, id string, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	stored.Status = status
+	stored.UpdatedAt = time.Now()
+
+	return nil
+}
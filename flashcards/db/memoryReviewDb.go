@@ -0,0 +1,104 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryReviewRepository struct {
+	mu        sync.RWMutex
+	states    map[int]*models.ReviewState
+	owners    map[int]int
+	logs      []*models.ReviewLog
+	nextID    int
+	nextLogID int
+}
+
+func NewMemoryReviewRepository() *MemoryReviewRepository {
+	return &MemoryReviewRepository{
+		states:    make(map[int]*models.ReviewState),
+		owners:    make(map[int]int),
+		nextID:    1,
+		nextLogID: 1,
+	}
+}
+
+func copyReviewState(state *models.ReviewState) *models.ReviewState {
+	copied := *state
+	if state.LastReviewedAt != nil {
+		lastReviewedAt := *state.LastReviewedAt
+		copied.LastReviewedAt = &lastReviewedAt
+	}
+	return &copied
+}
+
+func (r *MemoryReviewRepository) findState(userID int, itemType string, itemID int) *models.ReviewState {
+	for _, state := range r.states {
+		if r.owners[state.ID] == userID && state.ItemType == itemType && state.ItemID == itemID {
+			return state
+		}
+	}
+	return nil
+}
+
+func (r *MemoryReviewRepository) GetReviewState(userID int, itemType string, itemID int) (*models.ReviewState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	state := r.findState(userID, itemType, itemID)
+	if state == nil {
+		return nil, nil
+	}
+
+	return copyReviewState(state), nil
+}
+
+func (r *MemoryReviewRepository) GetReviewStates(userID int, itemType string) ([]*models.ReviewState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make([]*models.ReviewState, 0)
+	for _, state := range r.states {
+		if r.owners[state.ID] == userID && state.ItemType == itemType {
+			states = append(states, copyReviewState(state))
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].DueAt.Equal(states[j].DueAt) {
+			return states[i].ID < states[j].ID
+		}
+		return states[i].DueAt.Before(states[j].DueAt)
+	})
+
+	return states, nil
+}
+
+func (r *MemoryReviewRepository) SaveReview(userID int, state *models.ReviewState, entry *models.ReviewLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing := r.findState(userID, state.ItemType, state.ItemID); existing != nil {
+		state.ID = existing.ID
+		state.CreatedAt = existing.CreatedAt
+	} else {
+		state.ID = r.nextID
+		state.CreatedAt = now
+		r.nextID++
+	}
+	state.UpdatedAt = now
+	r.states[state.ID] = copyReviewState(state)
+	r.owners[state.ID] = userID
+
+	entry.ID = r.nextLogID
+	entry.StateID = state.ID
+	r.nextLogID++
+	stored := *entry
+	r.logs = append(r.logs, &stored)
+
+	return nil
+}
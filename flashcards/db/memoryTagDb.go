@@ -0,0 +1,260 @@
+package db
+
+import (
+	"sort"
+	"sync"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+// memoryTags holds the tag names of each note and todo of one user.
+type memoryTags struct {
+	notes map[int]map[string]bool
+	todos map[int]map[string]bool
+}
+
+// liveItems tells whether an item exists and is not in the trash.
+type liveItems interface {
+	live(id int) bool
+}
+
+// MemoryTagRepository keeps the tag names of each note and todo, per user. A
+// tag exists as long as some note or todo of its user uses it, like the
+// pruned tags table of the SQL drivers. Notes and todos in the trash are
+// left out when finding and counting, like the SQL drivers do with a join.
+type MemoryTagRepository struct {
+	mu    sync.RWMutex
+	users map[int]*memoryTags
+	notes liveItems
+	todos liveItems
+}
+
+func NewMemoryTagRepository(notes *MemoryNoteRepository, todos *MemoryTodoRepository) *MemoryTagRepository {
+	return &MemoryTagRepository{
+		users: make(map[int]*memoryTags),
+		notes: notes,
+		todos: todos,
+	}
+}
+
+// tags returns the tags of userID, creating them if needed. Callers must
+// hold the lock.
+func (r *MemoryTagRepository) tags(userID int) *memoryTags {
+	if r.users[userID] == nil {
+		r.users[userID] = &memoryTags{
+			notes: make(map[int]map[string]bool),
+			todos: make(map[int]map[string]bool),
+		}
+	}
+	return r.users[userID]
+}
+
+func (r *MemoryTagRepository) SetNoteTags(userID, noteID int, tags []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	setTags(r.tags(userID).notes, noteID, tags)
+	return nil
+}
+
+func (r *MemoryTagRepository) SetTodoTags(userID, todoID int, tags []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	setTags(r.tags(userID).todos, todoID, tags)
+	return nil
+}
+
+func (r *MemoryTagRepository) GetNoteTags(userID int, noteIDs []int) (map[int][]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.users[userID] == nil {
+		return map[int][]string{}, nil
+	}
+	return getTags(r.users[userID].notes, noteIDs), nil
+}
+
+func (r *MemoryTagRepository) GetTodoTags(userID int, todoIDs []int) (map[int][]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.users[userID] == nil {
+		return map[int][]string{}, nil
+	}
+	return getTags(r.users[userID].todos, todoIDs), nil
+}
+
+func (r *MemoryTagRepository) FindNoteIDs(userID int, tags []string, matchAll bool) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.users[userID] == nil {
+		return []int{}, nil
+	}
+	return findIDs(r.users[userID].notes, r.notes, tags, matchAll), nil
+}
+
+func (r *MemoryTagRepository) FindTodoIDs(userID int, tags []string, matchAll bool) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.users[userID] == nil {
+		return []int{}, nil
+	}
+	return findIDs(r.users[userID].todos, r.todos, tags, matchAll), nil
+}
+
+func setTags(items map[int]map[string]bool, itemID int, tags []string) {
+	if len(tags) == 0 {
+		delete(items, itemID)
+		return
+	}
+
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	items[itemID] = set
+}
+
+func getTags(items map[int]map[string]bool, itemIDs []int) map[int][]string {
+	tags := make(map[int][]string)
+	for _, id := range itemIDs {
+		if len(items[id]) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(items[id]))
+		for name := range items[id] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		tags[id] = names
+	}
+
+	return tags
+}
+
+func findIDs(items map[int]map[string]bool, live liveItems, tags []string, matchAll bool) []int {
+	ids := make([]int, 0)
+	for id, set := range items {
+		if !live.live(id) {
+			continue
+		}
+		matches := 0
+		for _, tag := range tags {
+			if set[tag] {
+				matches++
+			}
+		}
+		if (matchAll && matches == len(tags)) || (!matchAll && matches > 0) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	return ids
+}
+
+func (r *MemoryTagRepository) GetAllTags(userID int) ([]*models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make([]*models.Tag, 0)
+	user := r.users[userID]
+	if user == nil {
+		return tags, nil
+	}
+
+	counts := make(map[string]*models.Tag)
+	count := func(name string) *models.Tag {
+		if counts[name] == nil {
+			counts[name] = &models.Tag{Name: name}
+		}
+		return counts[name]
+	}
+	for id, set := range user.notes {
+		if !r.notes.live(id) {
+			continue
+		}
+		for name := range set {
+			count(name).NoteCount++
+		}
+	}
+	for id, set := range user.todos {
+		if !r.todos.live(id) {
+			continue
+		}
+		for name := range set {
+			count(name).TodoCount++
+		}
+	}
+
+	for _, tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+func (r *MemoryTagRepository) RenameTag(userID int, name, newName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.tags(userID)
+	if user.used(newName) {
+		return apperr.Conflict("tag %q already exists", newName)
+	}
+	if !user.used(name) {
+		return apperr.NotFound("tag %q not found", name)
+	}
+
+	user.replace([]string{name}, newName)
+
+	return nil
+}
+
+func (r *MemoryTagRepository) MergeTags(userID int, sources []string, target string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.tags(userID)
+	for _, source := range sources {
+		if !user.used(source) {
+			return apperr.NotFound("tag %q not found", source)
+		}
+	}
+
+	user.replace(sources, target)
+
+	return nil
+}
+
+func (t *memoryTags) used(name string) bool {
+	for _, items := range []map[int]map[string]bool{t.notes, t.todos} {
+		for _, set := range items {
+			if set[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replace swaps every tag in names for target.
+func (t *memoryTags) replace(names []string, target string) {
+	for _, items := range []map[int]map[string]bool{t.notes, t.todos} {
+		for _, set := range items {
+			for _, name := range names {
+				if set[name] {
+					delete(set, name)
+					set[target] = true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type MemoryTodoRepository struct {
+	mu     sync.RWMutex
+	todos  map[int]*models.Todo
+	owners map[int]int
+	nextID int
+	// tags is the tag repository of the same store, which new todos are
+	// tagged in.
+	tags *MemoryTagRepository
+}
+
+func NewMemoryTodoRepository() *MemoryTodoRepository {
+	return &MemoryTodoRepository{
+		todos:  make(map[int]*models.Todo),
+		owners: make(map[int]int),
+		nextID: 1,
+	}
+}
+
+// CreateTodo saves the todo with its tags.
+func (r *MemoryTodoRepository) CreateTodo(userID int, todo *models.Todo) error {
+	r.mu.Lock()
+	r.create(userID, todo)
+	r.mu.Unlock()
+
+	// The tag repository reads the todos under its own lock, so it is only
+	// called once the todos are unlocked. Neither step can fail.
+	if r.tags != nil {
+		r.tags.SetTodoTags(userID, todo.ID, todo.Tags)
+	}
+
+	return nil
+}
+
+// create stores a new todo. Callers must hold the lock.
+func (r *MemoryTodoRepository) create(userID int, todo *models.Todo) {
+	now := time.Now()
+	todo.ID = r.nextID
+	todo.CreatedAt = now
+	todo.UpdatedAt = now
+	todo.Version = 1
+	r.nextID++
+
+	stored := *todo
+	r.todos[todo.ID] = &stored
+	r.owners[todo.ID] = userID
+}
+
+// get returns the stored todo if it belongs to userID and is not in the
+// trash. Callers must hold the lock.
+func (r *MemoryTodoRepository) get(userID, id int) (*models.Todo, bool) {
+	stored, ok := r.todos[id]
+	if !ok || r.owners[id] != userID || stored.DeletedAt != nil {
+		return nil, false
+	}
+	return stored, true
+}
+
+// getVersion is get for a write conditional on the version of the todo,
+// where 0 matches any version. Callers must hold the lock.
+func (r *MemoryTodoRepository) getVersion(userID, id, version int) (*models.Todo, error) {
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("todo with id %d not found", id)
+	}
+	if version > 0 && stored.Version != version {
+		return nil, apperr.PreconditionFailed("todo with id %d has been modified, it is at version %d", id, stored.Version)
+	}
+	return stored, nil
+}
+
+// live reports whether the todo exists and is not in the trash.
+func (r *MemoryTodoRepository) live(id int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.todos[id]
+	return ok && stored.DeletedAt == nil
+}
+
+func (r *MemoryTodoRepository) GetTodoByID(userID, id int) (*models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.get(userID, id)
+	if !ok {
+		return nil, apperr.NotFound("todo with id %d not found", id)
+	}
+
+	todo := *stored
+	return &todo, nil
+}
+
+func (r *MemoryTodoRepository) ListTodos(userID int, opts models.TodoListOptions) (*models.Page[*models.Todo], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := make([]*models.Todo, 0, len(r.todos))
+	for _, stored := range r.todos {
+		if r.owners[stored.ID] != userID || stored.DeletedAt != nil || !matchesList(opts.ListOptions, todoListKey(stored), stored.Title, stored.Description) {
+			continue
+		}
+		if opts.Completed != nil && stored.Completed != *opts.Completed {
+			continue
+		}
+		if opts.Priority != "" && stored.Priority != opts.Priority {
+			continue
+		}
+		if (opts.DueAfter != nil || opts.DueBefore != nil) && stored.DueAt == nil {
+			continue
+		}
+		if opts.DueAfter != nil && stored.DueAt.Before(*opts.DueAfter) {
+			continue
+		}
+		if opts.DueBefore != nil && !stored.DueAt.Before(*opts.DueBefore) {
+			continue
+		}
+		todo := *stored
+		todos = append(todos, &todo)
+	}
+
+	return pageMemory(todos, opts.ListOptions, todoListKey), nil
+}
+
+// UpdateTodo applies the updates and bumps the version of the todo. Unless
+// version is 0, the todo is only updated while it is at version. A tags
+// update replaces the tags of the todo at once.
+func (r *MemoryTodoRepository) UpdateTodo(userID, id int, updates map[string]any, version int) error {
+	r.mu.Lock()
+	err := r.update(userID, id, updates, version)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Like in CreateTodo, the tags are set once the todos are unlocked.
+	r.setUpdatedTags(userID, id, updates)
+	return nil
+}
+
+// CompleteTodo applies updates like UpdateTodo and creates next, the next
+// todo of a recurring one, with its tags at once.
+func (r *MemoryTodoRepository) CompleteTodo(userID, id int, updates map[string]any, version int, next *models.Todo) error {
+	r.mu.Lock()
+	err := r.update(userID, id, updates, version)
+	if err == nil {
+		r.create(userID, next)
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	r.setUpdatedTags(userID, id, updates)
+	if r.tags != nil {
+		r.tags.SetTodoTags(userID, next.ID, next.Tags)
+	}
+	return nil
+}
+
+// setUpdatedTags applies the tags update in updates, if there is one.
+func (r *MemoryTodoRepository) setUpdatedTags(userID, id int, updates map[string]any) {
+	if tags, ok := updates[tagsUpdate].([]string); ok && r.tags != nil {
+		r.tags.SetTodoTags(userID, id, tags)
+	}
+}
+
+// update applies updates to a stored todo. Callers must hold the lock.
+func (r *MemoryTodoRepository) update(userID, id int, updates map[string]any, version int) error {
+	stored, err := r.getVersion(userID, id, version)
+	if err != nil {
+		return err
+	}
+
+	updated := *stored
+	for field, value := range updates {
+		var ok bool
+		switch field {
+		case "title":
+			updated.Title, ok = value.(string)
+		case "description":
+			updated.Description, ok = value.(string)
+		case "completed":
+			updated.Completed, ok = value.(bool)
+		case "dueAt":
+			updated.DueAt, ok = value.(*time.Time)
+		case "priority":
+			updated.Priority, ok = value.(string)
+		case "completedAt":
+			updated.CompletedAt, ok = value.(*time.Time)
+		case "recurrence":
+			updated.Recurrence, ok = value.(string)
+		case tagsUpdate:
+			_, ok = value.([]string)
+		default:
+			return fmt.Errorf("failed to update todo: unknown field %s", field)
+		}
+		if !ok {
+			return fmt.Errorf("failed to update todo: invalid value for %s", field)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	updated.Version++
+	r.todos[id] = &updated
+
+	return nil
+}
+
+// DeleteTodo moves the todo to the trash. Unless version is 0, only while
+// the todo is at version.
+func (r *MemoryTodoRepository) DeleteTodo(userID, id, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.getVersion(userID, id, version)
+	if err != nil {
+		return err
+	}
+
+	deleted := *stored
+	now := time.Now()
+	deleted.DeletedAt = &now
+	r.todos[id] = &deleted
+
+	return nil
+}
+
+func (r *MemoryTodoRepository) RestoreTodo(userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.todos[id]
+	if !ok || r.owners[id] != userID || stored.DeletedAt == nil {
+		return apperr.NotFound("todo with id %d not found in trash", id)
+	}
+
+	restored := *stored
+	restored.DeletedAt = nil
+	r.todos[id] = &restored
+
+	return nil
+}
+
+// ListDeletedTodos returns the todos in the trash, most recently deleted
+// first.
+func (r *MemoryTodoRepository) ListDeletedTodos(userID int) ([]*models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := make([]*models.Todo, 0)
+	for _, stored := range r.todos {
+		if r.owners[stored.ID] != userID || stored.DeletedAt == nil {
+			continue
+		}
+		todo := *stored
+		todos = append(todos, &todo)
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].DeletedAt.Equal(*todos[j].DeletedAt) {
+			return todos[i].DeletedAt.After(*todos[j].DeletedAt)
+		}
+		return todos[i].ID > todos[j].ID
+	})
+
+	return todos, nil
+}
+
+// PurgeTodos removes the todos of every user that have been in the trash
+// for longer than olderThan.
+func (r *MemoryTodoRepository) PurgeTodos(olderThan time.Duration) ([]PurgedItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	purged := make([]PurgedItem, 0)
+	for id, stored := range r.todos {
+		if stored.DeletedAt == nil || !stored.DeletedAt.Before(cutoff) {
+			continue
+		}
+		purged = append(purged, PurgedItem{UserID: r.owners[id], ID: id})
+		delete(r.todos, id)
+		delete(r.owners, id)
+	}
+
+	return purged, nil
+}
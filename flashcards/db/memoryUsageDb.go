@@ -0,0 +1,46 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+type MemoryLLMUsageRepository struct {
+	mu sync.RWMutex
+	// usage holds the tokens spent per user and day.
+	usage map[int]map[string]int
+}
+
+func NewMemoryLLMUsageRepository() *MemoryLLMUsageRepository {
+	return &MemoryLLMUsageRepository{
+		usage: make(map[int]map[string]int),
+	}
+}
+
+func (r *MemoryLLMUsageRepository) AddLLMUsage(userID int, at time.Time, promptTokens, completionTokens int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.usage[userID] == nil {
+		r.usage[userID] = make(map[string]int)
+	}
+	r.usage[userID][at.UTC().Format(usageDayLayout)] += promptTokens + completionTokens
+
+	return nil
+}
+
+func (r *MemoryLLMUsageRepository) GetLLMUsage(userID int, since time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// The layout sorts like the days it formats.
+	from := since.UTC().Format(usageDayLayout)
+	used := 0
+	for day, tokens := range r.usage[userID] {
+		if day >= from {
+			used += tokens
+		}
+	}
+
+	return used, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[int]*models.User
+	emails map[string]int
+	nextID int
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:  make(map[int]*models.User),
+		emails: make(map[string]int),
+		nextID: 1,
+	}
+}
+
+func (r *MemoryUserRepository) CreateUser(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.emails[user.Email]; ok {
+		return apperr.Conflict("user with email %q already exists", user.Email)
+	}
+
+	user.ID = r.nextID
+	user.CreatedAt = time.Now()
+	r.nextID++
+
+	stored := *user
+	r.users[user.ID] = &stored
+	r.emails[user.Email] = user.ID
+
+	return nil
+}
+
+func (r *MemoryUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.emails[email]
+	if !ok {
+		return nil, apperr.NotFound("user with email %q not found", email)
+	}
+
+	user := *r.users[id]
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetUserByID(id int) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.users[id]
+	if !ok {
+		return nil, apperr.NotFound("user with id %d not found", id)
+	}
+
+	user := *stored
+	return &user, nil
+}
@@ -0,0 +1,349 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockID keys the Postgres advisory lock held while migrating.
+const migrationLockID = 20250623
+
+// Migration is one versioned schema change, embedded from the files
+// migrations/<driver>/<version>_<name>.up.sql and .down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+
+	up   string
+	down string
+}
+
+// MigrationStatus tells whether a migration has been applied. Migrations
+// recorded in the database but unknown to this build are listed too.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// migrationDialect holds the SQL that differs between drivers. Postgres
+// holds an advisory lock while migrating and applies each migration in its
+// own transaction. SQLite has no advisory locks, so the whole run is one
+// immediate transaction, which keeps other writers out until it commits.
+type migrationDialect struct {
+	setup          string
+	list           string
+	insert         string
+	delete         string
+	lock           string
+	unlock         string
+	abort          string
+	txPerMigration bool
+}
+
+var migrationDialects = map[string]migrationDialect{
+	DriverPostgres: {
+		setup: `
+			CREATE SCHEMA IF NOT EXISTS gocourse;
+			CREATE TABLE IF NOT EXISTS gocourse.schema_migrations (
+				version BIGINT PRIMARY KEY,
+				name TEXT NOT NULL,
+				appliedAt TIMESTAMP NOT NULL
+			)`,
+		list:           `SELECT version, name, appliedAt FROM gocourse.schema_migrations ORDER BY version`,
+		insert:         `INSERT INTO gocourse.schema_migrations (version, name, appliedAt) VALUES ($1, $2, $3)`,
+		delete:         `DELETE FROM gocourse.schema_migrations WHERE version = $1`,
+		lock:           fmt.Sprintf(`SELECT pg_advisory_lock(%d)`, migrationLockID),
+		unlock:         fmt.Sprintf(`SELECT pg_advisory_unlock(%d)`, migrationLockID),
+		abort:          fmt.Sprintf(`SELECT pg_advisory_unlock(%d)`, migrationLockID),
+		txPerMigration: true,
+	},
+	DriverSQLite: {
+		setup: `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				appliedAt TIMESTAMP NOT NULL
+			)`,
+		list:   `SELECT version, name, appliedAt FROM schema_migrations ORDER BY version`,
+		insert: `INSERT INTO schema_migrations (version, name, appliedAt) VALUES (?, ?, ?)`,
+		delete: `DELETE FROM schema_migrations WHERE version = ?`,
+		lock:   `BEGIN IMMEDIATE`,
+		unlock: `COMMIT`,
+		abort:  `ROLLBACK`,
+	},
+}
+
+// Migrator applies and reverts the embedded migrations of a driver, keeping
+// track of them in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []Migration
+}
+
+func NewMigrator(driver string, conn *sql.DB) (*Migrator, error) {
+	dialect, ok := migrationDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("storage driver %q has no migrations", driver)
+	}
+
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         conn,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// loadMigrations reads the embedded migrations of driver, ordered by
+// version.
+func loadMigrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q, expected <version>_<name>.up.sql or .down.sql", fileName)
+		}
+		versionText, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionText, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q, expected <version>_<name>.up.sql or .down.sql", fileName)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names, %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones it
+// applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			err := m.run(ctx, conn, migration.up, m.dialect.insert, migration.Version, migration.Name, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("invalid number of migrations to revert: %d", steps)
+	}
+
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(ctx, conn, migration.down, m.dialect.delete, migration.Version); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reverted, nil
+}
+
+// Status lists every known or applied migration, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if record, ok := done[migration.Version]; ok {
+				status.AppliedAt = record.AppliedAt
+				delete(done, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, record := range done {
+			record.Unknown = true
+			statuses = append(statuses, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// locked runs fn on a single connection while holding the migration lock,
+// after making sure the schema_migrations table exists.
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, m.dialect.lock); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	_, err = conn.ExecContext(ctx, m.dialect.setup)
+	if err != nil {
+		err = fmt.Errorf("failed to create migrations table: %w", err)
+	} else {
+		err = fn(conn)
+	}
+	if err != nil {
+		// The context may be what failed, the lock must be released anyway.
+		if _, abortErr := conn.ExecContext(context.Background(), m.dialect.abort); abortErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to unlock migrations: %w", abortErr))
+		}
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialect.unlock); err != nil {
+		return fmt.Errorf("failed to unlock migrations: %w", err)
+	}
+	return nil
+}
+
+// applied returns the migrations recorded in the database by version.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]MigrationStatus, error) {
+	rows, err := conn.QueryContext(ctx, m.dialect.list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var status MigrationStatus
+		var appliedAt time.Time
+		if err := rows.Scan(&status.Version, &status.Name, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		status.AppliedAt = &appliedAt
+		applied[status.Version] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// run executes script and records it with the record statement, atomically.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	if !m.dialect.txPerMigration {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
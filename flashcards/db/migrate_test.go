@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"flashcards/models"
+)
+
+// supabaseSchema is what the first three Supabase CLI migrations created,
+// the schema of databases deployed before accounts existed.
+const supabaseSchema = `
+	CREATE SCHEMA IF NOT EXISTS gocourse;
+
+	CREATE TABLE IF NOT EXISTS gocourse.todos (
+		id SERIAL PRIMARY KEY,
+		title VARCHAR(255) NOT NULL,
+		description TEXT,
+		completed BOOLEAN DEFAULT FALSE,
+		createdAt TIMESTAMP DEFAULT NOW(),
+		updatedAt TIMESTAMP DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_todos_created_at ON gocourse.todos(createdAt);
+	CREATE INDEX IF NOT EXISTS idx_todos_completed ON gocourse.todos(completed);
+
+	CREATE TABLE IF NOT EXISTS gocourse.flashcards (
+		id SERIAL PRIMARY KEY,
+		content TEXT NOT NULL,
+		createdAt TIMESTAMP DEFAULT NOW(),
+		updatedAt TIMESTAMP DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_flashcards_created_at ON gocourse.flashcards(createdAt);
+
+	ALTER TABLE gocourse.flashcards RENAME TO notes;
+
+	INSERT INTO gocourse.todos (title) VALUES ('written before accounts');
+	INSERT INTO gocourse.notes (content) VALUES ('written before accounts');`
+
+func TestMigrateFromSupabaseSchema(t *testing.T) {
+	url := os.Getenv(postgresTestURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", postgresTestURLEnv)
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(`DROP SCHEMA IF EXISTS gocourse CASCADE`); err != nil {
+		t.Fatalf("failed to drop schema: %v", err)
+	}
+	if _, err := conn.Exec(supabaseSchema); err != nil {
+		t.Fatalf("failed to create the Supabase schema: %v", err)
+	}
+
+	migrator, err := NewMigrator(DriverPostgres, conn)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("failed to migrate the Supabase schema: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Errorf("applied %d of %d migrations", len(applied), len(migrator.migrations))
+	}
+
+	store := openTestStore(t, DriverPostgres, url)
+	userID := createTestUser(t, store)
+
+	note := &models.Note{Content: "The Magna Carta was sealed at Runnymede"}
+	if err := store.Notes.CreateNote(userID, note); err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+	if err := store.Tags.SetNoteTags(userID, note.ID, []string{"history"}); err != nil {
+		t.Fatalf("set tags: %v", err)
+	}
+	results, err := store.Notes.SearchNotes(userID, "runnymede", 10)
+	if err != nil {
+		t.Fatalf("search notes: %v", err)
+	}
+	if len(results) != 1 || results[0].Note.ID != note.ID {
+		t.Errorf("search found %d notes, want the new note", len(results))
+	}
+
+	// Rows written before accounts have no owner and stay out of sight.
+	page, err := store.Notes.ListNotes(userID, models.NoteListOptions{})
+	if err != nil {
+		t.Fatalf("list notes: %v", err)
+	}
+	if page.Total != 1 {
+		t.Errorf("listed %d notes, want only the new one", page.Total)
+	}
+	todo := &models.Todo{Title: "Review", Priority: models.PriorityNormal}
+	if err := store.Todos.CreateTodo(userID, todo); err != nil {
+		t.Fatalf("create todo: %v", err)
+	}
+}
+
+func TestMigrateDownAndUpAgain(t *testing.T) {
+	store := openTestStore(t, DriverSQLite, filepath.Join(t.TempDir(), "migrate.db"))
+	migrator, err := store.Migrator()
+	if err != nil {
+		t.Fatalf("failed to get migrator: %v", err)
+	}
+
+	ctx := context.Background()
+	reverted, err := migrator.Down(ctx, len(migrator.migrations))
+	if err != nil {
+		t.Fatalf("failed to revert migrations: %v", err)
+	}
+	if len(reverted) != len(migrator.migrations) {
+		t.Errorf("reverted %d of %d migrations", len(reverted), len(migrator.migrations))
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to apply migrations again: %v", err)
+	}
+	userID := createTestUser(t, store)
+	if err := store.Notes.CreateNote(userID, &models.Note{Content: "after a round trip"}); err != nil {
+		t.Errorf("create note: %v", err)
+	}
+}
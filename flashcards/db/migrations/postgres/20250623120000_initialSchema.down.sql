@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS gocourse.quiz_messages;
+DROP TABLE IF EXISTS gocourse.quiz_sessions;
+DROP TABLE IF EXISTS gocourse.review_logs;
+DROP TABLE IF EXISTS gocourse.review_states;
+DROP TABLE IF EXISTS gocourse.flashcards;
+DROP TABLE IF EXISTS gocourse.deck_notes;
+DROP TABLE IF EXISTS gocourse.decks;
+DROP TABLE IF EXISTS gocourse.todo_tags;
+DROP TABLE IF EXISTS gocourse.note_tags;
+DROP TABLE IF EXISTS gocourse.tags;
+DROP TABLE IF EXISTS gocourse.notes;
+DROP TABLE IF EXISTS gocourse.todos;
+DROP TABLE IF EXISTS gocourse.llm_usage;
+DROP TABLE IF EXISTS gocourse.api_keys;
+DROP TABLE IF EXISTS gocourse.users;
@@ -0,0 +1,210 @@
+-- The schema built by the former Supabase CLI migrations, squashed into one
+-- baseline. Every statement is guarded so that the baseline can be recorded
+-- on a database those migrations already created, up to any of them. Tables
+-- they created before a column existed get the column added, ahead of the
+-- indexes on it.
+CREATE SCHEMA IF NOT EXISTS gocourse;
+
+CREATE TABLE IF NOT EXISTS gocourse.users (
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    passwordHash TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS gocourse.api_keys (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    keyHash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expiresAt TIMESTAMP,
+    lastUsedAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON gocourse.api_keys(userId);
+
+CREATE TABLE IF NOT EXISTS gocourse.llm_usage (
+    userId INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    promptTokens INTEGER NOT NULL DEFAULT 0,
+    completionTokens INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, day)
+);
+
+-- userId is nullable on the tables that predate accounts: rows written
+-- before then keep a NULL owner and are not visible to anyone.
+CREATE TABLE IF NOT EXISTS gocourse.todos (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    completed BOOLEAN DEFAULT FALSE,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_id ON gocourse.todos(userId);
+CREATE INDEX IF NOT EXISTS idx_todos_created_at ON gocourse.todos(createdAt);
+CREATE INDEX IF NOT EXISTS idx_todos_completed ON gocourse.todos(completed);
+
+CREATE TABLE IF NOT EXISTS gocourse.notes (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    searchVector tsvector GENERATED ALWAYS AS (to_tsvector('english', coalesce(content, ''))) STORED,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS searchVector tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', coalesce(content, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON gocourse.notes(userId);
+CREATE INDEX IF NOT EXISTS idx_notes_created_at ON gocourse.notes(createdAt);
+CREATE INDEX IF NOT EXISTS idx_notes_search_vector ON gocourse.notes USING GIN (searchVector);
+
+CREATE TABLE IF NOT EXISTS gocourse.tags (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT tags_user_id_name_key UNIQUE (userId, name)
+);
+
+ALTER TABLE gocourse.tags ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+-- Tag names were unique across users before accounts existed.
+ALTER TABLE gocourse.tags DROP CONSTRAINT IF EXISTS tags_name_key;
+ALTER TABLE gocourse.tags DROP CONSTRAINT IF EXISTS tags_user_id_name_key;
+ALTER TABLE gocourse.tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (userId, name);
+
+CREATE TABLE IF NOT EXISTS gocourse.note_tags (
+    noteId INTEGER NOT NULL REFERENCES gocourse.notes(id) ON DELETE CASCADE,
+    tagId INTEGER NOT NULL REFERENCES gocourse.tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (noteId, tagId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON gocourse.note_tags(tagId);
+
+CREATE TABLE IF NOT EXISTS gocourse.todo_tags (
+    todoId INTEGER NOT NULL REFERENCES gocourse.todos(id) ON DELETE CASCADE,
+    tagId INTEGER NOT NULL REFERENCES gocourse.tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (todoId, tagId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_tags_tag_id ON gocourse.todo_tags(tagId);
+
+CREATE TABLE IF NOT EXISTS gocourse.decks (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    parentId INTEGER REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.decks ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_decks_user_id ON gocourse.decks(userId);
+CREATE INDEX IF NOT EXISTS idx_decks_parent_id ON gocourse.decks(parentId);
+
+CREATE TABLE IF NOT EXISTS gocourse.deck_notes (
+    deckId INTEGER NOT NULL REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    noteId INTEGER NOT NULL REFERENCES gocourse.notes(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (deckId, noteId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_notes_note_id ON gocourse.deck_notes(noteId);
+
+CREATE TABLE IF NOT EXISTS gocourse.flashcards (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    front TEXT NOT NULL,
+    back TEXT NOT NULL,
+    noteId INTEGER REFERENCES gocourse.notes(id) ON DELETE SET NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_cards_user_id ON gocourse.flashcards(userId);
+CREATE INDEX IF NOT EXISTS idx_cards_note_id ON gocourse.flashcards(noteId);
+CREATE INDEX IF NOT EXISTS idx_cards_created_at ON gocourse.flashcards(createdAt);
+
+-- The former migrations created notes as a flashcards table and renamed
+-- it, which left idx_flashcards_created_at on notes and pushed the card
+-- indexes to other names. Both are replaced by the indexes above.
+DROP INDEX IF EXISTS gocourse.idx_flashcards_created_at;
+DROP INDEX IF EXISTS gocourse.idx_flashcards_user_id;
+
+CREATE TABLE IF NOT EXISTS gocourse.review_states (
+    id SERIAL PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    itemType VARCHAR(20) NOT NULL,
+    itemId INTEGER NOT NULL,
+    algorithm VARCHAR(20) NOT NULL,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    easeFactor DOUBLE PRECISION NOT NULL DEFAULT 0,
+    intervalDays INTEGER NOT NULL DEFAULT 0,
+    stability DOUBLE PRECISION NOT NULL DEFAULT 0,
+    difficulty DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lastGrade VARCHAR(10) NOT NULL DEFAULT '',
+    dueAt TIMESTAMP NOT NULL,
+    lastReviewedAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW(),
+    UNIQUE (itemType, itemId)
+);
+
+ALTER TABLE gocourse.review_states ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_review_states_user_id ON gocourse.review_states(userId);
+CREATE INDEX IF NOT EXISTS idx_review_states_due_at ON gocourse.review_states(dueAt);
+
+CREATE TABLE IF NOT EXISTS gocourse.review_logs (
+    id SERIAL PRIMARY KEY,
+    stateId INTEGER NOT NULL REFERENCES gocourse.review_states(id) ON DELETE CASCADE,
+    grade VARCHAR(10) NOT NULL,
+    intervalDays INTEGER NOT NULL,
+    reviewedAt TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_logs_state_id ON gocourse.review_logs(stateId);
+
+CREATE TABLE IF NOT EXISTS gocourse.quiz_sessions (
+    id UUID PRIMARY KEY,
+    userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    noteIds INTEGER[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.quiz_sessions ADD COLUMN IF NOT EXISTS userId INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_user_id ON gocourse.quiz_sessions(userId);
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_created_at ON gocourse.quiz_sessions(createdAt);
+
+CREATE TABLE IF NOT EXISTS gocourse.quiz_messages (
+    id SERIAL PRIMARY KEY,
+    sessionId UUID NOT NULL REFERENCES gocourse.quiz_sessions(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    content TEXT NOT NULL,
+    grade JSONB,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.quiz_messages ADD COLUMN IF NOT EXISTS grade JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_quiz_messages_session_id ON gocourse.quiz_messages(sessionId, id);
@@ -0,0 +1,6 @@
+-- Notes and todos in the trash would come back to life without the column.
+DELETE FROM gocourse.notes WHERE deletedAt IS NOT NULL;
+DELETE FROM gocourse.todos WHERE deletedAt IS NOT NULL;
+
+ALTER TABLE gocourse.notes DROP COLUMN IF EXISTS deletedAt;
+ALTER TABLE gocourse.todos DROP COLUMN IF EXISTS deletedAt;
@@ -0,0 +1,5 @@
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS deletedAt TIMESTAMP;
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS deletedAt TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON gocourse.notes(deletedAt) WHERE deletedAt IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON gocourse.todos(deletedAt) WHERE deletedAt IS NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS gocourse.note_revisions;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS gocourse.note_revisions (
+    noteId INTEGER NOT NULL REFERENCES gocourse.notes(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    createdAt TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (noteId, revision)
+);
+
+-- Existing notes start their history with their current content.
+INSERT INTO gocourse.note_revisions (noteId, revision, content, createdAt)
+SELECT id, 1, content, COALESCE(updatedAt, NOW()) FROM gocourse.notes
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE gocourse.notes DROP COLUMN IF EXISTS version;
+ALTER TABLE gocourse.todos DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- Bumped on every update, and sent as the ETag of a note or todo.
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
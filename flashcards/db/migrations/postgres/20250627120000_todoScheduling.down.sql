@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS gocourse.idx_todos_due_at;
+
+ALTER TABLE gocourse.todos DROP COLUMN IF EXISTS dueAt;
+ALTER TABLE gocourse.todos DROP COLUMN IF EXISTS priority;
+ALTER TABLE gocourse.todos DROP COLUMN IF EXISTS completedAt;
+ALTER TABLE gocourse.todos DROP COLUMN IF EXISTS recurrence;
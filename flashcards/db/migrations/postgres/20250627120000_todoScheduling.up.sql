@@ -0,0 +1,10 @@
+-- Due dates, priorities and recurrence rules of todos. Times are in UTC.
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS dueAt TIMESTAMP;
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS priority VARCHAR(16) NOT NULL DEFAULT 'normal';
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS completedAt TIMESTAMP;
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255) NOT NULL DEFAULT '';
+
+-- The last update of a completed todo is the best guess of when it was done.
+UPDATE gocourse.todos SET completedAt = updatedAt WHERE completed AND completedAt IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_due_at ON gocourse.todos(userId, dueAt) WHERE dueAt IS NOT NULL;
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS quiz_messages;
+DROP TABLE IF EXISTS quiz_sessions;
+DROP TABLE IF EXISTS review_logs;
+DROP TABLE IF EXISTS review_states;
+DROP TABLE IF EXISTS flashcards;
+DROP TABLE IF EXISTS deck_notes;
+DROP TABLE IF EXISTS decks;
+DROP TABLE IF EXISTS todo_tags;
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS tags;
+DROP TRIGGER IF EXISTS notes_fts_update;
+DROP TRIGGER IF EXISTS notes_fts_delete;
+DROP TRIGGER IF EXISTS notes_fts_insert;
+DROP TABLE IF EXISTS notes_fts;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS todos;
+DROP TABLE IF EXISTS llm_usage;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,196 @@
+-- The schema the server used to create on every start, recorded as the
+-- baseline. Every statement is guarded so that it also applies to those
+-- existing databases.
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL UNIQUE,
+    passwordHash TEXT NOT NULL,
+    createdAt TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    keyHash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL DEFAULT '[]',
+    expiresAt TIMESTAMP,
+    lastUsedAt TIMESTAMP,
+    createdAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(userId);
+
+CREATE TABLE IF NOT EXISTS llm_usage (
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    promptTokens INTEGER NOT NULL DEFAULT 0,
+    completionTokens INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, day)
+);
+
+CREATE TABLE IF NOT EXISTS todos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    completed BOOLEAN DEFAULT FALSE,
+    createdAt TIMESTAMP,
+    updatedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(userId);
+CREATE INDEX IF NOT EXISTS idx_todos_created_at ON todos(createdAt);
+CREATE INDEX IF NOT EXISTS idx_todos_completed ON todos(completed);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    createdAt TIMESTAMP,
+    updatedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON notes(userId);
+CREATE INDEX IF NOT EXISTS idx_notes_created_at ON notes(createdAt);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+    content,
+    content = 'notes',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS notes_fts_insert AFTER INSERT ON notes BEGIN
+    INSERT INTO notes_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS notes_fts_delete AFTER DELETE ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS notes_fts_update AFTER UPDATE OF content ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO notes_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    createdAt TIMESTAMP,
+    UNIQUE (userId, name)
+);
+
+CREATE TABLE IF NOT EXISTS note_tags (
+    noteId INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    tagId INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (noteId, tagId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON note_tags(tagId);
+
+CREATE TABLE IF NOT EXISTS todo_tags (
+    todoId INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    tagId INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (todoId, tagId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_tags_tag_id ON todo_tags(tagId);
+
+CREATE TABLE IF NOT EXISTS decks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    parentId INTEGER REFERENCES decks(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP,
+    updatedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_decks_user_id ON decks(userId);
+CREATE INDEX IF NOT EXISTS idx_decks_parent_id ON decks(parentId);
+
+CREATE TABLE IF NOT EXISTS deck_notes (
+    deckId INTEGER NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    noteId INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP,
+    PRIMARY KEY (deckId, noteId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_notes_note_id ON deck_notes(noteId);
+
+CREATE TABLE IF NOT EXISTS flashcards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    front TEXT NOT NULL,
+    back TEXT NOT NULL,
+    noteId INTEGER REFERENCES notes(id) ON DELETE SET NULL,
+    tags TEXT NOT NULL DEFAULT '[]',
+    createdAt TIMESTAMP,
+    updatedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_flashcards_user_id ON flashcards(userId);
+CREATE INDEX IF NOT EXISTS idx_flashcards_note_id ON flashcards(noteId);
+CREATE INDEX IF NOT EXISTS idx_flashcards_created_at ON flashcards(createdAt);
+
+CREATE TABLE IF NOT EXISTS review_states (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    itemType VARCHAR(20) NOT NULL,
+    itemId INTEGER NOT NULL,
+    algorithm VARCHAR(20) NOT NULL,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    easeFactor REAL NOT NULL DEFAULT 0,
+    intervalDays INTEGER NOT NULL DEFAULT 0,
+    stability REAL NOT NULL DEFAULT 0,
+    difficulty REAL NOT NULL DEFAULT 0,
+    lastGrade VARCHAR(10) NOT NULL DEFAULT '',
+    dueAt TIMESTAMP NOT NULL,
+    lastReviewedAt TIMESTAMP,
+    createdAt TIMESTAMP,
+    updatedAt TIMESTAMP,
+    UNIQUE (itemType, itemId)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_states_user_id ON review_states(userId);
+CREATE INDEX IF NOT EXISTS idx_review_states_due_at ON review_states(dueAt);
+
+CREATE TABLE IF NOT EXISTS review_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    stateId INTEGER NOT NULL REFERENCES review_states(id) ON DELETE CASCADE,
+    grade VARCHAR(10) NOT NULL,
+    intervalDays INTEGER NOT NULL,
+    reviewedAt TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_logs_state_id ON review_logs(stateId);
+
+CREATE TABLE IF NOT EXISTS quiz_sessions (
+    id TEXT PRIMARY KEY,
+    userId INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    noteIds TEXT NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    createdAt TIMESTAMP,
+    updatedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_user_id ON quiz_sessions(userId);
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_created_at ON quiz_sessions(createdAt);
+
+CREATE TABLE IF NOT EXISTS quiz_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sessionId TEXT NOT NULL REFERENCES quiz_sessions(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    content TEXT NOT NULL,
+    grade TEXT,
+    createdAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_messages_session_id ON quiz_messages(sessionId, id);
+
+-- Notes written before the search index existed are not in it yet.
+INSERT INTO notes_fts (notes_fts) VALUES ('rebuild');
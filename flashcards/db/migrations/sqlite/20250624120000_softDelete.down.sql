@@ -0,0 +1,8 @@
+-- Notes and todos in the trash would come back to life without the column.
+DELETE FROM notes WHERE deletedAt IS NOT NULL;
+DELETE FROM todos WHERE deletedAt IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_notes_deleted_at;
+DROP INDEX IF EXISTS idx_todos_deleted_at;
+ALTER TABLE notes DROP COLUMN deletedAt;
+ALTER TABLE todos DROP COLUMN deletedAt;
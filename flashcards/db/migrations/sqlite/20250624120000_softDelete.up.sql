@@ -0,0 +1,5 @@
+ALTER TABLE notes ADD COLUMN deletedAt TIMESTAMP;
+ALTER TABLE todos ADD COLUMN deletedAt TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes(deletedAt) WHERE deletedAt IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos(deletedAt) WHERE deletedAt IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS note_revisions (
+    noteId INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    createdAt TIMESTAMP NOT NULL,
+    PRIMARY KEY (noteId, revision)
+);
+
+-- Existing notes start their history with their current content.
+INSERT OR IGNORE INTO note_revisions (noteId, revision, content, createdAt)
+SELECT id, 1, content, COALESCE(updatedAt, CURRENT_TIMESTAMP) FROM notes;
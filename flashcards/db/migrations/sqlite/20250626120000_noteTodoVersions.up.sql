@@ -0,0 +1,3 @@
+-- Bumped on every update, and sent as the ETag of a note or todo.
+ALTER TABLE notes ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
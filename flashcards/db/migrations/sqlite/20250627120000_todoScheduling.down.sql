@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_todos_due_at;
+
+ALTER TABLE todos DROP COLUMN dueAt;
+ALTER TABLE todos DROP COLUMN priority;
+ALTER TABLE todos DROP COLUMN completedAt;
+ALTER TABLE todos DROP COLUMN recurrence;
@@ -0,0 +1,10 @@
+-- Due dates, priorities and recurrence rules of todos. Times are in UTC.
+ALTER TABLE todos ADD COLUMN dueAt TIMESTAMP;
+ALTER TABLE todos ADD COLUMN priority VARCHAR(16) NOT NULL DEFAULT 'normal';
+ALTER TABLE todos ADD COLUMN completedAt TIMESTAMP;
+ALTER TABLE todos ADD COLUMN recurrence VARCHAR(255) NOT NULL DEFAULT '';
+
+-- The last update of a completed todo is the best guess of when it was done.
+UPDATE todos SET completedAt = updatedAt WHERE completed AND completedAt IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_due_at ON todos(userId, dueAt) WHERE dueAt IS NOT NULL;
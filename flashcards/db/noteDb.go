@@ -3,66 +3,144 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
+	"flashcards/apperr"
 	"flashcards/models"
+	"flashcards/search"
 
 	_ "github.com/lib/pq"
 )
 
 type NoteRepository interface {
-	CreateNote(note *models.Note) error
-	GetNoteByID(id int) (*models.Note, error)
-	GetAllNotes() ([]*models.Note, error)
-	UpdateNote(id int, updates map[string]any) error
-	DeleteNote(id int) error
+	CreateNote(userID int, note *models.Note) error
+	ImportNotes(userID int, notes []*models.Note) ([]bool, error)
+	GetNoteByID(userID, id int) (*models.Note, error)
+	ListNotes(userID int, opts models.NoteListOptions) (*models.Page[*models.Note], error)
+	UpdateNote(userID, id int, updates map[string]any, version int) error
+	DeleteNote(userID, id, version int) error
+	RestoreNote(userID, id int) error
+	ListDeletedNotes(userID int) ([]*models.Note, error)
+	PurgeNotes(olderThan time.Duration) ([]PurgedItem, error)
+	SearchNotes(userID int, query string, limit int) ([]*models.NoteSearchResult, error)
+	ListNoteRevisions(userID, noteID int) ([]*models.NoteRevision, error)
+	GetNoteRevision(userID, noteID, revision int) (*models.NoteRevision, error)
+	RestoreNoteRevision(userID, noteID, revision int) error
 }
 
+// postgresNoteVersionQuery selects the version of a note for staleVersion.
+const postgresNoteVersionQuery = "SELECT version FROM gocourse.notes WHERE id = $1 AND userId = $2 AND deletedAt IS NULL"
+
 type PostgresNoteRepository struct {
 	db *sql.DB
 }
 
 func NewPostgresNoteRepository(databaseURL string) (*PostgresNoteRepository, error) {
-	db, err := sql.Open("postgres", databaseURL)
+	db, err := openPostgres(databaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	return &PostgresNoteRepository{db: db}, nil
+}
+
+// CreateNote saves the note with its tags and its first revision.
+func (r *PostgresNoteRepository) CreateNote(userID int, note *models.Note) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return &PostgresNoteRepository{db: db}, nil
+	if err := insertPostgresNote(tx, userID, note); err != nil {
+		return err
+	}
+	if err := addPostgresTags(tx, userID, noteTagLink, note.ID, note.Tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
+	}
+
+	return nil
 }
 
-func (r *PostgresNoteRepository) CreateNote(note *models.Note) error {
+// ImportNotes saves the notes with their tags and first revisions in a
+// single transaction. A note whose content a note of the user outside the
+// trash already has, including one saved earlier in the same call, is not
+// saved and gets the ID of that note instead, whose tags are left as they
+// are.
+func (r *PostgresNoteRepository) ImportNotes(userID int, notes []*models.Note) ([]bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO gocourse.notes (content) 
-		VALUES ($1) 
-		RETURNING id, createdAt, updatedAt`
+		SELECT id 
+		FROM gocourse.notes 
+		WHERE userId = $1 AND content = $2 AND deletedAt IS NULL 
+		LIMIT 1`
+
+	created := make([]bool, len(notes))
+	for i, note := range notes {
+		err := tx.QueryRow(query, userID, note.Content).Scan(&note.ID)
+		if err == nil {
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to look up note content: %w", err)
+		}
 
-	row := r.db.QueryRow(query, note.Content)
+		if err := insertPostgresNote(tx, userID, note); err != nil {
+			return nil, err
+		}
+		if err := addPostgresTags(tx, userID, noteTagLink, note.ID, note.Tags); err != nil {
+			return nil, err
+		}
+		created[i] = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit notes: %w", err)
+	}
+
+	return created, nil
+}
+
+// insertPostgresNote saves a new note and its first revision within tx.
+func insertPostgresNote(tx *sql.Tx, userID int, note *models.Note) error {
+	query := `
+		INSERT INTO gocourse.notes (userId, content) 
+		VALUES ($1, $2) 
+		RETURNING id, createdAt, updatedAt, version`
 
-	err := row.Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
+	row := tx.QueryRow(query, userID, note.Content)
+
+	err := row.Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &note.Version)
 	if err != nil {
 		return fmt.Errorf("failed to create note: %w", err)
 	}
 
-	return nil
+	return addPostgresNoteRevision(tx, note.ID)
 }
 
-func (r *PostgresNoteRepository) GetNoteByID(id int) (*models.Note, error) {
+func (r *PostgresNoteRepository) GetNoteByID(userID, id int) (*models.Note, error) {
 	query := `
-		SELECT id, content, createdAt, updatedAt 
+		SELECT id, content, createdAt, updatedAt, version 
 		FROM gocourse.notes 
-		WHERE id = $1`
+		WHERE id = $1 AND userId = $2 AND deletedAt IS NULL`
 
 	note := &models.Note{}
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRow(query, id, userID)
 
-	err := row.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	err := row.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("note with id %d not found", id)
+			return nil, apperr.NotFound("note with id %d not found", id)
 		}
 		return nil, fmt.Errorf("failed to get note: %w", err)
 	}
@@ -70,57 +148,74 @@ func (r *PostgresNoteRepository) GetNoteByID(id int) (*models.Note, error) {
 	return note, nil
 }
 
-func (r *PostgresNoteRepository) GetAllNotes() ([]*models.Note, error) {
-	query := `
-		SELECT id, content, createdAt, updatedAt 
-		FROM gocourse.notes 
-		ORDER BY createdAt DESC`
+// ListNotes returns a page of notes. The total is only counted when there is
+// more than one page.
+func (r *PostgresNoteRepository) ListNotes(userID int, opts models.NoteListOptions) (*models.Page[*models.Note], error) {
+	q := newListQuery(true)
+	q.ownedBy(userID)
+	q.excludeDeleted()
+	q.filter(opts.ListOptions, "content")
+	countQuery := "SELECT COUNT(*) FROM gocourse.notes" + q.whereClause()
+	countArgs := append([]any{}, q.args...)
+
+	q.after(opts.ListOptions)
+	query := "SELECT id, content, createdAt, updatedAt, version FROM gocourse.notes" + q.whereClause() + q.orderAndLimit(opts.ListOptions)
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, q.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query notes: %w", err)
 	}
 	defer rows.Close()
 
-	notes := make([]*models.Note, 0)
-	for rows.Next() {
-		note := &models.Note{}
-		err := rows.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan note: %w", err)
-		}
-		notes = append(notes, note)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over notes: %w", err)
+	notes, err := scanNotes(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	return notes, nil
+	return newPage(notes, opts.ListOptions, noteListKey, func() (int, error) {
+		var total int
+		err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+		return total, err
+	})
 }
 
-func (r *PostgresNoteRepository) UpdateNote(id int, updates map[string]any) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// UpdateNote applies the updates and bumps the version of the note. Unless
+// version is 0, the note is only updated while it is at version. New content
+// is saved as a new revision. A tags update replaces the tags of the note
+// in the same transaction.
+func (r *PostgresNoteRepository) UpdateNote(userID, id int, updates map[string]any, version int) error {
+	tags, setTags, err := updatedTags(updates)
+	if err != nil {
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
 	query := "UPDATE gocourse.notes SET "
 	args := []any{}
 	argIndex := 1
 
 	for field, value := range updates {
-		if argIndex > 1 {
-			query += ", "
+		if field == tagsUpdate {
+			continue
 		}
-		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		query += fmt.Sprintf("%s = $%d, ", field, argIndex)
 		args = append(args, value)
 		argIndex++
 	}
 
-	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d", argIndex)
-	args = append(args, id)
+	query += fmt.Sprintf("updatedAt = NOW(), version = version + 1 WHERE id = $%d AND userId = $%d AND deletedAt IS NULL", argIndex, argIndex+1)
+	args = append(args, id, userID)
+	if version > 0 {
+		query += fmt.Sprintf(" AND version = $%d", argIndex+2)
+		args = append(args, version)
+	}
 
-	result, err := r.db.Exec(query, args...)
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update note: %w", err)
 	}
@@ -131,16 +226,33 @@ func (r *PostgresNoteRepository) UpdateNote(id int, updates map[string]any) erro
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("note with id %d not found", id)
+		return staleVersion(tx, "note", id, postgresNoteVersionQuery, id, userID)
+	}
+
+	if _, ok := updates["content"]; ok {
+		if err := addPostgresNoteRevision(tx, id); err != nil {
+			return err
+		}
+	}
+	if setTags {
+		if err := replacePostgresTags(tx, userID, noteTagLink, id, tags); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
 	}
 
 	return nil
 }
 
-func (r *PostgresNoteRepository) DeleteNote(id int) error {
-	query := "DELETE FROM gocourse.notes WHERE id = $1"
+// DeleteNote moves the note to the trash. Unless version is 0, only while
+// the note is at version.
+func (r *PostgresNoteRepository) DeleteNote(userID, id, version int) error {
+	query := "UPDATE gocourse.notes SET deletedAt = NOW() WHERE id = $1 AND userId = $2 AND deletedAt IS NULL AND ($3 = 0 OR version = $3)"
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.Exec(query, id, userID, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete note: %w", err)
 	}
@@ -151,12 +263,289 @@ func (r *PostgresNoteRepository) DeleteNote(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("note with id %d not found", id)
+		return staleVersion(r.db, "note", id, postgresNoteVersionQuery, id, userID)
 	}
 
 	return nil
 }
 
+func (r *PostgresNoteRepository) RestoreNote(userID, id int) error {
+	query := "UPDATE gocourse.notes SET deletedAt = NULL WHERE id = $1 AND userId = $2 AND deletedAt IS NOT NULL"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("note with id %d not found in trash", id)
+	}
+
+	return nil
+}
+
+// ListDeletedNotes returns the notes in the trash, most recently deleted
+// first.
+func (r *PostgresNoteRepository) ListDeletedNotes(userID int) ([]*models.Note, error) {
+	query := `
+		SELECT id, content, createdAt, updatedAt, version, deletedAt 
+		FROM gocourse.notes 
+		WHERE userId = $1 AND deletedAt IS NOT NULL 
+		ORDER BY deletedAt DESC, id DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeletedNotes(rows)
+}
+
+// PurgeNotes removes the notes of every user that have been in the trash
+// for longer than olderThan.
+func (r *PostgresNoteRepository) PurgeNotes(olderThan time.Duration) ([]PurgedItem, error) {
+	query := `
+		DELETE FROM gocourse.notes 
+		WHERE deletedAt < NOW() - make_interval(secs => $1) 
+		RETURNING userId, id`
+
+	rows, err := r.db.Query(query, olderThan.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPurgedItems(rows)
+}
+
+// SearchNotes ranks notes with the searchVector column. Every query word has
+// to match, either exactly or as a prefix of a stemmed word in the note.
+func (r *PostgresNoteRepository) SearchNotes(userID int, query string, limit int) ([]*models.NoteSearchResult, error) {
+	tsQuery := prefixQuery(query, " & ", "%s:*")
+	if tsQuery == "" {
+		return []*models.NoteSearchResult{}, nil
+	}
+
+	sqlQuery := `
+		SELECT n.id, n.content, n.createdAt, n.updatedAt, n.version,
+			ts_rank(n.searchVector, q) AS score,
+			ts_headline('english', n.content, q, 'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15')
+		FROM gocourse.notes n, to_tsquery('english', $1) q
+		WHERE n.searchVector @@ q AND n.userId = $2 AND n.deletedAt IS NULL
+		ORDER BY score DESC, n.createdAt DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(sqlQuery, tsQuery, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNoteSearchResults(rows)
+}
+
+// ListNoteRevisions returns the revisions of a note, newest first.
+func (r *PostgresNoteRepository) ListNoteRevisions(userID, noteID int) ([]*models.NoteRevision, error) {
+	query := `
+		SELECT r.noteId, r.revision, r.content, r.createdAt 
+		FROM gocourse.note_revisions r 
+		JOIN gocourse.notes n ON n.id = r.noteId 
+		WHERE r.noteId = $1 AND n.userId = $2 AND n.deletedAt IS NULL 
+		ORDER BY r.revision DESC`
+
+	rows, err := r.db.Query(query, noteID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query note revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions, err := scanNoteRevisions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every note has at least the revision it was created with.
+	if len(revisions) == 0 {
+		return nil, apperr.NotFound("note with id %d not found", noteID)
+	}
+
+	return revisions, nil
+}
+
+func (r *PostgresNoteRepository) GetNoteRevision(userID, noteID, revision int) (*models.NoteRevision, error) {
+	query := `
+		SELECT r.noteId, r.revision, r.content, r.createdAt 
+		FROM gocourse.note_revisions r 
+		JOIN gocourse.notes n ON n.id = r.noteId 
+		WHERE r.noteId = $1 AND n.userId = $2 AND n.deletedAt IS NULL AND r.revision = $3`
+
+	rev := &models.NoteRevision{}
+	row := r.db.QueryRow(query, noteID, userID, revision)
+
+	err := row.Scan(&rev.NoteID, &rev.Revision, &rev.Content, &rev.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("revision %d of note %d not found", revision, noteID)
+		}
+		return nil, fmt.Errorf("failed to get note revision: %w", err)
+	}
+
+	return rev, nil
+}
+
+// RestoreNoteRevision sets the content of the note back to the one of
+// revision, saved as a new revision so that the history is never rewritten.
+func (r *PostgresNoteRepository) RestoreNoteRevision(userID, noteID, revision int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE gocourse.notes n 
+		SET content = r.content, updatedAt = NOW(), version = n.version + 1 
+		FROM gocourse.note_revisions r 
+		WHERE n.id = $1 AND n.userId = $2 AND n.deletedAt IS NULL AND r.noteId = n.id AND r.revision = $3`
+
+	result, err := tx.Exec(query, noteID, userID, revision)
+	if err != nil {
+		return fmt.Errorf("failed to restore note revision: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("revision %d of note %d not found", revision, noteID)
+	}
+
+	if err := addPostgresNoteRevision(tx, noteID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
+	}
+
+	return nil
+}
+
+// addPostgresNoteRevision saves the current content of the note as its next
+// revision. It runs after the note row is written in the same transaction,
+// whose row lock keeps concurrent updates from taking the same number.
+func addPostgresNoteRevision(execer sqlExecer, noteID int) error {
+	query := `
+		INSERT INTO gocourse.note_revisions (noteId, revision, content, createdAt) 
+		SELECT id, COALESCE((SELECT MAX(revision) FROM gocourse.note_revisions WHERE noteId = $1), 0) + 1, content, updatedAt 
+		FROM gocourse.notes 
+		WHERE id = $1`
+
+	if _, err := execer.Exec(query, noteID); err != nil {
+		return fmt.Errorf("failed to save note revision: %w", err)
+	}
+
+	return nil
+}
+
+func scanNoteRevisions(rows *sql.Rows) ([]*models.NoteRevision, error) {
+	revisions := make([]*models.NoteRevision, 0)
+	for rows.Next() {
+		rev := &models.NoteRevision{}
+		err := rows.Scan(&rev.NoteID, &rev.Revision, &rev.Content, &rev.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over note revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func scanNotes(rows *sql.Rows) ([]*models.Note, error) {
+	notes := make([]*models.Note, 0)
+	for rows.Next() {
+		note := &models.Note{}
+		err := rows.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over notes: %w", err)
+	}
+
+	return notes, nil
+}
+
+func scanDeletedNotes(rows *sql.Rows) ([]*models.Note, error) {
+	notes := make([]*models.Note, 0)
+	for rows.Next() {
+		note := &models.Note{}
+		err := rows.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Version, &note.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deleted note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deleted notes: %w", err)
+	}
+
+	return notes, nil
+}
+
+func noteListKey(note *models.Note) listKey {
+	return listKey{id: note.ID, createdAt: note.CreatedAt, updatedAt: note.UpdatedAt}
+}
+
+// prefixQuery turns free text into a full-text query where each word is
+// formatted with termFormat and the words are joined with sep. Only letters
+// and digits survive tokenizing, so the result is safe to pass to the
+// database's query parser.
+func prefixQuery(query, sep, termFormat string) string {
+	tokens := search.Tokenize(query)
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		terms = append(terms, fmt.Sprintf(termFormat, token))
+	}
+	return strings.Join(terms, sep)
+}
+
+func scanNoteSearchResults(rows *sql.Rows) ([]*models.NoteSearchResult, error) {
+	results := make([]*models.NoteSearchResult, 0)
+	for rows.Next() {
+		note := &models.Note{}
+		result := &models.NoteSearchResult{Note: note}
+		err := rows.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Version, &result.Score, &result.Snippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note search result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over note search results: %w", err)
+	}
+
+	return results, nil
+}
+
 func (r *PostgresNoteRepository) Close() error {
 	return r.db.Close()
 }
\ No newline at end of file
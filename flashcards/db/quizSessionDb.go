@@ -0,0 +1,278 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type QuizSessionRepository interface {
+	CreateQuizSession(userID int, session *models.QuizSession) error
+	GetQuizSessionByID(userID int, id string) (*models.QuizSession, error)
+	ListQuizSessions(userID int, after string, limit int) ([]*models.QuizSession, error)
+	AppendQuizMessages(userID int, id string, messages []models.Message) error
+	UpdateQuizSessionStatus(userID int, id string, status string) error
+}
+
+type PostgresQuizSessionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresQuizSessionRepository(databaseURL string) (*PostgresQuizSessionRepository, error) {
+	db, err := openPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresQuizSessionRepository{db: db}, nil
+}
+
+func (r *PostgresQuizSessionRepository) CreateQuizSession(userID int, session *models.QuizSession) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gocourse.quiz_sessions (id, userId, noteIds, status) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING createdAt, updatedAt`
+
+	row := tx.QueryRow(query, session.ID, userID, pq.Array(toInt64s(session.NoteIDs)), session.Status)
+	if err := row.Scan(&session.CreatedAt, &session.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create quiz session: %w", err)
+	}
+
+	if err := insertPostgresQuizMessages(tx, session.ID, session.Messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit quiz session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresQuizSessionRepository) GetQuizSessionByID(userID int, id string) (*models.QuizSession, error) {
+	query := `
+		SELECT id, noteIds, status, createdAt, updatedAt 
+		FROM gocourse.quiz_sessions 
+		WHERE id = $1 AND userId = $2`
+
+	session := &models.QuizSession{}
+	var noteIDs []int64
+
+	row := r.db.QueryRow(query, id, userID)
+	err := row.Scan(&session.ID, pq.Array(&noteIDs), &session.Status, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("quiz session with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get quiz session: %w", err)
+	}
+	session.NoteIDs = fromInt64s(noteIDs)
+
+	messagesQuery := `
+		SELECT role, content, grade 
+		FROM gocourse.quiz_messages 
+		WHERE sessionId = $1 
+		ORDER BY id ASC`
+
+	session.Messages, err = queryQuizMessages(r.db, messagesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListQuizSessions returns up to limit sessions with their messages, oldest
+// first, starting after the session with id after, or at the first session
+// when after is empty.
+func (r *PostgresQuizSessionRepository) ListQuizSessions(userID int, after string, limit int) ([]*models.QuizSession, error) {
+	query := `
+		SELECT id, noteIds, status, createdAt, updatedAt 
+		FROM gocourse.quiz_sessions 
+		WHERE userId = $1 AND ($2 = '' OR (createdAt, id) > (
+			SELECT createdAt, id FROM gocourse.quiz_sessions WHERE id = $2 AND userId = $1)) 
+		ORDER BY createdAt ASC, id ASC 
+		LIMIT $3`
+
+	rows, err := r.db.Query(query, userID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quiz sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*models.QuizSession, 0)
+	for rows.Next() {
+		session := &models.QuizSession{}
+		var noteIDs []int64
+		err := rows.Scan(&session.ID, pq.Array(&noteIDs), &session.Status, &session.CreatedAt, &session.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quiz session: %w", err)
+		}
+		session.NoteIDs = fromInt64s(noteIDs)
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over quiz sessions: %w", err)
+	}
+
+	messagesQuery := `
+		SELECT role, content, grade 
+		FROM gocourse.quiz_messages 
+		WHERE sessionId = $1 
+		ORDER BY id ASC`
+
+	for _, session := range sessions {
+		session.Messages, err = queryQuizMessages(r.db, messagesQuery, session.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *PostgresQuizSessionRepository) AppendQuizMessages(userID int, id string, messages []models.Message) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE gocourse.quiz_sessions SET updatedAt = NOW() WHERE id = $1 AND userId = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update quiz session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	if err := insertPostgresQuizMessages(tx, id, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit quiz messages: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresQuizSessionRepository) UpdateQuizSessionStatus(userID int, id string, status string) error {
+	query := "UPDATE gocourse.quiz_sessions SET status = $1, updatedAt = NOW() WHERE id = $2 AND userId = $3"
+
+	result, err := r.db.Exec(query, status, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update quiz session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	return nil
+}
+
+func (r *PostgresQuizSessionRepository) Close() error {
+	return r.db.Close()
+}
+
+func insertPostgresQuizMessages(tx *sql.Tx, sessionID string, messages []models.Message) error {
+	query := `
+		INSERT INTO gocourse.quiz_messages (sessionId, role, content, grade) 
+		VALUES ($1, $2, $3, $4)`
+
+	for _, message := range messages {
+		grade, err := encodeAnswerGrade(message.Grade)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, sessionID, message.Role, message.Content, grade); err != nil {
+			return fmt.Errorf("failed to save quiz message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func queryQuizMessages(db *sql.DB, query string, args ...any) ([]models.Message, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quiz messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.Message, 0)
+	for rows.Next() {
+		var message models.Message
+		var grade sql.NullString
+		if err := rows.Scan(&message.Role, &message.Content, &grade); err != nil {
+			return nil, fmt.Errorf("failed to scan quiz message: %w", err)
+		}
+		if grade.Valid {
+			message.Grade = &models.AnswerGrade{}
+			if err := json.Unmarshal([]byte(grade.String), message.Grade); err != nil {
+				return nil, fmt.Errorf("failed to decode quiz message grade: %w", err)
+			}
+		}
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over quiz messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// encodeAnswerGrade returns the JSON encoded grade, or nil for messages
+// that were not graded so that the column stays NULL.
+func encodeAnswerGrade(grade *models.AnswerGrade) (any, error) {
+	if grade == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(grade)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quiz message grade: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// pq.Array only supports the sized integer slices.
+func toInt64s(values []int) []int64 {
+	converted := make([]int64, len(values))
+	for i, value := range values {
+		converted[i] = int64(value)
+	}
+	return converted
+}
+
+func fromInt64s(values []int64) []int {
+	converted := make([]int, len(values))
+	for i, value := range values {
+		converted[i] = int(value)
+	}
+	return converted
+}
@@ -0,0 +1,752 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+// postgresTestURLEnv names the Postgres database the conformance suite runs
+// against as well. The suite creates users of its own, but purging the trash
+// affects every user, so it must be a database that can be thrown away.
+const postgresTestURLEnv = "FLASHCARDS_TEST_DATABASE_URL"
+
+// conformanceBackends opens a fresh, migrated store for every driver. Each
+// driver must behave the same in every test of the suite.
+func conformanceBackends(t *testing.T) map[string]func(t *testing.T) *Store {
+	backends := map[string]func(t *testing.T) *Store{
+		DriverMemory: func(t *testing.T) *Store {
+			return openTestStore(t, DriverMemory, "")
+		},
+		DriverSQLite: func(t *testing.T) *Store {
+			return openTestStore(t, DriverSQLite, filepath.Join(t.TempDir(), "conformance.db"))
+		},
+	}
+
+	if url := os.Getenv(postgresTestURLEnv); url != "" {
+		backends[DriverPostgres] = func(t *testing.T) *Store {
+			return openTestStore(t, DriverPostgres, url)
+		}
+	} else {
+		t.Logf("%s is not set, skipping the postgres driver", postgresTestURLEnv)
+	}
+
+	return backends
+}
+
+func openTestStore(t *testing.T, driver, url string) *Store {
+	t.Helper()
+
+	store, err := NewStore(driver, url)
+	if err != nil {
+		t.Fatalf("failed to open %s store: %v", driver, err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	if driver != DriverMemory {
+		migrator, err := store.Migrator()
+		if err != nil {
+			t.Fatalf("failed to get migrator: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("failed to migrate %s store: %v", driver, err)
+		}
+	}
+
+	return store
+}
+
+var testUserCount atomic.Int64
+
+// createTestUser adds a user with an email no other run uses, so that the
+// suite can share a Postgres database with earlier runs.
+func createTestUser(t *testing.T, store *Store) int {
+	t.Helper()
+
+	user := &models.User{
+		Email:        fmt.Sprintf("conformance-%d-%d@example.com", time.Now().UnixNano(), testUserCount.Add(1)),
+		PasswordHash: "not a hash",
+	}
+	if err := store.Users.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user.ID
+}
+
+// itemRepository adapts the note and todo repositories to the parts they
+// share, which the suite checks for both: text is the content of a note or
+// the title of a todo.
+type itemRepository struct {
+	create      func(userID int, text string) (int, error)
+	get         func(userID, id int) (text string, version int, err error)
+	list        func(userID int, opts models.ListOptions) (*models.Page[int], error)
+	update      func(userID, id int, text string, version int) error
+	delete      func(userID, id, version int) error
+	restore     func(userID, id int) error
+	listDeleted func(userID int) ([]int, error)
+	purge       func(olderThan time.Duration) ([]PurgedItem, error)
+}
+
+func noteItems(store *Store) itemRepository {
+	repo := store.Notes
+	return itemRepository{
+		create: func(userID int, text string) (int, error) {
+			note := &models.Note{Content: text}
+			err := repo.CreateNote(userID, note)
+			return note.ID, err
+		},
+		get: func(userID, id int) (string, int, error) {
+			note, err := repo.GetNoteByID(userID, id)
+			if err != nil {
+				return "", 0, err
+			}
+			return note.Content, note.Version, nil
+		},
+		list: func(userID int, opts models.ListOptions) (*models.Page[int], error) {
+			page, err := repo.ListNotes(userID, models.NoteListOptions{ListOptions: opts})
+			if err != nil {
+				return nil, err
+			}
+			return pageIDs(page, func(note *models.Note) int { return note.ID }), nil
+		},
+		update: func(userID, id int, text string, version int) error {
+			return repo.UpdateNote(userID, id, map[string]any{"content": text}, version)
+		},
+		delete:  repo.DeleteNote,
+		restore: repo.RestoreNote,
+		listDeleted: func(userID int) ([]int, error) {
+			notes, err := repo.ListDeletedNotes(userID)
+			return deletedIDs(notes, err, func(note *models.Note) (int, *time.Time) { return note.ID, note.DeletedAt })
+		},
+		purge: repo.PurgeNotes,
+	}
+}
+
+func todoItems(store *Store) itemRepository {
+	repo := store.Todos
+	return itemRepository{
+		create: func(userID int, text string) (int, error) {
+			todo := &models.Todo{Title: text, Priority: models.PriorityNormal}
+			err := repo.CreateTodo(userID, todo)
+			return todo.ID, err
+		},
+		get: func(userID, id int) (string, int, error) {
+			todo, err := repo.GetTodoByID(userID, id)
+			if err != nil {
+				return "", 0, err
+			}
+			return todo.Title, todo.Version, nil
+		},
+		list: func(userID int, opts models.ListOptions) (*models.Page[int], error) {
+			page, err := repo.ListTodos(userID, models.TodoListOptions{ListOptions: opts})
+			if err != nil {
+				return nil, err
+			}
+			return pageIDs(page, func(todo *models.Todo) int { return todo.ID }), nil
+		},
+		update: func(userID, id int, text string, version int) error {
+			return repo.UpdateTodo(userID, id, map[string]any{"title": text}, version)
+		},
+		delete:  repo.DeleteTodo,
+		restore: repo.RestoreTodo,
+		listDeleted: func(userID int) ([]int, error) {
+			todos, err := repo.ListDeletedTodos(userID)
+			return deletedIDs(todos, err, func(todo *models.Todo) (int, *time.Time) { return todo.ID, todo.DeletedAt })
+		},
+		purge: repo.PurgeTodos,
+	}
+}
+
+func pageIDs[T any](page *models.Page[T], id func(T) int) *models.Page[int] {
+	ids := &models.Page[int]{Items: make([]int, len(page.Items)), NextCursor: page.NextCursor, Total: page.Total}
+	for i, item := range page.Items {
+		ids.Items[i] = id(item)
+	}
+	return ids
+}
+
+// deletedIDs returns the ids of items in the trash, which must all have
+// their deletedAt.
+func deletedIDs[T any](items []T, err error, fields func(T) (int, *time.Time)) ([]int, error) {
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(items))
+	for i, item := range items {
+		id, deletedAt := fields(item)
+		if deletedAt == nil {
+			return nil, fmt.Errorf("item %d in the trash has no deletedAt", id)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+var itemConformanceCases = []struct {
+	name string
+	run  func(t *testing.T, store *Store, items itemRepository)
+}{
+	{"create and get", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		id := mustCreate(t, items, userID, "first")
+
+		text, version, err := items.get(userID, id)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if text != "first" || version != 1 {
+			t.Errorf("got %q at version %d, want %q at version 1", text, version, "first")
+		}
+	}},
+	{"missing and foreign items are not found", func(t *testing.T, store *Store, items itemRepository) {
+		owner := createTestUser(t, store)
+		other := createTestUser(t, store)
+		id := mustCreate(t, items, owner, "private")
+
+		_, _, err := items.get(other, id)
+		expectKind(t, "get of another user", err, apperr.ErrNotFound)
+		_, _, err = items.get(owner, id+1000000)
+		expectKind(t, "get of a missing item", err, apperr.ErrNotFound)
+		expectKind(t, "update of another user", items.update(other, id, "stolen", 0), apperr.ErrNotFound)
+		expectKind(t, "delete of another user", items.delete(other, id, 0), apperr.ErrNotFound)
+		expectKind(t, "restore of a live item", items.restore(owner, id), apperr.ErrNotFound)
+	}},
+	{"updates check and bump the version", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		id := mustCreate(t, items, userID, "v1")
+
+		if err := items.update(userID, id, "v2", 1); err != nil {
+			t.Fatalf("update at the current version: %v", err)
+		}
+		expectKind(t, "update at a stale version", items.update(userID, id, "stale", 1), apperr.ErrPreconditionFailed)
+		if err := items.update(userID, id, "v3", 0); err != nil {
+			t.Fatalf("unconditional update: %v", err)
+		}
+		expectKind(t, "delete at a stale version", items.delete(userID, id, 2), apperr.ErrPreconditionFailed)
+
+		text, version, err := items.get(userID, id)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if text != "v3" || version != 3 {
+			t.Errorf("got %q at version %d, want %q at version 3", text, version, "v3")
+		}
+	}},
+	{"delete moves to the trash and restore brings back", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		kept := mustCreate(t, items, userID, "kept")
+		deleted := mustCreate(t, items, userID, "deleted")
+
+		if err := items.delete(userID, deleted, 1); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		_, _, err := items.get(userID, deleted)
+		expectKind(t, "get of a deleted item", err, apperr.ErrNotFound)
+		expectKind(t, "second delete", items.delete(userID, deleted, 0), apperr.ErrNotFound)
+		expectKind(t, "update of a deleted item", items.update(userID, deleted, "gone", 0), apperr.ErrNotFound)
+		expectIDs(t, "list", mustList(t, items, userID, models.ListOptions{}).Items, kept)
+
+		trash, err := items.listDeleted(userID)
+		if err != nil {
+			t.Fatalf("list deleted: %v", err)
+		}
+		expectIDs(t, "trash", trash, deleted)
+
+		if err := items.restore(userID, deleted); err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+		if _, _, err := items.get(userID, deleted); err != nil {
+			t.Errorf("get of a restored item: %v", err)
+		}
+		trash, err = items.listDeleted(userID)
+		if err != nil {
+			t.Fatalf("list deleted: %v", err)
+		}
+		expectIDs(t, "trash after restore", trash)
+	}},
+	{"purge removes old items in the trash", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		live := mustCreate(t, items, userID, "live")
+		deleted := mustCreate(t, items, userID, "deleted")
+		if err := items.delete(userID, deleted, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+
+		purged, err := items.purge(time.Hour)
+		if err != nil {
+			t.Fatalf("purge: %v", err)
+		}
+		for _, item := range purged {
+			if item.ID == deleted {
+				t.Errorf("item deleted just now was purged with a retention of an hour")
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		purged, err = items.purge(time.Millisecond)
+		if err != nil {
+			t.Fatalf("purge: %v", err)
+		}
+		if !containsPurged(purged, userID, deleted) {
+			t.Errorf("purged %v, want it to include item %d of user %d", purged, deleted, userID)
+		}
+		if containsPurged(purged, userID, live) {
+			t.Errorf("live item %d was purged", live)
+		}
+		expectKind(t, "restore of a purged item", items.restore(userID, deleted), apperr.ErrNotFound)
+	}},
+	{"lists are ordered by createdAt and id", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		ids := mustCreateMany(t, items, userID, 4)
+		mustCreate(t, items, createTestUser(t, store), "someone else")
+
+		expectIDs(t, "default order", mustList(t, items, userID, models.ListOptions{}).Items, reversed(ids)...)
+		expectIDs(t, "ascending", mustList(t, items, userID, models.ListOptions{SortOrder: models.SortOrderAsc}).Items, ids...)
+
+		// Updating the first item moves it to the end of updatedAt order.
+		time.Sleep(10 * time.Millisecond)
+		if err := items.update(userID, ids[0], "updated", 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		byUpdate := models.ListOptions{SortBy: models.SortByUpdatedAt, SortOrder: models.SortOrderAsc}
+		expectIDs(t, "by updatedAt", mustList(t, items, userID, byUpdate).Items, ids[1], ids[2], ids[3], ids[0])
+	}},
+	{"cursors page through every item once", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		ids := mustCreateMany(t, items, userID, 5)
+
+		for _, order := range []string{models.SortOrderAsc, models.SortOrderDesc} {
+			opts := models.ListOptions{Limit: 2, SortOrder: order}
+			var seen []int
+			for pages := 1; ; pages++ {
+				page := mustList(t, items, userID, opts)
+				if page.Total != len(ids) {
+					t.Errorf("%s page %d: total %d, want %d", order, pages, page.Total, len(ids))
+				}
+				seen = append(seen, page.Items...)
+				if page.NextCursor == "" {
+					break
+				}
+				if pages > len(ids) {
+					t.Fatalf("%s: the cursor never ends", order)
+				}
+
+				cursor, err := models.DecodeCursor(page.NextCursor)
+				if err != nil {
+					t.Fatalf("decode cursor: %v", err)
+				}
+				opts.After = cursor
+			}
+
+			want := ids
+			if order == models.SortOrderDesc {
+				want = reversed(ids)
+			}
+			expectIDs(t, order+" pages", seen, want...)
+		}
+	}},
+	{"query matches case-insensitive text", func(t *testing.T, store *Store, items itemRepository) {
+		userID := createTestUser(t, store)
+		match := mustCreate(t, items, userID, "The Magna Carta")
+		mustCreate(t, items, userID, "Wars of the Roses")
+		mustCreate(t, items, userID, "100% wrong_match")
+
+		expectIDs(t, "query", mustList(t, items, userID, models.ListOptions{Query: "magna"}).Items, match)
+		expectIDs(t, "query with wildcards", mustList(t, items, userID, models.ListOptions{Query: "0%"}).Items, mustIDOf(t, items, userID, "100% wrong_match"))
+		expectIDs(t, "ids", mustList(t, items, userID, models.ListOptions{IDs: []int{match}}).Items, match)
+	}},
+}
+
+func TestItemRepositoryConformance(t *testing.T) {
+	kinds := map[string]func(*Store) itemRepository{
+		"notes": noteItems,
+		"todos": todoItems,
+	}
+
+	for driver, open := range conformanceBackends(t) {
+		for kind, items := range kinds {
+			for _, tc := range itemConformanceCases {
+				t.Run(driver+"/"+kind+"/"+tc.name, func(t *testing.T) {
+					store := open(t)
+					tc.run(t, store, items(store))
+				})
+			}
+		}
+	}
+}
+
+var todoConformanceCases = []struct {
+	name string
+	run  func(t *testing.T, store *Store)
+}{
+	{"scheduling fields round-trip", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		dueAt := time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC)
+		todo := &models.Todo{Title: "Review", DueAt: &dueAt, Priority: models.PriorityHigh, Recurrence: "FREQ=WEEKLY"}
+		if err := store.Todos.CreateTodo(userID, todo); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		got, err := store.Todos.GetTodoByID(userID, todo.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if got.DueAt == nil || !got.DueAt.Equal(dueAt) || got.Priority != models.PriorityHigh || got.Recurrence != "FREQ=WEEKLY" || got.CompletedAt != nil {
+			t.Errorf("got dueAt %v, priority %q, recurrence %q, completedAt %v", got.DueAt, got.Priority, got.Recurrence, got.CompletedAt)
+		}
+
+		completedAt := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+		updates := map[string]any{"completed": true, "completedAt": &completedAt, "dueAt": (*time.Time)(nil)}
+		if err := store.Todos.UpdateTodo(userID, todo.ID, updates, 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		got, err = store.Todos.GetTodoByID(userID, todo.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !got.Completed || got.CompletedAt == nil || !got.CompletedAt.Equal(completedAt) || got.DueAt != nil {
+			t.Errorf("got completed %t, completedAt %v, dueAt %v", got.Completed, got.CompletedAt, got.DueAt)
+		}
+	}},
+	{"list filters by completion, priority and due date", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		day := func(d int) *time.Time {
+			t := time.Date(2025, 6, d, 12, 0, 0, 0, time.UTC)
+			return &t
+		}
+		create := func(todo *models.Todo) int {
+			if todo.Priority == "" {
+				todo.Priority = models.PriorityNormal
+			}
+			if err := store.Todos.CreateTodo(userID, todo); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			return todo.ID
+		}
+		early := create(&models.Todo{Title: "early", DueAt: day(1), Priority: models.PriorityLow})
+		late := create(&models.Todo{Title: "late", DueAt: day(5)})
+		done := create(&models.Todo{Title: "done", DueAt: day(2), Completed: true})
+		undated := create(&models.Todo{Title: "undated", Priority: models.PriorityLow})
+
+		list := func(opts models.TodoListOptions) []int {
+			opts.SortOrder = models.SortOrderAsc
+			page, err := store.Todos.ListTodos(userID, opts)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			return pageIDs(page, func(todo *models.Todo) int { return todo.ID }).Items
+		}
+		notCompleted := false
+		expectIDs(t, "not completed", list(models.TodoListOptions{Completed: &notCompleted}), early, late, undated)
+		expectIDs(t, "low priority", list(models.TodoListOptions{Priority: models.PriorityLow}), early, undated)
+		expectIDs(t, "due from June 2", list(models.TodoListOptions{DueAfter: day(2)}), late, done)
+		expectIDs(t, "due before June 5", list(models.TodoListOptions{DueBefore: day(5)}), early, done)
+		expectIDs(t, "due in [June 1, June 2)", list(models.TodoListOptions{DueAfter: day(1), DueBefore: day(2)}), early)
+	}},
+	{"complete updates and creates the next todo at once", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		todo := &models.Todo{Title: "weekly", Priority: models.PriorityNormal}
+		if err := store.Todos.CreateTodo(userID, todo); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		dueAt := time.Date(2025, 6, 9, 7, 0, 0, 0, time.UTC)
+		next := &models.Todo{Title: "weekly", DueAt: &dueAt, Priority: models.PriorityNormal, Recurrence: "FREQ=WEEKLY"}
+		stale := *next
+		expectKind(t, "complete at a stale version", store.Todos.CompleteTodo(userID, todo.ID, map[string]any{"completed": true}, 2, &stale), apperr.ErrPreconditionFailed)
+		page, err := store.Todos.ListTodos(userID, models.TodoListOptions{})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if page.Total != 1 {
+			t.Fatalf("a failed completion left %d todos, want 1", page.Total)
+		}
+
+		if err := store.Todos.CompleteTodo(userID, todo.ID, map[string]any{"completed": true, "recurrence": ""}, 1, next); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		if next.ID == 0 || next.ID == todo.ID || next.Version != 1 {
+			t.Fatalf("next todo has id %d and version %d", next.ID, next.Version)
+		}
+
+		completed, err := store.Todos.GetTodoByID(userID, todo.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !completed.Completed || completed.Version != 2 {
+			t.Errorf("completed todo is completed %t at version %d", completed.Completed, completed.Version)
+		}
+		created, err := store.Todos.GetTodoByID(userID, next.ID)
+		if err != nil {
+			t.Fatalf("get next: %v", err)
+		}
+		if created.Completed || created.DueAt == nil || !created.DueAt.Equal(dueAt) || created.Recurrence != "FREQ=WEEKLY" {
+			t.Errorf("next todo is completed %t, due %v, recurrence %q", created.Completed, created.DueAt, created.Recurrence)
+		}
+	}},
+}
+
+func TestTodoRepositoryConformance(t *testing.T) {
+	for driver, open := range conformanceBackends(t) {
+		for _, tc := range todoConformanceCases {
+			t.Run(driver+"/"+tc.name, func(t *testing.T) {
+				tc.run(t, open(t))
+			})
+		}
+	}
+}
+
+func TestNoteImportConformance(t *testing.T) {
+	for driver, open := range conformanceBackends(t) {
+		t.Run(driver, func(t *testing.T) {
+			store := open(t)
+			userID := createTestUser(t, store)
+			existing := &models.Note{Content: "known"}
+			if err := store.Notes.CreateNote(userID, existing); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if err := store.Tags.SetNoteTags(userID, existing.ID, []string{"kept"}); err != nil {
+				t.Fatalf("set tags: %v", err)
+			}
+
+			notes := []*models.Note{
+				{Content: "known", Tags: []string{"ignored"}},
+				{Content: "new", Tags: []string{"history", "law"}},
+				{Content: "new", Tags: []string{"ignored"}},
+			}
+			created, err := store.Notes.ImportNotes(userID, notes)
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+
+			want := []bool{false, true, false}
+			for i := range want {
+				if created[i] != want[i] {
+					t.Errorf("note %d created %t, want %t", i, created[i], want[i])
+				}
+			}
+			if notes[0].ID != existing.ID || notes[2].ID != notes[1].ID {
+				t.Errorf("duplicates got ids %d and %d, want %d and %d", notes[0].ID, notes[2].ID, existing.ID, notes[1].ID)
+			}
+
+			tags, err := store.Tags.GetNoteTags(userID, []int{existing.ID, notes[1].ID})
+			if err != nil {
+				t.Fatalf("get tags: %v", err)
+			}
+			expectTags(t, "tags of the duplicated note", tags[existing.ID], "kept")
+			expectTags(t, "tags of the new note", tags[notes[1].ID], "history", "law")
+		})
+	}
+}
+
+// taggedWriteConformanceCases check that writes that carry tags store them
+// with the note or todo.
+var taggedWriteConformanceCases = []struct {
+	name string
+	run  func(t *testing.T, store *Store)
+}{
+	{"create a note with tags", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		note := &models.Note{Content: "Magna Carta", Tags: []string{"history", "law"}}
+		if err := store.Notes.CreateNote(userID, note); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		tags, err := store.Tags.GetNoteTags(userID, []int{note.ID})
+		if err != nil {
+			t.Fatalf("get tags: %v", err)
+		}
+		expectTags(t, "tags of the new note", tags[note.ID], "history", "law")
+	}},
+	{"create a todo with tags", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		todo := &models.Todo{Title: "Review", Priority: models.PriorityNormal, Tags: []string{"history"}}
+		if err := store.Todos.CreateTodo(userID, todo); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		tags, err := store.Tags.GetTodoTags(userID, []int{todo.ID})
+		if err != nil {
+			t.Fatalf("get tags: %v", err)
+		}
+		expectTags(t, "tags of the new todo", tags[todo.ID], "history")
+	}},
+	{"update a note with tags", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		note := &models.Note{Content: "Magna Carta", Tags: []string{"history"}}
+		if err := store.Notes.CreateNote(userID, note); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		stale := map[string]any{"content": "stale", "tags": []string{"stale"}}
+		expectKind(t, "update at a stale version", store.Notes.UpdateNote(userID, note.ID, stale, 2), apperr.ErrPreconditionFailed)
+		updates := map[string]any{"content": "Magna Carta, 1215", "tags": []string{"law"}}
+		if err := store.Notes.UpdateNote(userID, note.ID, updates, 1); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+
+		updated, err := store.Notes.GetNoteByID(userID, note.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if updated.Content != "Magna Carta, 1215" || updated.Version != 2 {
+			t.Errorf("updated note has content %q at version %d", updated.Content, updated.Version)
+		}
+		tags, err := store.Tags.GetNoteTags(userID, []int{note.ID})
+		if err != nil {
+			t.Fatalf("get tags: %v", err)
+		}
+		expectTags(t, "tags of the updated note", tags[note.ID], "law")
+	}},
+	{"update the tags of a todo", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		todo := &models.Todo{Title: "Review", Priority: models.PriorityNormal, Tags: []string{"history"}}
+		if err := store.Todos.CreateTodo(userID, todo); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		expectKind(t, "update at a stale version", store.Todos.UpdateTodo(userID, todo.ID, map[string]any{"tags": []string{"stale"}}, 2), apperr.ErrPreconditionFailed)
+		if err := store.Todos.UpdateTodo(userID, todo.ID, map[string]any{"tags": []string{"law"}}, 1); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+
+		tags, err := store.Tags.GetTodoTags(userID, []int{todo.ID})
+		if err != nil {
+			t.Fatalf("get tags: %v", err)
+		}
+		expectTags(t, "tags of the updated todo", tags[todo.ID], "law")
+		all, err := store.Tags.GetAllTags(userID)
+		if err != nil {
+			t.Fatalf("get all tags: %v", err)
+		}
+		if len(all) != 1 {
+			t.Errorf("user has %d tags, want only the one in use", len(all))
+		}
+	}},
+	{"complete a todo and tag the next one", func(t *testing.T, store *Store) {
+		userID := createTestUser(t, store)
+		todo := &models.Todo{Title: "weekly", Priority: models.PriorityNormal, Tags: []string{"history"}}
+		if err := store.Todos.CreateTodo(userID, todo); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		dueAt := time.Date(2025, 6, 9, 7, 0, 0, 0, time.UTC)
+		next := &models.Todo{Title: "weekly", DueAt: &dueAt, Priority: models.PriorityNormal, Recurrence: "FREQ=WEEKLY", Tags: []string{"law"}}
+		updates := map[string]any{"completed": true, "recurrence": "", "tags": []string{"law"}}
+		if err := store.Todos.CompleteTodo(userID, todo.ID, updates, 1, next); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+
+		tags, err := store.Tags.GetTodoTags(userID, []int{todo.ID, next.ID})
+		if err != nil {
+			t.Fatalf("get tags: %v", err)
+		}
+		expectTags(t, "tags of the completed todo", tags[todo.ID], "law")
+		expectTags(t, "tags of the next todo", tags[next.ID], "law")
+	}},
+}
+
+func TestTaggedWriteConformance(t *testing.T) {
+	for driver, open := range conformanceBackends(t) {
+		for _, tc := range taggedWriteConformanceCases {
+			t.Run(driver+"/"+tc.name, func(t *testing.T) {
+				tc.run(t, open(t))
+			})
+		}
+	}
+}
+
+func mustCreate(t *testing.T, items itemRepository, userID int, text string) int {
+	t.Helper()
+	id, err := items.create(userID, text)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return id
+}
+
+// mustCreateMany creates n items a little apart, so that they have distinct
+// timestamps on every driver.
+func mustCreateMany(t *testing.T, items itemRepository, userID, n int) []int {
+	t.Helper()
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = mustCreate(t, items, userID, fmt.Sprintf("item %d", i))
+		time.Sleep(2 * time.Millisecond)
+	}
+	return ids
+}
+
+func mustList(t *testing.T, items itemRepository, userID int, opts models.ListOptions) *models.Page[int] {
+	t.Helper()
+	page, err := items.list(userID, opts)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	return page
+}
+
+func mustIDOf(t *testing.T, items itemRepository, userID int, text string) int {
+	t.Helper()
+	for _, id := range mustList(t, items, userID, models.ListOptions{}).Items {
+		if got, _, err := items.get(userID, id); err == nil && got == text {
+			return id
+		}
+	}
+	t.Fatalf("no item %q", text)
+	return 0
+}
+
+func expectKind(t *testing.T, what string, err, kind error) {
+	t.Helper()
+	if !errors.Is(err, kind) {
+		t.Errorf("%s: got error %v, want %v", what, err, kind)
+	}
+}
+
+func expectIDs(t *testing.T, what string, got []int, want ...int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got ids %v, want %v", what, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: got ids %v, want %v", what, got, want)
+			return
+		}
+	}
+}
+
+func expectTags(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("%s: got %v, want %v", what, got, want)
+	}
+}
+
+func reversed(ids []int) []int {
+	out := make([]int, len(ids))
+	for i, id := range ids {
+		out[len(ids)-1-i] = id
+	}
+	return out
+}
+
+func containsPurged(purged []PurgedItem, userID, id int) bool {
+	for _, item := range purged {
+		if item.UserID == userID && item.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+)
+
+type ReviewRepository interface {
+	// GetReviewState returns nil without an error when the item has never
+	// been reviewed.
+	GetReviewState(userID int, itemType string, itemID int) (*models.ReviewState, error)
+	GetReviewStates(userID int, itemType string) ([]*models.ReviewState, error)
+	SaveReview(userID int, state *models.ReviewState, entry *models.ReviewLog) error
+}
+
+type PostgresReviewRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReviewRepository(databaseURL string) (*PostgresReviewRepository, error) {
+	db, err := openPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresReviewRepository{db: db}, nil
+}
+
+const reviewStateColumns = `id, itemType, itemId, algorithm, repetitions, lapses, easeFactor, intervalDays,
+		stability, difficulty, lastGrade, dueAt, lastReviewedAt, createdAt, updatedAt`
+
+func scanReviewState(row rowScanner) (*models.ReviewState, error) {
+	state := &models.ReviewState{}
+	var lastReviewedAt sql.NullTime
+
+	err := row.Scan(&state.ID, &state.ItemType, &state.ItemID, &state.Algorithm, &state.Repetitions, &state.Lapses,
+		&state.EaseFactor, &state.IntervalDays, &state.Stability, &state.Difficulty, &state.LastGrade,
+		&state.DueAt, &lastReviewedAt, &state.CreatedAt, &state.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastReviewedAt.Valid {
+		state.LastReviewedAt = &lastReviewedAt.Time
+	}
+
+	return state, nil
+}
+
+func (r *PostgresReviewRepository) GetReviewState(userID int, itemType string, itemID int) (*models.ReviewState, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM gocourse.review_states 
+		WHERE itemType = $1 AND itemId = $2 AND userId = $3`, reviewStateColumns)
+
+	state, err := scanReviewState(r.db.QueryRow(query, itemType, itemID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get review state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (r *PostgresReviewRepository) GetReviewStates(userID int, itemType string) ([]*models.ReviewState, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM gocourse.review_states 
+		WHERE itemType = $1 AND userId = $2 
+		ORDER BY dueAt ASC, id ASC`, reviewStateColumns)
+
+	rows, err := r.db.Query(query, itemType, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review states: %w", err)
+	}
+	defer rows.Close()
+
+	states := make([]*models.ReviewState, 0)
+	for rows.Next() {
+		state, err := scanReviewState(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review state: %w", err)
+		}
+		states = append(states, state)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over review states: %w", err)
+	}
+
+	return states, nil
+}
+
+// SaveReview upserts the review state of an item and appends to its log. Item
+// ids are unique across users, the owner check on conflict only guards
+// against rows without an owner.
+func (r *PostgresReviewRepository) SaveReview(userID int, state *models.ReviewState, entry *models.ReviewLog) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gocourse.review_states (userId, itemType, itemId, algorithm, repetitions, lapses, easeFactor,
+			intervalDays, stability, difficulty, lastGrade, dueAt, lastReviewedAt) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) 
+		ON CONFLICT (itemType, itemId) DO UPDATE SET 
+			algorithm = EXCLUDED.algorithm, 
+			repetitions = EXCLUDED.repetitions, 
+			lapses = EXCLUDED.lapses, 
+			easeFactor = EXCLUDED.easeFactor, 
+			intervalDays = EXCLUDED.intervalDays, 
+			stability = EXCLUDED.stability, 
+			difficulty = EXCLUDED.difficulty, 
+			lastGrade = EXCLUDED.lastGrade, 
+			dueAt = EXCLUDED.dueAt, 
+			lastReviewedAt = EXCLUDED.lastReviewedAt, 
+			updatedAt = NOW() 
+		WHERE gocourse.review_states.userId = EXCLUDED.userId 
+		RETURNING id, createdAt, updatedAt`
+
+	row := tx.QueryRow(query, userID, state.ItemType, state.ItemID, state.Algorithm, state.Repetitions, state.Lapses,
+		state.EaseFactor, state.IntervalDays, state.Stability, state.Difficulty, state.LastGrade,
+		state.DueAt, state.LastReviewedAt)
+	if err := row.Scan(&state.ID, &state.CreatedAt, &state.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save review state: %w", err)
+	}
+
+	entry.StateID = state.ID
+	logQuery := `
+		INSERT INTO gocourse.review_logs (stateId, grade, intervalDays, reviewedAt) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id`
+
+	row = tx.QueryRow(logQuery, entry.StateID, entry.Grade, entry.IntervalDays, entry.ReviewedAt)
+	if err := row.Scan(&entry.ID); err != nil {
+		return fmt.Errorf("failed to save review log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresReviewRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAPIKeyRepository(db *sql.DB) *SQLiteAPIKeyRepository {
+	return &SQLiteAPIKeyRepository{db: db}
+}
+
+// Scopes are stored as a JSON encoded list, like flashcard tags.
+func scanSQLiteAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &scopes,
+		&expiresAt, &lastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode scopes: %w", err)
+	}
+	setAPIKeyTimes(key, expiresAt, lastUsedAt)
+
+	return key, nil
+}
+
+func (r *SQLiteAPIKeyRepository) CreateAPIKey(userID int, key *models.APIKey, keyHash string) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode scopes: %w", err)
+	}
+
+	query := `
+		INSERT INTO api_keys (userId, name, prefix, keyHash, scopes, expiresAt, createdAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	var expiresAt any
+	if key.ExpiresAt != nil {
+		expiresAt = key.ExpiresAt.UTC()
+	}
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(query, userID, key.Name, key.Prefix, keyHash, string(scopes), expiresAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	key.ID = int(id)
+	key.UserID = userID
+	key.CreatedAt = now
+
+	return nil
+}
+
+func (r *SQLiteAPIKeyRepository) GetAllAPIKeys(userID int) ([]*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE userId = ? ORDER BY createdAt DESC, id DESC"
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *SQLiteAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE keyHash = ?"
+
+	key, err := scanSQLiteAPIKey(r.db.QueryRow(query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("API key not found")
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *SQLiteAPIKeyRepository) TouchAPIKey(id int, usedAt time.Time) error {
+	query := "UPDATE api_keys SET lastUsedAt = ? WHERE id = ?"
+
+	if _, err := r.db.Exec(query, usedAt.UTC(), id); err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteAPIKeyRepository) DeleteAPIKey(userID, id int) error {
+	query := "DELETE FROM api_keys WHERE id = ? AND userId = ?"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("API key with id %d not found", id)
+	}
+
+	return nil
+}
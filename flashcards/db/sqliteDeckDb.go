@@ -0,0 +1,190 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type SQLiteDeckRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDeckRepository(db *sql.DB) *SQLiteDeckRepository {
+	return &SQLiteDeckRepository{db: db}
+}
+
+func (r *SQLiteDeckRepository) CreateDeck(userID int, deck *models.Deck) error {
+	query := `
+		INSERT INTO decks (userId, name, description, parentId, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(query, userID, deck.Name, deck.Description, deck.ParentID, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create deck: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to create deck: %w", err)
+	}
+
+	deck.ID = int(id)
+	deck.CreatedAt = now
+	deck.UpdatedAt = now
+
+	return nil
+}
+
+func (r *SQLiteDeckRepository) GetDeckByID(userID, id int) (*models.Deck, error) {
+	query := "SELECT " + deckColumns + " FROM decks WHERE id = ? AND userId = ?"
+
+	deck, err := scanDeck(r.db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("deck with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get deck: %w", err)
+	}
+
+	return deck, nil
+}
+
+func (r *SQLiteDeckRepository) GetAllDecks(userID int) ([]*models.Deck, error) {
+	query := "SELECT " + deckColumns + " FROM decks WHERE userId = ? ORDER BY name, id"
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query decks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDecks(rows)
+}
+
+func (r *SQLiteDeckRepository) UpdateDeck(userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return apperr.Validation("", "no updates provided")
+	}
+
+	query := "UPDATE decks SET "
+	args := []any{}
+
+	for field, value := range updates {
+		query += fmt.Sprintf("%s = ?, ", field)
+		args = append(args, value)
+	}
+
+	query += "updatedAt = ? WHERE id = ? AND userId = ?"
+	args = append(args, time.Now().UTC(), id, userID)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+// DeleteDeck deletes the deck and, through the foreign keys, its sub-decks
+// and note memberships. The notes themselves are kept.
+func (r *SQLiteDeckRepository) DeleteDeck(userID, id int) error {
+	query := "DELETE FROM decks WHERE id = ? AND userId = ?"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+// AddNoteToDeck only links the note when both the deck and the note belong
+// to userID.
+func (r *SQLiteDeckRepository) AddNoteToDeck(userID, deckID, noteID int) error {
+	query := `
+		INSERT OR IGNORE INTO deck_notes (deckId, noteId, createdAt) 
+		SELECT d.id, n.id, ? 
+		FROM decks d, notes n 
+		WHERE d.id = ? AND n.id = ? AND d.userId = ? AND n.userId = ?`
+
+	if _, err := r.db.Exec(query, time.Now().UTC(), deckID, noteID, userID, userID); err != nil {
+		return fmt.Errorf("failed to add note to deck: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteDeckRepository) RemoveNoteFromDeck(userID, deckID, noteID int) error {
+	query := `
+		DELETE FROM deck_notes 
+		WHERE deckId = ? AND noteId = ? 
+		AND deckId IN (SELECT id FROM decks WHERE userId = ?)`
+
+	result, err := r.db.Exec(query, deckID, noteID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove note from deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("note %d in deck %d not found", noteID, deckID)
+	}
+
+	return nil
+}
+
+// GetDeckNoteIDs returns the distinct ids of the notes in any of deckIDs.
+func (r *SQLiteDeckRepository) GetDeckNoteIDs(userID int, deckIDs []int) ([]int, error) {
+	if len(deckIDs) == 0 {
+		return []int{}, nil
+	}
+
+	placeholders := sqlitePlaceholders(len(deckIDs))
+	query := `
+		SELECT DISTINCT dn.noteId 
+		FROM deck_notes dn 
+		JOIN decks d ON d.id = dn.deckId 
+		WHERE dn.deckId IN (` + placeholders + `) AND d.userId = ? 
+		ORDER BY dn.noteId`
+
+	args := make([]any, 0, len(deckIDs)+1)
+	for _, id := range deckIDs {
+		args = append(args, id)
+	}
+	args = append(args, userID)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deck notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNoteIDs(rows)
+}
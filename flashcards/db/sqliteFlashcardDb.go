@@ -0,0 +1,253 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type SQLiteFlashcardRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteFlashcardRepository(db *sql.DB) *SQLiteFlashcardRepository {
+	return &SQLiteFlashcardRepository{db: db}
+}
+
+const sqliteFlashcardColumns = "id, front, back, noteId, tags, createdAt, updatedAt"
+
+// SQLite has no array type, so tags are stored as a JSON encoded list.
+func encodeSQLiteTags(tags []string) (string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tags: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func scanSQLiteFlashcard(row rowScanner) (*models.Flashcard, error) {
+	card := &models.Flashcard{}
+	var noteID sql.NullInt64
+	var tags string
+
+	err := row.Scan(&card.ID, &card.Front, &card.Back, &noteID, &tags, &card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if noteID.Valid {
+		id := int(noteID.Int64)
+		card.NoteID = &id
+	}
+	if err := json.Unmarshal([]byte(tags), &card.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	if card.Tags == nil {
+		card.Tags = []string{}
+	}
+
+	return card, nil
+}
+
+func (r *SQLiteFlashcardRepository) CreateFlashcard(userID int, card *models.Flashcard) error {
+	return r.insertFlashcard(r.db, userID, card)
+}
+
+func (r *SQLiteFlashcardRepository) CreateFlashcards(userID int, cards []*models.Flashcard) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, card := range cards {
+		if err := r.insertFlashcard(tx, userID, card); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit flashcards: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteFlashcardRepository) insertFlashcard(execer sqlExecer, userID int, card *models.Flashcard) error {
+	query := `
+		INSERT INTO flashcards (userId, front, back, noteId, tags, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	tags, err := encodeSQLiteTags(card.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to create flashcard: %w", err)
+	}
+
+	now := time.Now().UTC()
+	result, err := execer.Exec(query, userID, card.Front, card.Back, card.NoteID, tags, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create flashcard: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to create flashcard: %w", err)
+	}
+
+	card.ID = int(id)
+	card.CreatedAt = now
+	card.UpdatedAt = now
+
+	return nil
+}
+
+func (r *SQLiteFlashcardRepository) GetFlashcardByID(userID, id int) (*models.Flashcard, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM flashcards 
+		WHERE id = ? AND userId = ?`, sqliteFlashcardColumns)
+
+	card, err := scanSQLiteFlashcard(r.db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("flashcard with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get flashcard: %w", err)
+	}
+
+	return card, nil
+}
+
+func (r *SQLiteFlashcardRepository) GetAllFlashcards(userID int) ([]*models.Flashcard, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM flashcards 
+		WHERE userId = ? 
+		ORDER BY createdAt DESC, id DESC`, sqliteFlashcardColumns)
+
+	return r.queryFlashcards(query, userID)
+}
+
+// ListFlashcards returns a page of flashcards. The total is only counted when
+// there is more than one page.
+func (r *SQLiteFlashcardRepository) ListFlashcards(userID int, opts models.ListOptions) (*models.Page[*models.Flashcard], error) {
+	q := newListQuery(false)
+	q.ownedBy(userID)
+	q.filter(opts, "front", "back")
+	countQuery := "SELECT COUNT(*) FROM flashcards" + q.whereClause()
+	countArgs := append([]any{}, q.args...)
+
+	q.after(opts)
+	query := "SELECT " + sqliteFlashcardColumns + " FROM flashcards" + q.whereClause() + q.orderAndLimit(opts)
+
+	cards, err := r.queryFlashcards(query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(cards, opts, flashcardListKey, func() (int, error) {
+		var total int
+		err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+		return total, err
+	})
+}
+
+func (r *SQLiteFlashcardRepository) GetFlashcardsByNoteID(userID, noteID int) ([]*models.Flashcard, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM flashcards 
+		WHERE noteId = ? AND userId = ? 
+		ORDER BY createdAt DESC, id DESC`, sqliteFlashcardColumns)
+
+	return r.queryFlashcards(query, noteID, userID)
+}
+
+func (r *SQLiteFlashcardRepository) queryFlashcards(query string, args ...any) ([]*models.Flashcard, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := make([]*models.Flashcard, 0)
+	for rows.Next() {
+		card, err := scanSQLiteFlashcard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+		}
+		cards = append(cards, card)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return cards, nil
+}
+
+func (r *SQLiteFlashcardRepository) UpdateFlashcard(userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return apperr.Validation("", "no updates provided")
+	}
+
+	query := "UPDATE flashcards SET "
+	args := []any{}
+
+	for field, value := range updates {
+		if tags, ok := value.([]string); ok {
+			encoded, err := encodeSQLiteTags(tags)
+			if err != nil {
+				return fmt.Errorf("failed to update flashcard: %w", err)
+			}
+			value = encoded
+		}
+		query += fmt.Sprintf("%s = ?, ", field)
+		args = append(args, value)
+	}
+
+	query += "updatedAt = ? WHERE id = ? AND userId = ?"
+	args = append(args, time.Now().UTC(), id, userID)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("flashcard with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *SQLiteFlashcardRepository) DeleteFlashcard(userID, id int) error {
+	query := "DELETE FROM flashcards WHERE id = ? AND userId = ?"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("flashcard with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,433 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+// sqliteNoteVersionQuery selects the version of a note for staleVersion.
+const sqliteNoteVersionQuery = "SELECT version FROM notes WHERE id = ? AND userId = ? AND deletedAt IS NULL"
+
+type SQLiteNoteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteNoteRepository(db *sql.DB) *SQLiteNoteRepository {
+	return &SQLiteNoteRepository{db: db}
+}
+
+// CreateNote saves the note and its first revision.
+func (r *SQLiteNoteRepository) CreateNote(userID int, note *models.Note) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSQLiteNote(tx, userID, note); err != nil {
+		return err
+	}
+	if err := addSQLiteTags(tx, userID, noteTagLink, note.ID, note.Tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
+	}
+
+	return nil
+}
+
+// ImportNotes saves the notes with their tags and first revisions in a
+// single transaction. A note whose content a note of the user outside the
+// trash already has, including one saved earlier in the same call, is not
+// saved and gets the ID of that note instead, whose tags are left as they
+// are.
+func (r *SQLiteNoteRepository) ImportNotes(userID int, notes []*models.Note) ([]bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id 
+		FROM notes 
+		WHERE userId = ? AND content = ? AND deletedAt IS NULL 
+		LIMIT 1`
+
+	created := make([]bool, len(notes))
+	for i, note := range notes {
+		err := tx.QueryRow(query, userID, note.Content).Scan(&note.ID)
+		if err == nil {
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to look up note content: %w", err)
+		}
+
+		if err := insertSQLiteNote(tx, userID, note); err != nil {
+			return nil, err
+		}
+		if err := addSQLiteTags(tx, userID, noteTagLink, note.ID, note.Tags); err != nil {
+			return nil, err
+		}
+		created[i] = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit notes: %w", err)
+	}
+
+	return created, nil
+}
+
+// insertSQLiteNote saves a new note and its first revision within tx.
+func insertSQLiteNote(tx *sql.Tx, userID int, note *models.Note) error {
+	query := `
+		INSERT INTO notes (userId, content, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(query, userID, note.Content, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create note: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to create note: %w", err)
+	}
+
+	note.ID = int(id)
+	note.CreatedAt = now
+	note.UpdatedAt = now
+	note.Version = 1
+
+	return addSQLiteNoteRevision(tx, note.ID)
+}
+
+func (r *SQLiteNoteRepository) GetNoteByID(userID, id int) (*models.Note, error) {
+	query := `
+		SELECT id, content, createdAt, updatedAt, version 
+		FROM notes 
+		WHERE id = ? AND userId = ? AND deletedAt IS NULL`
+
+	note := &models.Note{}
+	row := r.db.QueryRow(query, id, userID)
+
+	err := row.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("note with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+
+	return note, nil
+}
+
+func (r *SQLiteNoteRepository) ListNotes(userID int, opts models.NoteListOptions) (*models.Page[*models.Note], error) {
+	q := newListQuery(false)
+	q.ownedBy(userID)
+	q.excludeDeleted()
+	q.filter(opts.ListOptions, "content")
+	countQuery := "SELECT COUNT(*) FROM notes" + q.whereClause()
+	countArgs := append([]any{}, q.args...)
+
+	q.after(opts.ListOptions)
+	query := "SELECT id, content, createdAt, updatedAt, version FROM notes" + q.whereClause() + q.orderAndLimit(opts.ListOptions)
+
+	rows, err := r.db.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes, err := scanNotes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(notes, opts.ListOptions, noteListKey, func() (int, error) {
+		var total int
+		err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+		return total, err
+	})
+}
+
+// UpdateNote applies the updates and bumps the version of the note. Unless
+// version is 0, the note is only updated while it is at version. New content
+// is saved as a new revision. A tags update replaces the tags of the note
+// in the same transaction.
+func (r *SQLiteNoteRepository) UpdateNote(userID, id int, updates map[string]any, version int) error {
+	tags, setTags, err := updatedTags(updates)
+	if err != nil {
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE notes SET "
+	args := []any{}
+
+	for field, value := range updates {
+		if field == tagsUpdate {
+			continue
+		}
+		query += fmt.Sprintf("%s = ?, ", field)
+		args = append(args, value)
+	}
+
+	query += "updatedAt = ?, version = version + 1 WHERE id = ? AND userId = ? AND deletedAt IS NULL"
+	args = append(args, time.Now().UTC(), id, userID)
+	if version > 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return staleVersion(tx, "note", id, sqliteNoteVersionQuery, id, userID)
+	}
+
+	if _, ok := updates["content"]; ok {
+		if err := addSQLiteNoteRevision(tx, id); err != nil {
+			return err
+		}
+	}
+	if setTags {
+		if err := replaceSQLiteTags(tx, userID, noteTagLink, id, tags); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteNote moves the note to the trash. Unless version is 0, only while
+// the note is at version.
+func (r *SQLiteNoteRepository) DeleteNote(userID, id, version int) error {
+	query := "UPDATE notes SET deletedAt = ? WHERE id = ? AND userId = ? AND deletedAt IS NULL AND (? = 0 OR version = ?)"
+
+	result, err := r.db.Exec(query, time.Now().UTC(), id, userID, version, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return staleVersion(r.db, "note", id, sqliteNoteVersionQuery, id, userID)
+	}
+
+	return nil
+}
+
+func (r *SQLiteNoteRepository) RestoreNote(userID, id int) error {
+	query := "UPDATE notes SET deletedAt = NULL WHERE id = ? AND userId = ? AND deletedAt IS NOT NULL"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("note with id %d not found in trash", id)
+	}
+
+	return nil
+}
+
+// ListDeletedNotes returns the notes in the trash, most recently deleted
+// first.
+func (r *SQLiteNoteRepository) ListDeletedNotes(userID int) ([]*models.Note, error) {
+	query := `
+		SELECT id, content, createdAt, updatedAt, version, deletedAt 
+		FROM notes 
+		WHERE userId = ? AND deletedAt IS NOT NULL 
+		ORDER BY deletedAt DESC, id DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeletedNotes(rows)
+}
+
+// PurgeNotes removes the notes of every user that have been in the trash
+// for longer than olderThan.
+func (r *SQLiteNoteRepository) PurgeNotes(olderThan time.Duration) ([]PurgedItem, error) {
+	query := "DELETE FROM notes WHERE deletedAt < ? RETURNING userId, id"
+
+	rows, err := r.db.Query(query, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPurgedItems(rows)
+}
+
+// SearchNotes ranks notes with the notes_fts index using bm25. Every query
+// word has to match, either exactly or as a prefix of a word in the note.
+func (r *SQLiteNoteRepository) SearchNotes(userID int, query string, limit int) ([]*models.NoteSearchResult, error) {
+	match := prefixQuery(query, " ", `"%s"*`)
+	if match == "" {
+		return []*models.NoteSearchResult{}, nil
+	}
+
+	sqlQuery := `
+		SELECT n.id, n.content, n.createdAt, n.updatedAt, n.version,
+			-bm25(notes_fts) AS score,
+			snippet(notes_fts, 0, '<mark>', '</mark>', '…', 24)
+		FROM notes_fts
+		JOIN notes n ON n.id = notes_fts.rowid
+		WHERE notes_fts MATCH ? AND n.userId = ? AND n.deletedAt IS NULL
+		ORDER BY score DESC, n.createdAt DESC
+		LIMIT ?`
+
+	rows, err := r.db.Query(sqlQuery, match, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNoteSearchResults(rows)
+}
+
+// ListNoteRevisions returns the revisions of a note, newest first.
+func (r *SQLiteNoteRepository) ListNoteRevisions(userID, noteID int) ([]*models.NoteRevision, error) {
+	query := `
+		SELECT r.noteId, r.revision, r.content, r.createdAt 
+		FROM note_revisions r 
+		JOIN notes n ON n.id = r.noteId 
+		WHERE r.noteId = ? AND n.userId = ? AND n.deletedAt IS NULL 
+		ORDER BY r.revision DESC`
+
+	rows, err := r.db.Query(query, noteID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query note revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions, err := scanNoteRevisions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every note has at least the revision it was created with.
+	if len(revisions) == 0 {
+		return nil, apperr.NotFound("note with id %d not found", noteID)
+	}
+
+	return revisions, nil
+}
+
+func (r *SQLiteNoteRepository) GetNoteRevision(userID, noteID, revision int) (*models.NoteRevision, error) {
+	query := `
+		SELECT r.noteId, r.revision, r.content, r.createdAt 
+		FROM note_revisions r 
+		JOIN notes n ON n.id = r.noteId 
+		WHERE r.noteId = ? AND n.userId = ? AND n.deletedAt IS NULL AND r.revision = ?`
+
+	rev := &models.NoteRevision{}
+	row := r.db.QueryRow(query, noteID, userID, revision)
+
+	err := row.Scan(&rev.NoteID, &rev.Revision, &rev.Content, &rev.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("revision %d of note %d not found", revision, noteID)
+		}
+		return nil, fmt.Errorf("failed to get note revision: %w", err)
+	}
+
+	return rev, nil
+}
+
+// RestoreNoteRevision sets the content of the note back to the one of
+// revision, saved as a new revision so that the history is never rewritten.
+func (r *SQLiteNoteRepository) RestoreNoteRevision(userID, noteID, revision int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE notes 
+		SET content = r.content, updatedAt = ?, version = notes.version + 1 
+		FROM note_revisions r 
+		WHERE notes.id = ? AND notes.userId = ? AND notes.deletedAt IS NULL AND r.noteId = notes.id AND r.revision = ?`
+
+	result, err := tx.Exec(query, time.Now().UTC(), noteID, userID, revision)
+	if err != nil {
+		return fmt.Errorf("failed to restore note revision: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("revision %d of note %d not found", revision, noteID)
+	}
+
+	if err := addSQLiteNoteRevision(tx, noteID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
+	}
+
+	return nil
+}
+
+// addSQLiteNoteRevision saves the current content of the note as its next
+// revision.
+func addSQLiteNoteRevision(execer sqlExecer, noteID int) error {
+	query := `
+		INSERT INTO note_revisions (noteId, revision, content, createdAt) 
+		SELECT id, COALESCE((SELECT MAX(revision) FROM note_revisions WHERE noteId = ?), 0) + 1, content, updatedAt 
+		FROM notes 
+		WHERE id = ?`
+
+	if _, err := execer.Exec(query, noteID, noteID); err != nil {
+		return fmt.Errorf("failed to save note revision: %w", err)
+	}
+
+	return nil
+}
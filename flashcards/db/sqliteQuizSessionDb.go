@@ -0,0 +1,226 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type SQLiteQuizSessionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteQuizSessionRepository(db *sql.DB) *SQLiteQuizSessionRepository {
+	return &SQLiteQuizSessionRepository{db: db}
+}
+
+func (r *SQLiteQuizSessionRepository) CreateQuizSession(userID int, session *models.QuizSession) error {
+	noteIDs, err := json.Marshal(session.NoteIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode note ids: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO quiz_sessions (id, userId, noteIds, status, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(query, session.ID, userID, string(noteIDs), session.Status, now, now); err != nil {
+		return fmt.Errorf("failed to create quiz session: %w", err)
+	}
+
+	if err := insertSQLiteQuizMessages(tx, session.ID, session.Messages, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit quiz session: %w", err)
+	}
+
+	session.CreatedAt = now
+	session.UpdatedAt = now
+
+	return nil
+}
+
+func (r *SQLiteQuizSessionRepository) GetQuizSessionByID(userID int, id string) (*models.QuizSession, error) {
+	query := `
+		SELECT id, noteIds, status, createdAt, updatedAt 
+		FROM quiz_sessions 
+		WHERE id = ? AND userId = ?`
+
+	session := &models.QuizSession{}
+	var noteIDs string
+
+	row := r.db.QueryRow(query, id, userID)
+	err := row.Scan(&session.ID, &noteIDs, &session.Status, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("quiz session with id %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get quiz session: %w", err)
+	}
+
+	if session.NoteIDs, err = decodeNoteIDs(noteIDs); err != nil {
+		return nil, err
+	}
+
+	messagesQuery := `
+		SELECT role, content, grade 
+		FROM quiz_messages 
+		WHERE sessionId = ? 
+		ORDER BY id ASC`
+
+	session.Messages, err = queryQuizMessages(r.db, messagesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListQuizSessions returns up to limit sessions with their messages, oldest
+// first, starting after the session with id after, or at the first session
+// when after is empty.
+func (r *SQLiteQuizSessionRepository) ListQuizSessions(userID int, after string, limit int) ([]*models.QuizSession, error) {
+	query := `
+		SELECT id, noteIds, status, createdAt, updatedAt 
+		FROM quiz_sessions 
+		WHERE userId = ? AND (? = '' OR (createdAt, id) > (
+			SELECT createdAt, id FROM quiz_sessions WHERE id = ? AND userId = ?)) 
+		ORDER BY createdAt ASC, id ASC 
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, userID, after, after, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quiz sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*models.QuizSession, 0)
+	for rows.Next() {
+		session := &models.QuizSession{}
+		var noteIDs string
+		err := rows.Scan(&session.ID, &noteIDs, &session.Status, &session.CreatedAt, &session.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quiz session: %w", err)
+		}
+		if session.NoteIDs, err = decodeNoteIDs(noteIDs); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over quiz sessions: %w", err)
+	}
+
+	messagesQuery := `
+		SELECT role, content, grade 
+		FROM quiz_messages 
+		WHERE sessionId = ? 
+		ORDER BY id ASC`
+
+	for _, session := range sessions {
+		session.Messages, err = queryQuizMessages(r.db, messagesQuery, session.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *SQLiteQuizSessionRepository) AppendQuizMessages(userID int, id string, messages []models.Message) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec("UPDATE quiz_sessions SET updatedAt = ? WHERE id = ? AND userId = ?", now, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update quiz session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	if err := insertSQLiteQuizMessages(tx, id, messages, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit quiz messages: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteQuizSessionRepository) UpdateQuizSessionStatus(userID int, id string, status string) error {
+	query := "UPDATE quiz_sessions SET status = ?, updatedAt = ? WHERE id = ? AND userId = ?"
+
+	result, err := r.db.Exec(query, status, time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update quiz session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("quiz session with id %s not found", id)
+	}
+
+	return nil
+}
+
+func insertSQLiteQuizMessages(tx *sql.Tx, sessionID string, messages []models.Message, now time.Time) error {
+	query := `
+		INSERT INTO quiz_messages (sessionId, role, content, grade, createdAt) 
+		VALUES (?, ?, ?, ?, ?)`
+
+	for _, message := range messages {
+		grade, err := encodeAnswerGrade(message.Grade)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, sessionID, message.Role, message.Content, grade, now); err != nil {
+			return fmt.Errorf("failed to save quiz message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// decodeNoteIDs reads the note ids of a session. Sessions over all notes
+// used to be stored with null, which is read as no note ids.
+func decodeNoteIDs(data string) ([]int, error) {
+	var noteIDs []int
+	if err := json.Unmarshal([]byte(data), &noteIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode note ids: %w", err)
+	}
+	if noteIDs == nil {
+		noteIDs = []int{}
+	}
+	return noteIDs, nil
+}
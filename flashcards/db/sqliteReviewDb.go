@@ -0,0 +1,120 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteReviewRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteReviewRepository(db *sql.DB) *SQLiteReviewRepository {
+	return &SQLiteReviewRepository{db: db}
+}
+
+func (r *SQLiteReviewRepository) GetReviewState(userID int, itemType string, itemID int) (*models.ReviewState, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM review_states 
+		WHERE itemType = ? AND itemId = ? AND userId = ?`, reviewStateColumns)
+
+	state, err := scanReviewState(r.db.QueryRow(query, itemType, itemID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get review state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (r *SQLiteReviewRepository) GetReviewStates(userID int, itemType string) ([]*models.ReviewState, error) {
+	query := fmt.Sprintf(`
+		SELECT %s 
+		FROM review_states 
+		WHERE itemType = ? AND userId = ? 
+		ORDER BY dueAt ASC, id ASC`, reviewStateColumns)
+
+	rows, err := r.db.Query(query, itemType, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review states: %w", err)
+	}
+	defer rows.Close()
+
+	states := make([]*models.ReviewState, 0)
+	for rows.Next() {
+		state, err := scanReviewState(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review state: %w", err)
+		}
+		states = append(states, state)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over review states: %w", err)
+	}
+
+	return states, nil
+}
+
+func (r *SQLiteReviewRepository) SaveReview(userID int, state *models.ReviewState, entry *models.ReviewLog) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO review_states (userId, itemType, itemId, algorithm, repetitions, lapses, easeFactor,
+			intervalDays, stability, difficulty, lastGrade, dueAt, lastReviewedAt, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) 
+		ON CONFLICT (itemType, itemId) DO UPDATE SET 
+			algorithm = excluded.algorithm, 
+			repetitions = excluded.repetitions, 
+			lapses = excluded.lapses, 
+			easeFactor = excluded.easeFactor, 
+			intervalDays = excluded.intervalDays, 
+			stability = excluded.stability, 
+			difficulty = excluded.difficulty, 
+			lastGrade = excluded.lastGrade, 
+			dueAt = excluded.dueAt, 
+			lastReviewedAt = excluded.lastReviewedAt, 
+			updatedAt = excluded.updatedAt 
+		WHERE review_states.userId = excluded.userId 
+		RETURNING id, createdAt, updatedAt`
+
+	now := time.Now().UTC()
+	row := tx.QueryRow(query, userID, state.ItemType, state.ItemID, state.Algorithm, state.Repetitions, state.Lapses,
+		state.EaseFactor, state.IntervalDays, state.Stability, state.Difficulty, state.LastGrade,
+		state.DueAt, state.LastReviewedAt, now, now)
+	if err := row.Scan(&state.ID, &state.CreatedAt, &state.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save review state: %w", err)
+	}
+
+	entry.StateID = state.ID
+	logQuery := `
+		INSERT INTO review_logs (stateId, grade, intervalDays, reviewedAt) 
+		VALUES (?, ?, ?, ?)`
+
+	result, err := tx.Exec(logQuery, entry.StateID, entry.Grade, entry.IntervalDays, entry.ReviewedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save review log: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to save review log: %w", err)
+	}
+	entry.ID = int(id)
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type SQLiteTagRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteTagRepository(db *sql.DB) *SQLiteTagRepository {
+	return &SQLiteTagRepository{db: db}
+}
+
+func (r *SQLiteTagRepository) SetNoteTags(userID, noteID int, tags []string) error {
+	return r.setTags(userID, noteTagLink, noteID, tags)
+}
+
+func (r *SQLiteTagRepository) SetTodoTags(userID, todoID int, tags []string) error {
+	return r.setTags(userID, todoTagLink, todoID, tags)
+}
+
+func (r *SQLiteTagRepository) GetNoteTags(userID int, noteIDs []int) (map[int][]string, error) {
+	return r.getTags(userID, noteTagLink, noteIDs)
+}
+
+func (r *SQLiteTagRepository) GetTodoTags(userID int, todoIDs []int) (map[int][]string, error) {
+	return r.getTags(userID, todoTagLink, todoIDs)
+}
+
+func (r *SQLiteTagRepository) FindNoteIDs(userID int, tags []string, matchAll bool) ([]int, error) {
+	return r.findIDs(userID, noteTagLink, tags, matchAll)
+}
+
+func (r *SQLiteTagRepository) FindTodoIDs(userID int, tags []string, matchAll bool) ([]int, error) {
+	return r.findIDs(userID, todoTagLink, tags, matchAll)
+}
+
+func (r *SQLiteTagRepository) setTags(userID int, link tagLink, itemID int, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceSQLiteTags(tx, userID, link, itemID, tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tags: %w", err)
+	}
+
+	return nil
+}
+
+// replaceSQLiteTags is setTags within tx.
+func replaceSQLiteTags(tx sqlExecer, userID int, link tagLink, itemID int, tags []string) error {
+	deleteQuery := fmt.Sprintf(`
+		DELETE FROM %s 
+		WHERE %s = ? AND tagId IN (SELECT id FROM tags WHERE userId = ?)`, link.table, link.column)
+	if _, err := tx.Exec(deleteQuery, itemID, userID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	if err := addSQLiteTags(tx, userID, link, itemID, tags); err != nil {
+		return err
+	}
+
+	pruneQuery := `
+		DELETE FROM tags
+		WHERE userId = ?
+		AND NOT EXISTS (SELECT 1 FROM note_tags WHERE tagId = tags.id)
+		AND NOT EXISTS (SELECT 1 FROM todo_tags WHERE tagId = tags.id)`
+	if _, err := tx.Exec(pruneQuery, userID); err != nil {
+		return fmt.Errorf("failed to remove unused tags: %w", err)
+	}
+
+	return nil
+}
+
+// addSQLiteTags links an item to tags within tx, creating the tags that do
+// not exist yet.
+func addSQLiteTags(tx sqlExecer, userID int, link tagLink, itemID int, tags []string) error {
+	now := time.Now().UTC()
+	linkQuery := fmt.Sprintf(`
+		INSERT OR IGNORE INTO %s (%s, tagId)
+		SELECT ?, id FROM tags WHERE userId = ? AND name = ?`, link.table, link.column)
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (userId, name, createdAt) VALUES (?, ?, ?)", userID, tag, now); err != nil {
+			return fmt.Errorf("failed to create tag %q: %w", tag, err)
+		}
+		if _, err := tx.Exec(linkQuery, itemID, userID, tag); err != nil {
+			return fmt.Errorf("failed to set tag %q: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLiteTagRepository) getTags(userID int, link tagLink, itemIDs []int) (map[int][]string, error) {
+	if len(itemIDs) == 0 {
+		return map[int][]string{}, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT l.%[2]s, t.name
+		FROM %[1]s l
+		JOIN tags t ON t.id = l.tagId
+		WHERE l.%[2]s IN (%[3]s) AND t.userId = ?
+		ORDER BY l.%[2]s, t.name`, link.table, link.column, sqlitePlaceholders(len(itemIDs)))
+
+	args := make([]any, 0, len(itemIDs)+1)
+	for _, id := range itemIDs {
+		args = append(args, id)
+	}
+	args = append(args, userID)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	return scanItemTags(rows)
+}
+
+func (r *SQLiteTagRepository) findIDs(userID int, link tagLink, tags []string, matchAll bool) ([]int, error) {
+	if len(tags) == 0 {
+		return []int{}, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT l.%[2]s
+		FROM %[1]s l
+		JOIN tags t ON t.id = l.tagId
+		JOIN %[4]s i ON i.id = l.%[2]s
+		WHERE t.name IN (%[3]s) AND t.userId = ? AND i.deletedAt IS NULL
+		GROUP BY l.%[2]s`, link.table, link.column, sqlitePlaceholders(len(tags)), link.items)
+
+	args := make([]any, 0, len(tags)+2)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	args = append(args, userID)
+	if matchAll {
+		query += " HAVING COUNT(*) = ?"
+		args = append(args, len(tags))
+	}
+	query += fmt.Sprintf(" ORDER BY l.%s", link.column)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tagged items: %w", err)
+	}
+	defer rows.Close()
+
+	return scanItemIDs(rows)
+}
+
+func (r *SQLiteTagRepository) GetAllTags(userID int) ([]*models.Tag, error) {
+	query := `
+		SELECT name, noteCount, todoCount FROM (
+			SELECT t.name,
+				(SELECT COUNT(*) FROM note_tags l JOIN notes n ON n.id = l.noteId
+					WHERE l.tagId = t.id AND n.deletedAt IS NULL) AS noteCount,
+				(SELECT COUNT(*) FROM todo_tags l JOIN todos d ON d.id = l.todoId
+					WHERE l.tagId = t.id AND d.deletedAt IS NULL) AS todoCount
+			FROM tags t
+			WHERE t.userId = ?
+		)
+		WHERE noteCount + todoCount > 0
+		ORDER BY name`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTags(rows)
+}
+
+func (r *SQLiteTagRepository) RenameTag(userID int, name, newName string) error {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM tags WHERE userId = ? AND name = ?)", userID, newName).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	if exists {
+		return apperr.Conflict("tag %q already exists", newName)
+	}
+
+	result, err := r.db.Exec("UPDATE tags SET name = ? WHERE userId = ? AND name = ?", newName, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("tag %q not found", name)
+	}
+
+	return nil
+}
+
+func (r *SQLiteTagRepository) MergeTags(userID int, sources []string, target string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sourceIDs := make([]any, len(sources))
+	for i, source := range sources {
+		var id int
+		err := tx.QueryRow("SELECT id FROM tags WHERE userId = ? AND name = ?", userID, source).Scan(&id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return apperr.NotFound("tag %q not found", source)
+			}
+			return fmt.Errorf("failed to merge tags: %w", err)
+		}
+		sourceIDs[i] = id
+	}
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO tags (userId, name, createdAt) VALUES (?, ?, ?)", userID, target, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to create tag %q: %w", target, err)
+	}
+	var targetID int
+	if err := tx.QueryRow("SELECT id FROM tags WHERE userId = ? AND name = ?", userID, target).Scan(&targetID); err != nil {
+		return fmt.Errorf("failed to create tag %q: %w", target, err)
+	}
+
+	placeholders := sqlitePlaceholders(len(sourceIDs))
+	for _, link := range tagLinks {
+		query := fmt.Sprintf(`
+			INSERT OR IGNORE INTO %[1]s (%[2]s, tagId)
+			SELECT DISTINCT %[2]s, ? FROM %[1]s WHERE tagId IN (%[3]s)`, link.table, link.column, placeholders)
+		args := append([]any{targetID}, sourceIDs...)
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to merge tags: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM tags WHERE id IN ("+placeholders+")", sourceIDs...); err != nil {
+		return fmt.Errorf("failed to delete merged tags: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag merge: %w", err)
+	}
+
+	return nil
+}
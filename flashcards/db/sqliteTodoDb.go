@@ -0,0 +1,279 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+// sqliteTodoVersionQuery selects the version of a todo for staleVersion.
+const sqliteTodoVersionQuery = "SELECT version FROM todos WHERE id = ? AND userId = ? AND deletedAt IS NULL"
+
+type SQLiteTodoRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteTodoRepository(db *sql.DB) *SQLiteTodoRepository {
+	return &SQLiteTodoRepository{db: db}
+}
+
+// CreateTodo saves the todo with its tags.
+func (r *SQLiteTodoRepository) CreateTodo(userID int, todo *models.Todo) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSQLiteTodo(tx, userID, todo); err != nil {
+		return err
+	}
+	if err := addSQLiteTags(tx, userID, todoTagLink, todo.ID, todo.Tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit todo: %w", err)
+	}
+
+	return nil
+}
+
+func insertSQLiteTodo(execer sqlExecer, userID int, todo *models.Todo) error {
+	query := `
+		INSERT INTO todos (userId, title, description, completed, dueAt, priority, completedAt, recurrence, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := execer.Exec(query, userID, todo.Title, todo.Description, todo.Completed, todo.DueAt, todo.Priority, todo.CompletedAt, todo.Recurrence, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create todo: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to create todo: %w", err)
+	}
+
+	todo.ID = int(id)
+	todo.CreatedAt = now
+	todo.UpdatedAt = now
+	todo.Version = 1
+
+	return nil
+}
+
+func (r *SQLiteTodoRepository) GetTodoByID(userID, id int) (*models.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + ` 
+		FROM todos 
+		WHERE id = ? AND userId = ? AND deletedAt IS NULL`
+
+	todo, err := scanTodo(r.db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("todo with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+func (r *SQLiteTodoRepository) ListTodos(userID int, opts models.TodoListOptions) (*models.Page[*models.Todo], error) {
+	q := newListQuery(false)
+	q.ownedBy(userID)
+	q.excludeDeleted()
+	filterTodos(q, opts)
+	countQuery := "SELECT COUNT(*) FROM todos" + q.whereClause()
+	countArgs := append([]any{}, q.args...)
+
+	q.after(opts.ListOptions)
+	query := "SELECT " + todoColumns + " FROM todos" + q.whereClause() + q.orderAndLimit(opts.ListOptions)
+
+	rows, err := r.db.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer rows.Close()
+
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(todos, opts.ListOptions, todoListKey, func() (int, error) {
+		var total int
+		err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+		return total, err
+	})
+}
+
+// UpdateTodo applies the updates and bumps the version of the todo. Unless
+// version is 0, the todo is only updated while it is at version. A tags
+// update replaces the tags of the todo in the same transaction.
+func (r *SQLiteTodoRepository) UpdateTodo(userID, id int, updates map[string]any, version int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateSQLiteTodo(tx, userID, id, updates, version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit todo: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteTodo applies updates like UpdateTodo and creates next, the next
+// todo of a recurring one, with its tags in the same transaction.
+func (r *SQLiteTodoRepository) CompleteTodo(userID, id int, updates map[string]any, version int, next *models.Todo) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateSQLiteTodo(tx, userID, id, updates, version); err != nil {
+		return err
+	}
+	if err := insertSQLiteTodo(tx, userID, next); err != nil {
+		return err
+	}
+	if err := addSQLiteTags(tx, userID, todoTagLink, next.ID, next.Tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit todo: %w", err)
+	}
+
+	return nil
+}
+
+func updateSQLiteTodo(tx sqlExecQueryer, userID, id int, updates map[string]any, version int) error {
+	tags, setTags, err := updatedTags(updates)
+	if err != nil {
+		return fmt.Errorf("failed to update todo: %w", err)
+	}
+
+	query := "UPDATE todos SET "
+	args := []any{}
+
+	for field, value := range updates {
+		if field == tagsUpdate {
+			continue
+		}
+		query += fmt.Sprintf("%s = ?, ", field)
+		args = append(args, value)
+	}
+
+	query += "updatedAt = ?, version = version + 1 WHERE id = ? AND userId = ? AND deletedAt IS NULL"
+	args = append(args, time.Now().UTC(), id, userID)
+	if version > 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return staleVersion(tx, "todo", id, sqliteTodoVersionQuery, id, userID)
+	}
+
+	if setTags {
+		return replaceSQLiteTags(tx, userID, todoTagLink, id, tags)
+	}
+
+	return nil
+}
+
+// DeleteTodo moves the todo to the trash. Unless version is 0, only while
+// the todo is at version.
+func (r *SQLiteTodoRepository) DeleteTodo(userID, id, version int) error {
+	query := "UPDATE todos SET deletedAt = ? WHERE id = ? AND userId = ? AND deletedAt IS NULL AND (? = 0 OR version = ?)"
+
+	result, err := r.db.Exec(query, time.Now().UTC(), id, userID, version, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return staleVersion(r.db, "todo", id, sqliteTodoVersionQuery, id, userID)
+	}
+
+	return nil
+}
+
+func (r *SQLiteTodoRepository) RestoreTodo(userID, id int) error {
+	query := "UPDATE todos SET deletedAt = NULL WHERE id = ? AND userId = ? AND deletedAt IS NOT NULL"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("todo with id %d not found in trash", id)
+	}
+
+	return nil
+}
+
+// ListDeletedTodos returns the todos in the trash, most recently deleted
+// first.
+func (r *SQLiteTodoRepository) ListDeletedTodos(userID int) ([]*models.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `, deletedAt 
+		FROM todos 
+		WHERE userId = ? AND deletedAt IS NOT NULL 
+		ORDER BY deletedAt DESC, id DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted todos: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeletedTodos(rows)
+}
+
+// PurgeTodos removes the todos of every user that have been in the trash
+// for longer than olderThan.
+func (r *SQLiteTodoRepository) PurgeTodos(olderThan time.Duration) ([]PurgedItem, error) {
+	query := "DELETE FROM todos WHERE deletedAt < ? RETURNING userId, id"
+
+	rows, err := r.db.Query(query, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge todos: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPurgedItems(rows)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type SQLiteLLMUsageRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteLLMUsageRepository(db *sql.DB) *SQLiteLLMUsageRepository {
+	return &SQLiteLLMUsageRepository{db: db}
+}
+
+func (r *SQLiteLLMUsageRepository) AddLLMUsage(userID int, at time.Time, promptTokens, completionTokens int) error {
+	query := `
+		INSERT INTO llm_usage (userId, day, promptTokens, completionTokens) 
+		VALUES (?, ?, ?, ?) 
+		ON CONFLICT (userId, day) DO UPDATE SET 
+			promptTokens = promptTokens + excluded.promptTokens, 
+			completionTokens = completionTokens + excluded.completionTokens`
+
+	day := at.UTC().Format(usageDayLayout)
+	if _, err := r.db.Exec(query, userID, day, promptTokens, completionTokens); err != nil {
+		return fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteLLMUsageRepository) GetLLMUsage(userID int, since time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(promptTokens + completionTokens), 0) 
+		FROM llm_usage 
+		WHERE userId = ? AND day >= ?`
+
+	var used int
+	if err := r.db.QueryRow(query, userID, since.UTC().Format(usageDayLayout)).Scan(&used); err != nil {
+		return 0, fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+
+	return used, nil
+}
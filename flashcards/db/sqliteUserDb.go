@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+type SQLiteUserRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteUserRepository(db *sql.DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{db: db}
+}
+
+func (r *SQLiteUserRepository) CreateUser(user *models.User) error {
+	query := "INSERT INTO users (email, passwordHash, createdAt) VALUES (?, ?, ?)"
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(query, user.Email, user.PasswordHash, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return apperr.Conflict("user with email %q already exists", user.Email)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	user.ID = int(id)
+	user.CreatedAt = now
+
+	return nil
+}
+
+func (r *SQLiteUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = ?"
+
+	user, err := scanUser(r.db.QueryRow(query, email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user with email %q not found", email)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *SQLiteUserRepository) GetUserByID(id int) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+
+	user, err := scanUser(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
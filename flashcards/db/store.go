@@ -0,0 +1,167 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flashcards/apperr"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Store groups the repositories of a single storage backend so that callers
+// can pick a driver once and share one connection between repositories.
+type Store struct {
+	Notes      NoteRepository
+	Todos      TodoRepository
+	Flashcards FlashcardRepository
+	Reviews    ReviewRepository
+	Quizzes    QuizSessionRepository
+	Decks      DeckRepository
+	Tags       TagRepository
+	Users      UserRepository
+	APIKeys    APIKeyRepository
+	LLMUsage   LLMUsageRepository
+
+	driver string
+	conn   *sql.DB
+}
+
+func NewStore(driver, databaseURL string) (*Store, error) {
+	switch driver {
+	case DriverPostgres:
+		conn, err := openPostgres(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return &Store{
+			Notes:      &PostgresNoteRepository{db: conn},
+			Todos:      &PostgresTodoRepository{db: conn},
+			Flashcards: &PostgresFlashcardRepository{db: conn},
+			Reviews:    &PostgresReviewRepository{db: conn},
+			Quizzes:    &PostgresQuizSessionRepository{db: conn},
+			Decks:      &PostgresDeckRepository{db: conn},
+			Tags:       NewPostgresTagRepository(conn),
+			Users:      NewPostgresUserRepository(conn),
+			APIKeys:    NewPostgresAPIKeyRepository(conn),
+			LLMUsage:   NewPostgresLLMUsageRepository(conn),
+			driver:     driver,
+			conn:       conn,
+		}, nil
+	case DriverSQLite:
+		conn, err := OpenSQLite(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return &Store{
+			Notes:      NewSQLiteNoteRepository(conn),
+			Todos:      NewSQLiteTodoRepository(conn),
+			Flashcards: NewSQLiteFlashcardRepository(conn),
+			Reviews:    NewSQLiteReviewRepository(conn),
+			Quizzes:    NewSQLiteQuizSessionRepository(conn),
+			Decks:      NewSQLiteDeckRepository(conn),
+			Tags:       NewSQLiteTagRepository(conn),
+			Users:      NewSQLiteUserRepository(conn),
+			APIKeys:    NewSQLiteAPIKeyRepository(conn),
+			LLMUsage:   NewSQLiteLLMUsageRepository(conn),
+			driver:     driver,
+			conn:       conn,
+		}, nil
+	case DriverMemory:
+		notes := NewMemoryNoteRepository()
+		todos := NewMemoryTodoRepository()
+		tags := NewMemoryTagRepository(notes, todos)
+		notes.tags = tags
+		todos.tags = tags
+		return &Store{
+			Notes:      notes,
+			Todos:      todos,
+			Flashcards: NewMemoryFlashcardRepository(),
+			Reviews:    NewMemoryReviewRepository(),
+			Quizzes:    NewMemoryQuizSessionRepository(),
+			Decks:      NewMemoryDeckRepository(),
+			Tags:       tags,
+			Users:      NewMemoryUserRepository(),
+			APIKeys:    NewMemoryAPIKeyRepository(),
+			LLMUsage:   NewMemoryLLMUsageRepository(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %q", driver)
+	}
+}
+
+// Migrator returns the migrator of the store's database. The memory driver
+// has no schema, so it has none.
+func (s *Store) Migrator() (*Migrator, error) {
+	if s.conn == nil {
+		return nil, errors.New("the memory driver has no schema to migrate")
+	}
+	return NewMigrator(s.driver, s.conn)
+}
+
+func (s *Store) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// sqlQueryer is satisfied by both *sql.DB and *sql.Tx.
+type sqlQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// sqlExecQueryer is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecQueryer interface {
+	sqlExecer
+	sqlQueryer
+}
+
+// staleVersion explains why a write of the item with id, conditional on its
+// version, changed no row: either the item is gone or it is at another
+// version. versionQuery selects the current version of the item.
+func staleVersion(queryer sqlQueryer, item string, id int, versionQuery string, args ...any) error {
+	var current int
+	err := queryer.QueryRow(versionQuery, args...).Scan(&current)
+	if err == sql.ErrNoRows {
+		return apperr.NotFound("%s with id %d not found", item, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s version: %w", item, err)
+	}
+
+	return apperr.PreconditionFailed("%s with id %d has been modified, it is at version %d", item, id, current)
+}
+
+func openPostgres(databaseURL string) (*sql.DB, error) {
+	if databaseURL == "" {
+		return nil, errors.New("database URL is required for the postgres driver")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
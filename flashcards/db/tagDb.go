@@ -0,0 +1,367 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+// TagRepository stores the tags of notes and todos. Every user has their own
+// set of tags. Tag names are expected to be normalized by the caller. Tags
+// that are no longer used by any note or todo are removed when the tags of an
+// item are replaced. Items in the trash keep their tags for a restore, but
+// are neither found nor counted.
+type TagRepository interface {
+	SetNoteTags(userID, noteID int, tags []string) error
+	SetTodoTags(userID, todoID int, tags []string) error
+	GetNoteTags(userID int, noteIDs []int) (map[int][]string, error)
+	GetTodoTags(userID int, todoIDs []int) (map[int][]string, error)
+	FindNoteIDs(userID int, tags []string, matchAll bool) ([]int, error)
+	FindTodoIDs(userID int, tags []string, matchAll bool) ([]int, error)
+	GetAllTags(userID int) ([]*models.Tag, error)
+	RenameTag(userID int, name, newName string) error
+	MergeTags(userID int, sources []string, target string) error
+}
+
+// tagLink names the join table between tags and one kind of item, and the
+// table of the items.
+type tagLink struct {
+	table  string
+	column string
+	items  string
+}
+
+var (
+	noteTagLink = tagLink{table: "note_tags", column: "noteId", items: "notes"}
+	todoTagLink = tagLink{table: "todo_tags", column: "todoId", items: "todos"}
+	tagLinks    = []tagLink{noteTagLink, todoTagLink}
+)
+
+// tagsUpdate is the update of UpdateNote and UpdateTodo that replaces the
+// tags of the note or todo. Its value is a []string. It is not a column, so
+// the update loops skip it.
+const tagsUpdate = "tags"
+
+// updatedTags returns the tags that replace those of the item, if updates
+// has a tagsUpdate.
+func updatedTags(updates map[string]any) ([]string, bool, error) {
+	value, ok := updates[tagsUpdate]
+	if !ok {
+		return nil, false, nil
+	}
+	tags, ok := value.([]string)
+	if !ok {
+		return nil, false, fmt.Errorf("invalid value for %s", tagsUpdate)
+	}
+	return tags, true, nil
+}
+
+func scanItemTags(rows *sql.Rows) (map[int][]string, error) {
+	tags := make(map[int][]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags[id] = append(tags[id], name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func scanItemIDs(rows *sql.Rows) ([]int, error) {
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan item id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tagged items: %w", err)
+	}
+
+	return ids, nil
+}
+
+func scanTags(rows *sql.Rows) ([]*models.Tag, error) {
+	tags := make([]*models.Tag, 0)
+	for rows.Next() {
+		tag := &models.Tag{}
+		if err := rows.Scan(&tag.Name, &tag.NoteCount, &tag.TodoCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+type PostgresTagRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTagRepository(db *sql.DB) *PostgresTagRepository {
+	return &PostgresTagRepository{db: db}
+}
+
+func (r *PostgresTagRepository) SetNoteTags(userID, noteID int, tags []string) error {
+	return r.setTags(userID, noteTagLink, noteID, tags)
+}
+
+func (r *PostgresTagRepository) SetTodoTags(userID, todoID int, tags []string) error {
+	return r.setTags(userID, todoTagLink, todoID, tags)
+}
+
+func (r *PostgresTagRepository) GetNoteTags(userID int, noteIDs []int) (map[int][]string, error) {
+	return r.getTags(userID, noteTagLink, noteIDs)
+}
+
+func (r *PostgresTagRepository) GetTodoTags(userID int, todoIDs []int) (map[int][]string, error) {
+	return r.getTags(userID, todoTagLink, todoIDs)
+}
+
+func (r *PostgresTagRepository) FindNoteIDs(userID int, tags []string, matchAll bool) ([]int, error) {
+	return r.findIDs(userID, noteTagLink, tags, matchAll)
+}
+
+func (r *PostgresTagRepository) FindTodoIDs(userID int, tags []string, matchAll bool) ([]int, error) {
+	return r.findIDs(userID, todoTagLink, tags, matchAll)
+}
+
+// setTags replaces the tags of one item, creating missing tags and removing
+// tags nobody uses anymore.
+func (r *PostgresTagRepository) setTags(userID int, link tagLink, itemID int, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replacePostgresTags(tx, userID, link, itemID, tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tags: %w", err)
+	}
+
+	return nil
+}
+
+// replacePostgresTags is setTags within tx.
+func replacePostgresTags(tx sqlExecer, userID int, link tagLink, itemID int, tags []string) error {
+	deleteQuery := fmt.Sprintf(`
+		DELETE FROM gocourse.%s 
+		WHERE %s = $1 AND tagId IN (SELECT id FROM gocourse.tags WHERE userId = $2)`, link.table, link.column)
+	if _, err := tx.Exec(deleteQuery, itemID, userID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	if err := addPostgresTags(tx, userID, link, itemID, tags); err != nil {
+		return err
+	}
+
+	pruneQuery := `
+		DELETE FROM gocourse.tags t
+		WHERE t.userId = $1
+		AND NOT EXISTS (SELECT 1 FROM gocourse.note_tags WHERE tagId = t.id)
+		AND NOT EXISTS (SELECT 1 FROM gocourse.todo_tags WHERE tagId = t.id)`
+	if _, err := tx.Exec(pruneQuery, userID); err != nil {
+		return fmt.Errorf("failed to remove unused tags: %w", err)
+	}
+
+	return nil
+}
+
+// addPostgresTags links an item without tags to tags within tx, creating
+// the tags that do not exist yet.
+func addPostgresTags(tx sqlExecer, userID int, link tagLink, itemID int, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	insertTags := `
+		INSERT INTO gocourse.tags (userId, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (userId, name) DO NOTHING`
+	if _, err := tx.Exec(insertTags, userID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	linkQuery := fmt.Sprintf(`
+		INSERT INTO gocourse.%s (%s, tagId)
+		SELECT $1, id FROM gocourse.tags WHERE userId = $2 AND name = ANY($3)`, link.table, link.column)
+	if _, err := tx.Exec(linkQuery, itemID, userID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to set tags: %w", err)
+	}
+
+	return nil
+}
+
+// getTags returns the sorted tag names of each item in itemIDs that has tags.
+func (r *PostgresTagRepository) getTags(userID int, link tagLink, itemIDs []int) (map[int][]string, error) {
+	if len(itemIDs) == 0 {
+		return map[int][]string{}, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT l.%[2]s, t.name
+		FROM gocourse.%[1]s l
+		JOIN gocourse.tags t ON t.id = l.tagId
+		WHERE l.%[2]s = ANY($1) AND t.userId = $2
+		ORDER BY l.%[2]s, t.name`, link.table, link.column)
+
+	rows, err := r.db.Query(query, pq.Array(itemIDs), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	return scanItemTags(rows)
+}
+
+// findIDs returns the ids of the items tagged with all of tags, or with any
+// of them when matchAll is false.
+func (r *PostgresTagRepository) findIDs(userID int, link tagLink, tags []string, matchAll bool) ([]int, error) {
+	query := fmt.Sprintf(`
+		SELECT l.%[2]s
+		FROM gocourse.%[1]s l
+		JOIN gocourse.tags t ON t.id = l.tagId
+		JOIN gocourse.%[3]s i ON i.id = l.%[2]s
+		WHERE t.name = ANY($1) AND t.userId = $2 AND i.deletedAt IS NULL
+		GROUP BY l.%[2]s`, link.table, link.column, link.items)
+	args := []any{pq.Array(tags), userID}
+	if matchAll {
+		query += " HAVING COUNT(*) = $3"
+		args = append(args, len(tags))
+	}
+	query += fmt.Sprintf(" ORDER BY l.%s", link.column)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tagged items: %w", err)
+	}
+	defer rows.Close()
+
+	return scanItemIDs(rows)
+}
+
+func (r *PostgresTagRepository) GetAllTags(userID int) ([]*models.Tag, error) {
+	query := `
+		SELECT name, noteCount, todoCount FROM (
+			SELECT t.name,
+				(SELECT COUNT(*) FROM gocourse.note_tags l JOIN gocourse.notes n ON n.id = l.noteId
+					WHERE l.tagId = t.id AND n.deletedAt IS NULL) AS noteCount,
+				(SELECT COUNT(*) FROM gocourse.todo_tags l JOIN gocourse.todos d ON d.id = l.todoId
+					WHERE l.tagId = t.id AND d.deletedAt IS NULL) AS todoCount
+			FROM gocourse.tags t
+			WHERE t.userId = $1
+		) counts
+		WHERE noteCount + todoCount > 0
+		ORDER BY name`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTags(rows)
+}
+
+func (r *PostgresTagRepository) RenameTag(userID int, name, newName string) error {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM gocourse.tags WHERE userId = $1 AND name = $2)", userID, newName).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	if exists {
+		return apperr.Conflict("tag %q already exists", newName)
+	}
+
+	result, err := r.db.Exec("UPDATE gocourse.tags SET name = $1 WHERE userId = $2 AND name = $3", newName, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("tag %q not found", name)
+	}
+
+	return nil
+}
+
+// MergeTags retags everything tagged with one of sources with target, then
+// deletes the source tags. target is created if it does not exist yet.
+func (r *PostgresTagRepository) MergeTags(userID int, sources []string, target string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sourceIDs := make([]int, len(sources))
+	for i, source := range sources {
+		err := tx.QueryRow("SELECT id FROM gocourse.tags WHERE userId = $1 AND name = $2", userID, source).Scan(&sourceIDs[i])
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return apperr.NotFound("tag %q not found", source)
+			}
+			return fmt.Errorf("failed to merge tags: %w", err)
+		}
+	}
+
+	var targetID int
+	upsertTarget := `
+		INSERT INTO gocourse.tags (userId, name) VALUES ($1, $2)
+		ON CONFLICT (userId, name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`
+	if err := tx.QueryRow(upsertTarget, userID, target).Scan(&targetID); err != nil {
+		return fmt.Errorf("failed to create tag %q: %w", target, err)
+	}
+
+	for _, link := range tagLinks {
+		query := fmt.Sprintf(`
+			INSERT INTO gocourse.%[1]s (%[2]s, tagId)
+			SELECT DISTINCT %[2]s, $1 FROM gocourse.%[1]s WHERE tagId = ANY($2)
+			ON CONFLICT DO NOTHING`, link.table, link.column)
+		if _, err := tx.Exec(query, targetID, pq.Array(sourceIDs)); err != nil {
+			return fmt.Errorf("failed to merge tags: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM gocourse.tags WHERE id = ANY($1)", pq.Array(sourceIDs)); err != nil {
+		return fmt.Errorf("failed to delete merged tags: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag merge: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresTagRepository) Close() error {
+	return r.db.Close()
+}
@@ -3,18 +3,40 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
+	"flashcards/apperr"
 	"flashcards/models"
 
 	_ "github.com/lib/pq"
 )
 
 type TodoRepository interface {
-	CreateTodo(todo *models.Todo) error
-	GetTodoByID(id int) (*models.Todo, error)
-	GetAllTodos() ([]*models.Todo, error)
-	UpdateTodo(id int, updates map[string]any) error
-	DeleteTodo(id int) error
+	CreateTodo(userID int, todo *models.Todo) error
+	GetTodoByID(userID, id int) (*models.Todo, error)
+	ListTodos(userID int, opts models.TodoListOptions) (*models.Page[*models.Todo], error)
+	UpdateTodo(userID, id int, updates map[string]any, version int) error
+	// CompleteTodo applies updates like UpdateTodo and creates next, the
+	// next todo of a recurring one, with its tags in the same transaction.
+	CompleteTodo(userID, id int, updates map[string]any, version int, next *models.Todo) error
+	DeleteTodo(userID, id, version int) error
+	RestoreTodo(userID, id int) error
+	ListDeletedTodos(userID int) ([]*models.Todo, error)
+	PurgeTodos(olderThan time.Duration) ([]PurgedItem, error)
+}
+
+// postgresTodoVersionQuery selects the version of a todo for staleVersion.
+const postgresTodoVersionQuery = "SELECT version FROM gocourse.todos WHERE id = $1 AND userId = $2 AND deletedAt IS NULL"
+
+const todoColumns = "id, title, description, completed, dueAt, priority, completedAt, recurrence, createdAt, updatedAt, version"
+
+func scanTodo(row rowScanner, extra ...any) (*models.Todo, error) {
+	todo := &models.Todo{}
+	dest := []any{&todo.ID, &todo.Title, &todo.Description, &todo.Completed, &todo.DueAt, &todo.Priority, &todo.CompletedAt, &todo.Recurrence, &todo.CreatedAt, &todo.UpdatedAt, &todo.Version}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return todo, nil
 }
 
 type PostgresTodoRepository struct {
@@ -22,27 +44,45 @@ type PostgresTodoRepository struct {
 }
 
 func NewPostgresTodoRepository(databaseURL string) (*PostgresTodoRepository, error) {
-	db, err := sql.Open("postgres", databaseURL)
+	db, err := openPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresTodoRepository{db: db}, nil
+}
+
+// CreateTodo saves the todo with its tags.
+func (r *PostgresTodoRepository) CreateTodo(userID int, todo *models.Todo) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	if err := insertPostgresTodo(tx, userID, todo); err != nil {
+		return err
+	}
+	if err := addPostgresTags(tx, userID, todoTagLink, todo.ID, todo.Tags); err != nil {
+		return err
 	}
 
-	return &PostgresTodoRepository{db: db}, nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit todo: %w", err)
+	}
+
+	return nil
 }
 
-func (r *PostgresTodoRepository) CreateTodo(todo *models.Todo) error {
+func insertPostgresTodo(queryer sqlQueryer, userID int, todo *models.Todo) error {
 	query := `
-		INSERT INTO gocourse.todos (title, description, completed) 
-		VALUES ($1, $2, $3) 
-		RETURNING id, createdAt, updatedAt`
+		INSERT INTO gocourse.todos (userId, title, description, completed, dueAt, priority, completedAt, recurrence) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		RETURNING id, createdAt, updatedAt, version`
 
-	row := r.db.QueryRow(query, todo.Title, todo.Description, todo.Completed)
+	row := queryer.QueryRow(query, userID, todo.Title, todo.Description, todo.Completed, todo.DueAt, todo.Priority, todo.CompletedAt, todo.Recurrence)
 
-	err := row.Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
+	err := row.Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt, &todo.Version)
 	if err != nil {
 		return fmt.Errorf("failed to create todo: %w", err)
 	}
@@ -50,19 +90,16 @@ func (r *PostgresTodoRepository) CreateTodo(todo *models.Todo) error {
 	return nil
 }
 
-func (r *PostgresTodoRepository) GetTodoByID(id int) (*models.Todo, error) {
+func (r *PostgresTodoRepository) GetTodoByID(userID, id int) (*models.Todo, error) {
 	query := `
-		SELECT id, title, description, completed, createdAt, updatedAt 
+		SELECT ` + todoColumns + ` 
 		FROM gocourse.todos 
-		WHERE id = $1`
-
-	todo := &models.Todo{}
-	row := r.db.QueryRow(query, id)
+		WHERE id = $1 AND userId = $2 AND deletedAt IS NULL`
 
-	err := row.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+	todo, err := scanTodo(r.db.QueryRow(query, id, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("todo with id %d not found", id)
+			return nil, apperr.NotFound("todo with id %d not found", id)
 		}
 		return nil, fmt.Errorf("failed to get todo: %w", err)
 	}
@@ -70,38 +107,88 @@ func (r *PostgresTodoRepository) GetTodoByID(id int) (*models.Todo, error) {
 	return todo, nil
 }
 
-func (r *PostgresTodoRepository) GetAllTodos() ([]*models.Todo, error) {
-	query := `
-		SELECT id, title, description, completed, createdAt, updatedAt 
-		FROM gocourse.todos 
-		ORDER BY createdAt DESC`
+// ListTodos returns a page of todos. The total is only counted when there is
+// more than one page.
+func (r *PostgresTodoRepository) ListTodos(userID int, opts models.TodoListOptions) (*models.Page[*models.Todo], error) {
+	q := newListQuery(true)
+	q.ownedBy(userID)
+	q.excludeDeleted()
+	filterTodos(q, opts)
+	countQuery := "SELECT COUNT(*) FROM gocourse.todos" + q.whereClause()
+	countArgs := append([]any{}, q.args...)
 
-	rows, err := r.db.Query(query)
+	q.after(opts.ListOptions)
+	query := "SELECT " + todoColumns + " FROM gocourse.todos" + q.whereClause() + q.orderAndLimit(opts.ListOptions)
+
+	rows, err := r.db.Query(query, q.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query todos: %w", err)
 	}
 	defer rows.Close()
 
-	todos := make([]*models.Todo, 0)
-	for rows.Next() {
-		todo := &models.Todo{}
-		err := rows.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan todo: %w", err)
-		}
-		todos = append(todos, todo)
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over todos: %w", err)
+	return newPage(todos, opts.ListOptions, todoListKey, func() (int, error) {
+		var total int
+		err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+		return total, err
+	})
+}
+
+// UpdateTodo applies the updates and bumps the version of the todo. Unless
+// version is 0, the todo is only updated while it is at version. A tags
+// update replaces the tags of the todo in the same transaction.
+func (r *PostgresTodoRepository) UpdateTodo(userID, id int, updates map[string]any, version int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return todos, nil
+	if err := updatePostgresTodo(tx, userID, id, updates, version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit todo: %w", err)
+	}
+
+	return nil
 }
 
-func (r *PostgresTodoRepository) UpdateTodo(id int, updates map[string]any) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// CompleteTodo applies updates like UpdateTodo and creates next, the next
+// todo of a recurring one, with its tags in the same transaction.
+func (r *PostgresTodoRepository) CompleteTodo(userID, id int, updates map[string]any, version int, next *models.Todo) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updatePostgresTodo(tx, userID, id, updates, version); err != nil {
+		return err
+	}
+	if err := insertPostgresTodo(tx, userID, next); err != nil {
+		return err
+	}
+	if err := addPostgresTags(tx, userID, todoTagLink, next.ID, next.Tags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit todo: %w", err)
+	}
+
+	return nil
+}
+
+func updatePostgresTodo(tx sqlExecQueryer, userID, id int, updates map[string]any, version int) error {
+	tags, setTags, err := updatedTags(updates)
+	if err != nil {
+		return fmt.Errorf("failed to update todo: %w", err)
 	}
 
 	query := "UPDATE gocourse.todos SET "
@@ -109,18 +196,22 @@ func (r *PostgresTodoRepository) UpdateTodo(id int, updates map[string]any) erro
 	argIndex := 1
 
 	for field, value := range updates {
-		if argIndex > 1 {
-			query += ", "
+		if field == tagsUpdate {
+			continue
 		}
-		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		query += fmt.Sprintf("%s = $%d, ", field, argIndex)
 		args = append(args, value)
 		argIndex++
 	}
 
-	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d", argIndex)
-	args = append(args, id)
+	query += fmt.Sprintf("updatedAt = NOW(), version = version + 1 WHERE id = $%d AND userId = $%d AND deletedAt IS NULL", argIndex, argIndex+1)
+	args = append(args, id, userID)
+	if version > 0 {
+		query += fmt.Sprintf(" AND version = $%d", argIndex+2)
+		args = append(args, version)
+	}
 
-	result, err := r.db.Exec(query, args...)
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update todo: %w", err)
 	}
@@ -131,16 +222,22 @@ func (r *PostgresTodoRepository) UpdateTodo(id int, updates map[string]any) erro
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("todo with id %d not found", id)
+		return staleVersion(tx, "todo", id, postgresTodoVersionQuery, id, userID)
+	}
+
+	if setTags {
+		return replacePostgresTags(tx, userID, todoTagLink, id, tags)
 	}
 
 	return nil
 }
 
-func (r *PostgresTodoRepository) DeleteTodo(id int) error {
-	query := "DELETE FROM gocourse.todos WHERE id = $1"
+// DeleteTodo moves the todo to the trash. Unless version is 0, only while
+// the todo is at version.
+func (r *PostgresTodoRepository) DeleteTodo(userID, id, version int) error {
+	query := "UPDATE gocourse.todos SET deletedAt = NOW() WHERE id = $1 AND userId = $2 AND deletedAt IS NULL AND ($3 = 0 OR version = $3)"
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.Exec(query, id, userID, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete todo: %w", err)
 	}
@@ -151,12 +248,123 @@ func (r *PostgresTodoRepository) DeleteTodo(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("todo with id %d not found", id)
+		return staleVersion(r.db, "todo", id, postgresTodoVersionQuery, id, userID)
+	}
+
+	return nil
+}
+
+func (r *PostgresTodoRepository) RestoreTodo(userID, id int) error {
+	query := "UPDATE gocourse.todos SET deletedAt = NULL WHERE id = $1 AND userId = $2 AND deletedAt IS NOT NULL"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperr.NotFound("todo with id %d not found in trash", id)
 	}
 
 	return nil
 }
 
+// ListDeletedTodos returns the todos in the trash, most recently deleted
+// first.
+func (r *PostgresTodoRepository) ListDeletedTodos(userID int) ([]*models.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `, deletedAt 
+		FROM gocourse.todos 
+		WHERE userId = $1 AND deletedAt IS NOT NULL 
+		ORDER BY deletedAt DESC, id DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted todos: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeletedTodos(rows)
+}
+
+// PurgeTodos removes the todos of every user that have been in the trash
+// for longer than olderThan.
+func (r *PostgresTodoRepository) PurgeTodos(olderThan time.Duration) ([]PurgedItem, error) {
+	query := `
+		DELETE FROM gocourse.todos 
+		WHERE deletedAt < NOW() - make_interval(secs => $1) 
+		RETURNING userId, id`
+
+	rows, err := r.db.Query(query, olderThan.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge todos: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPurgedItems(rows)
+}
+
+func filterTodos(q *listQuery, opts models.TodoListOptions) {
+	q.filter(opts.ListOptions, "title", "description")
+	if opts.Completed != nil {
+		q.where("completed = " + q.arg(*opts.Completed))
+	}
+	if opts.Priority != "" {
+		q.where("priority = " + q.arg(opts.Priority))
+	}
+	if opts.DueAfter != nil {
+		q.where("dueAt >= " + q.arg(*opts.DueAfter))
+	}
+	if opts.DueBefore != nil {
+		q.where("dueAt < " + q.arg(*opts.DueBefore))
+	}
+}
+
+func scanTodos(rows *sql.Rows) ([]*models.Todo, error) {
+	todos := make([]*models.Todo, 0)
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+func scanDeletedTodos(rows *sql.Rows) ([]*models.Todo, error) {
+	todos := make([]*models.Todo, 0)
+	for rows.Next() {
+		var deletedAt *time.Time
+		todo, err := scanTodo(rows, &deletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deleted todo: %w", err)
+		}
+		todo.DeletedAt = deletedAt
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deleted todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+func todoListKey(todo *models.Todo) listKey {
+	return listKey{id: todo.ID, createdAt: todo.CreatedAt, updatedAt: todo.UpdatedAt}
+}
+
 func (r *PostgresTodoRepository) Close() error {
 	return r.db.Close()
 }
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// PurgedItem identifies a soft deleted row that a purge removed for good.
+type PurgedItem struct {
+	UserID int
+	ID     int
+}
+
+func scanPurgedItems(rows *sql.Rows) ([]PurgedItem, error) {
+	items := make([]PurgedItem, 0)
+	for rows.Next() {
+		var item PurgedItem
+		if err := rows.Scan(&item.UserID, &item.ID); err != nil {
+			return nil, fmt.Errorf("failed to scan purged item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over purged items: %w", err)
+	}
+
+	return items, nil
+}
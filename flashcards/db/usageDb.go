@@ -0,0 +1,56 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// usageDayLayout formats the day usage is recorded under, in UTC.
+const usageDayLayout = "2006-01-02"
+
+// LLMUsageRepository adds up the LLM tokens each user spends per day.
+type LLMUsageRepository interface {
+	AddLLMUsage(userID int, at time.Time, promptTokens, completionTokens int) error
+	// GetLLMUsage returns the prompt and completion tokens spent from the
+	// day of since onwards.
+	GetLLMUsage(userID int, since time.Time) (int, error)
+}
+
+type PostgresLLMUsageRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresLLMUsageRepository(db *sql.DB) *PostgresLLMUsageRepository {
+	return &PostgresLLMUsageRepository{db: db}
+}
+
+func (r *PostgresLLMUsageRepository) AddLLMUsage(userID int, at time.Time, promptTokens, completionTokens int) error {
+	query := `
+		INSERT INTO gocourse.llm_usage (userId, day, promptTokens, completionTokens) 
+		VALUES ($1, $2, $3, $4) 
+		ON CONFLICT (userId, day) DO UPDATE SET 
+			promptTokens = gocourse.llm_usage.promptTokens + EXCLUDED.promptTokens, 
+			completionTokens = gocourse.llm_usage.completionTokens + EXCLUDED.completionTokens`
+
+	day := at.UTC().Format(usageDayLayout)
+	if _, err := r.db.Exec(query, userID, day, promptTokens, completionTokens); err != nil {
+		return fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresLLMUsageRepository) GetLLMUsage(userID int, since time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(promptTokens + completionTokens), 0) 
+		FROM gocourse.llm_usage 
+		WHERE userId = $1 AND day >= $2`
+
+	var used int
+	if err := r.db.QueryRow(query, userID, since.UTC().Format(usageDayLayout)).Scan(&used); err != nil {
+		return 0, fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+
+	return used, nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+// UserRepository stores user accounts. Emails are unique and expected to be
+// normalized by the caller.
+type UserRepository interface {
+	CreateUser(user *models.User) error
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(id int) (*models.User, error)
+}
+
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+const userColumns = "id, email, passwordHash, createdAt"
+
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *PostgresUserRepository) CreateUser(user *models.User) error {
+	query := `
+		INSERT INTO gocourse.users (email, passwordHash) 
+		VALUES ($1, $2) 
+		RETURNING id, createdAt`
+
+	err := r.db.QueryRow(query, user.Email, user.PasswordHash).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return apperr.Conflict("user with email %q already exists", user.Email)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM gocourse.users WHERE email = $1"
+
+	user, err := scanUser(r.db.QueryRow(query, email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user with email %q not found", email)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) GetUserByID(id int) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM gocourse.users WHERE id = $1"
+
+	user, err := scanUser(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) Close() error {
+	return r.db.Close()
+}
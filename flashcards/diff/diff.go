@@ -0,0 +1,199 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Op tells in which of the two texts a chunk appears.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// maxEdits bounds the work spent looking for a minimal diff. Texts that
+// differ by more edits than this are diffed as a whole replacement of
+// their differing middle, which keeps memory at O(maxEdits²).
+const maxEdits = 1000
+
+// Chunk is a run of consecutive tokens with the same Op. Count is the
+// number of lines or words in it, not counting whitespace.
+type Chunk struct {
+	Op    Op
+	Text  string
+	Count int
+}
+
+// Lines diffs a and b line by line.
+func Lines(a, b string) []Chunk {
+	return tokens(splitLines(a), splitLines(b))
+}
+
+// Words diffs a and b word by word. Runs of whitespace are tokens of their
+// own, so the chunks still add up to the exact texts.
+func Words(a, b string) []Chunk {
+	return tokens(splitWords(a), splitWords(b))
+}
+
+// splitLines splits text after each newline.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords splits text into alternating runs of whitespace and of other
+// characters.
+func splitWords(text string) []string {
+	var words []string
+	start := 0
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != isSpaceAt(text, start) {
+			words = append(words, text[start:i])
+			start = i
+		}
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+func isSpaceAt(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return unicode.IsSpace(r)
+}
+
+// tokens diffs two token lists. The common prefix and suffix are cut off
+// before searching for the shortest edit script of the rest.
+func tokens(a, b []string) []Chunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var chunks []Chunk
+	add := func(op Op, token string) {
+		count := 0
+		if strings.TrimSpace(token) != "" {
+			count = 1
+		}
+		if n := len(chunks); n > 0 && chunks[n-1].Op == op {
+			chunks[n-1].Text += token
+			chunks[n-1].Count += count
+			return
+		}
+		chunks = append(chunks, Chunk{Op: op, Text: token, Count: count})
+	}
+
+	for _, token := range a[:prefix] {
+		add(Equal, token)
+	}
+	for _, e := range shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		add(e.op, e.token)
+	}
+	for _, token := range a[len(a)-suffix:] {
+		add(Equal, token)
+	}
+
+	return chunks
+}
+
+type edit struct {
+	op    Op
+	token string
+}
+
+// shortestEdit finds the shortest way to turn a into b with Myers' O(ND)
+// algorithm, falling back to deleting a and inserting b when that takes
+// more than maxEdits edits.
+func shortestEdit(a, b []string) []edit {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEdits)
+
+	// v[k] is the furthest x reached on diagonal k = x - y, offset by limit
+	// so that negative diagonals fit. trace keeps the diagonals -d..d of v
+	// before each step d, for walking back the path.
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[limit-d:limit+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[limit+k-1] < v[limit+k+1]) {
+				x = v[limit+k+1]
+			} else {
+				x = v[limit+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[limit+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	edits := make([]edit, 0, n+m)
+	for _, token := range a {
+		edits = append(edits, edit{Delete, token})
+	}
+	for _, token := range b {
+		edits = append(edits, edit{Insert, token})
+	}
+	return edits
+}
+
+// backtrack walks the path found by shortestEdit from the end of both
+// lists back to their start.
+func backtrack(trace [][]int, a, b []string) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		// trace[d] holds diagonals -d..d, so diagonal k is at k+d.
+		prev := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, edit{Insert, b[y-1]})
+		} else {
+			edits = append(edits, edit{Delete, a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 {
+		edits = append(edits, edit{Equal, a[x-1]})
+		x--
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
@@ -0,0 +1,354 @@
+// Package exporter writes the data of a user as a ZIP archive of portable
+// files: a Markdown file per note, a CSV of todos, an Anki TSV of
+// flashcards and a JSON or NDJSON dump of everything. Entries are written
+// as the items come in, so an archive never has to be held in memory.
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"flashcards/models"
+)
+
+// Formats of the dump.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists the formats of the dump.
+var Formats = []string{FormatJSON, FormatNDJSON}
+
+// ValidFormat reports whether format is one of Formats.
+func ValidFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// Kinds of items in the dump. The JSON dump lists each kind under its
+// plural, the NDJSON dump tags every line with its kind.
+const (
+	KindNote        = "note"
+	KindTodo        = "todo"
+	KindDeck        = "deck"
+	KindFlashcard   = "flashcard"
+	KindQuizSession = "quizSession"
+)
+
+// dumpVersion is bumped when the layout of the dump changes.
+const dumpVersion = 1
+
+var todoColumns = []string{"id", "title", "description", "completed", "completedAt", "dueAt", "priority", "recurrence", "tags", "createdAt", "updatedAt"}
+
+// ankiHeader tells Anki how to import flashcards.tsv: fields are HTML and
+// the third column holds the tags.
+const ankiHeader = "#separator:tab\n#html:true\n#tags column:3\n"
+
+// Archive writes the entries of an export. The entries are written one
+// after another: all notes, then todos, flashcards and the dump, each in
+// their own sections. Close must be called to finish the archive.
+type Archive struct {
+	zip        *zip.Writer
+	format     string
+	exportedAt time.Time
+
+	table *csv.Writer
+
+	dump     io.Writer
+	kind     string
+	count    int
+	sections int
+}
+
+// NewArchive starts an archive on w, with a dump in format.
+func NewArchive(w io.Writer, format string, exportedAt time.Time) (*Archive, error) {
+	if !ValidFormat(format) {
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+	return &Archive{zip: zip.NewWriter(w), format: format, exportedAt: exportedAt}, nil
+}
+
+func (a *Archive) create(name string, modified time.Time) (io.Writer, error) {
+	if err := a.flushTable(); err != nil {
+		return nil, err
+	}
+
+	entry, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	return entry, nil
+}
+
+// flushTable finishes the CSV or TSV entry being written, if any.
+func (a *Archive) flushTable() error {
+	if a.table == nil {
+		return nil
+	}
+	a.table.Flush()
+	err := a.table.Error()
+	a.table = nil
+	if err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+	return nil
+}
+
+// WriteNote adds a note as notes/<id>-<title>.md, with its id, version,
+// tags and timestamps as YAML front matter.
+func (a *Archive) WriteNote(note *models.Note) error {
+	entry, err := a.create(noteFileName(note), note.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	tags, err := json.Marshal(note.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+
+	_, err = fmt.Fprintf(entry, "---\nid: %d\nversion: %d\ntags: %s\ncreatedAt: %s\nupdatedAt: %s\n---\n\n%s\n",
+		note.ID, note.Version, tags, formatTime(note.CreatedAt), formatTime(note.UpdatedAt), note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to write note %d: %w", note.ID, err)
+	}
+	return nil
+}
+
+// noteFileName names a note after its id and the first words of its first
+// line.
+func noteFileName(note *models.Note) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(note.Content), "\n")
+	title = strings.TrimLeft(title, "# ")
+
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if slug.Len() >= 50 {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	if slug.Len() == 0 {
+		return fmt.Sprintf("notes/%d.md", note.ID)
+	}
+	return fmt.Sprintf("notes/%d-%s.md", note.ID, slug.String())
+}
+
+// StartTodos starts todos.csv, with a header row.
+func (a *Archive) StartTodos() error {
+	entry, err := a.create("todos.csv", a.exportedAt)
+	if err != nil {
+		return err
+	}
+
+	a.table = csv.NewWriter(entry)
+	if err := a.table.Write(todoColumns); err != nil {
+		return fmt.Errorf("failed to write todos: %w", err)
+	}
+	return nil
+}
+
+// WriteTodo adds a row to todos.csv. Tags are separated by commas, and
+// missing times are empty.
+func (a *Archive) WriteTodo(todo *models.Todo) error {
+	if a.table == nil {
+		return errors.New("todos.csv has not been started")
+	}
+
+	err := a.table.Write([]string{
+		strconv.Itoa(todo.ID),
+		todo.Title,
+		todo.Description,
+		strconv.FormatBool(todo.Completed),
+		formatOptionalTime(todo.CompletedAt),
+		formatOptionalTime(todo.DueAt),
+		todo.Priority,
+		todo.Recurrence,
+		strings.Join(todo.Tags, ","),
+		formatTime(todo.CreatedAt),
+		formatTime(todo.UpdatedAt),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write todo %d: %w", todo.ID, err)
+	}
+	return nil
+}
+
+// StartFlashcards starts flashcards.tsv, which Anki imports as notes in
+// plain text.
+func (a *Archive) StartFlashcards() error {
+	entry, err := a.create("flashcards.tsv", a.exportedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(entry, ankiHeader); err != nil {
+		return fmt.Errorf("failed to write flashcards: %w", err)
+	}
+	a.table = csv.NewWriter(entry)
+	a.table.Comma = '\t'
+	return nil
+}
+
+// WriteFlashcard adds the front, back and tags of a card to
+// flashcards.tsv. Anki tags cannot contain spaces, so they become
+// underscores.
+func (a *Archive) WriteFlashcard(card *models.Flashcard) error {
+	if a.table == nil {
+		return errors.New("flashcards.tsv has not been started")
+	}
+
+	tags := make([]string, len(card.Tags))
+	for i, tag := range card.Tags {
+		tags[i] = strings.Join(strings.Fields(tag), "_")
+	}
+
+	err := a.table.Write([]string{ankiField(card.Front), ankiField(card.Back), strings.Join(tags, " ")})
+	if err != nil {
+		return fmt.Errorf("failed to write flashcard %d: %w", card.ID, err)
+	}
+	return nil
+}
+
+// ankiField turns text into the HTML of an Anki field.
+func ankiField(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// StartDump starts data.json or data.ndjson.
+func (a *Archive) StartDump() error {
+	entry, err := a.create("data."+a.format, a.exportedAt)
+	if err != nil {
+		return err
+	}
+	a.dump = entry
+
+	if a.format == FormatNDJSON {
+		return a.writeLine("export", map[string]any{"version": dumpVersion, "exportedAt": a.exportedAt.UTC()})
+	}
+
+	exportedAt, err := json.Marshal(a.exportedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to encode export time: %w", err)
+	}
+	_, err = fmt.Fprintf(entry, `{"version":%d,"exportedAt":%s`, dumpVersion, exportedAt)
+	return dumpError(err)
+}
+
+// StartSection starts the list of items of kind in the dump. Items written
+// before the next section are of that kind.
+func (a *Archive) StartSection(kind string) error {
+	if a.dump == nil {
+		return errors.New("the dump has not been started")
+	}
+	a.kind = kind
+	a.count = 0
+	a.sections++
+
+	if a.format == FormatNDJSON {
+		return nil
+	}
+
+	closing := ""
+	if a.sections > 1 {
+		closing = "]"
+	}
+	_, err := fmt.Fprintf(a.dump, "%s,%q:[", closing, kind+"s")
+	return dumpError(err)
+}
+
+// WriteItem adds an item of the current section to the dump.
+func (a *Archive) WriteItem(item any) error {
+	if a.kind == "" {
+		return errors.New("no dump section has been started")
+	}
+
+	if a.format == FormatNDJSON {
+		return a.writeLine(a.kind, item)
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", a.kind, err)
+	}
+	if a.count > 0 {
+		data = append([]byte{','}, data...)
+	}
+	a.count++
+	_, err = a.dump.Write(data)
+	return dumpError(err)
+}
+
+func (a *Archive) writeLine(kind string, data any) error {
+	line, err := json.Marshal(struct {
+		Type string `json:"type"`
+		Data any    `json:"data"`
+	}{kind, data})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", kind, err)
+	}
+	_, err = a.dump.Write(append(line, '\n'))
+	return dumpError(err)
+}
+
+// Close finishes the dump and the archive. It does not close the
+// underlying writer.
+func (a *Archive) Close() error {
+	if err := a.flushTable(); err != nil {
+		return err
+	}
+
+	if a.dump != nil && a.format == FormatJSON {
+		closing := "}\n"
+		if a.sections > 0 {
+			closing = "]}\n"
+		}
+		if _, err := io.WriteString(a.dump, closing); err != nil {
+			return dumpError(err)
+		}
+	}
+
+	if err := a.zip.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+func dumpError(err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatOptionalTime writes a missing time as an empty field.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
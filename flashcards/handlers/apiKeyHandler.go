@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/keys", h.CreateAPIKey).Methods("POST")
+	router.HandleFunc("/auth/keys", h.GetAllAPIKeys).Methods("GET")
+	router.HandleFunc("/auth/keys/{id:[0-9]+}", h.RevokeAPIKey).Methods("DELETE")
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(requestUserID(r), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) GetAllAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAllAPIKeys(requestUserID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid API key ID"))
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(requestUserID(r), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIKeyHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"flashcards/apperr"
+	"flashcards/auth"
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type AuthHandler struct {
+	service *services.AuthService
+}
+
+func NewAuthHandler(service *services.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+func (h *AuthHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/register", h.Register).Methods("POST")
+	router.HandleFunc("/auth/login", h.Login).Methods("POST")
+	router.HandleFunc("/auth/me", h.GetCurrentUser).Methods("GET")
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	response, err := h.service.Register(&req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, response)
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	response, err := h.service.Login(&req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetUser(requestUserID(r))
+	if err != nil {
+		// The token is valid but its user has been deleted.
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteProblem(w, r, http.StatusUnauthorized, "User no longer exists")
+			return
+		}
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, user)
+}
+
+func (h *AuthHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+// requestUserID returns the id of the user the auth middleware signed in.
+// Every route outside /auth is behind the middleware, so it is always set.
+func requestUserID(r *http.Request) int {
+	userID, _ := auth.UserID(r.Context())
+	return userID
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DeckHandler struct {
+	service *services.DeckService
+}
+
+func NewDeckHandler(service *services.DeckService) *DeckHandler {
+	return &DeckHandler{service: service}
+}
+
+func (h *DeckHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/decks", h.CreateDeck).Methods("POST")
+	router.HandleFunc("/decks", h.GetAllDecks).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}", h.GetDeckByID).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}", h.UpdateDeck).Methods("PUT")
+	router.HandleFunc("/decks/{id:[0-9]+}", h.DeleteDeck).Methods("DELETE")
+	router.HandleFunc("/decks/{id:[0-9]+}/notes", h.GetDeckNotes).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}/notes/{noteId:[0-9]+}", h.AddNote).Methods("PUT")
+	router.HandleFunc("/decks/{id:[0-9]+}/notes/{noteId:[0-9]+}", h.RemoveNote).Methods("DELETE")
+}
+
+func (h *DeckHandler) CreateDeck(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDeckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	deck, err := h.service.CreateDeck(requestUserID(r), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, deck)
+}
+
+func (h *DeckHandler) GetAllDecks(w http.ResponseWriter, r *http.Request) {
+	decks, err := h.service.GetAllDecks(requestUserID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, decks)
+}
+
+func (h *DeckHandler) GetDeckByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid deck ID"))
+		return
+	}
+
+	deck, err := h.service.GetDeckByID(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, deck)
+}
+
+func (h *DeckHandler) UpdateDeck(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid deck ID"))
+		return
+	}
+
+	var req models.UpdateDeckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	deck, err := h.service.UpdateDeck(requestUserID(r), id, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, deck)
+}
+
+func (h *DeckHandler) DeleteDeck(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid deck ID"))
+		return
+	}
+
+	err = h.service.DeleteDeck(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeckHandler) GetDeckNotes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid deck ID"))
+		return
+	}
+
+	includeSubDecks := false
+	if value := r.URL.Query().Get("recursive"); value != "" {
+		includeSubDecks, err = strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, r, apperr.Validation("recursive", "Invalid recursive flag"))
+			return
+		}
+	}
+
+	notes, err := h.service.GetDeckNotes(requestUserID(r), id, includeSubDecks)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, notes)
+}
+
+func (h *DeckHandler) AddNote(w http.ResponseWriter, r *http.Request) {
+	deckID, noteID, ok := h.membershipIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.AddNote(requestUserID(r), deckID, noteID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeckHandler) RemoveNote(w http.ResponseWriter, r *http.Request) {
+	deckID, noteID, ok := h.membershipIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveNote(requestUserID(r), deckID, noteID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeckHandler) membershipIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	deckID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid deck ID"))
+		return 0, 0, false
+	}
+
+	noteID, err := strconv.Atoi(vars["noteId"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("noteId", "Invalid note ID"))
+		return 0, 0, false
+	}
+
+	return deckID, noteID, true
+}
+
+func (h *DeckHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
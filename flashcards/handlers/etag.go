@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flashcards/apperr"
+)
+
+// setETag sets the ETag of a note or todo response, which is its version.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion returns the version the If-Match header of r requires the
+// item to be at. Without the header, or with "*", it returns 0, which
+// matches any version. Weak and unknown entity tags never match, as
+// If-Match only uses strong comparison.
+func ifMatchVersion(r *http.Request) (int, error) {
+	value := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+	if strings.Contains(value, ",") {
+		return 0, apperr.Validation("If-Match", "If-Match must be a single ETag")
+	}
+
+	if strings.HasPrefix(value, "W/") {
+		return 0, apperr.PreconditionFailed("weak ETag %s cannot be used with If-Match", value)
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		return 0, apperr.Validation("If-Match", "invalid ETag %s, it must be quoted", value)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, apperr.PreconditionFailed("ETag %s does not match the current version", value)
+	}
+
+	return version, nil
+}
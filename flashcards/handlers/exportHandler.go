@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"flashcards/exporter"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ExportHandler struct {
+	service *services.ExportService
+}
+
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+func (h *ExportHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/export", h.Export).Methods("GET")
+}
+
+// Export streams a ZIP archive of everything the user has. format picks the
+// dump, json (the default) or ndjson.
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exporter.FormatJSON
+	}
+	if err := h.service.ValidateFormat(format); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flashcards-export-%s.zip"`, time.Now().UTC().Format("2006-01-02")))
+
+	body := &startedWriter{ResponseWriter: w}
+	if err := h.service.Export(requestUserID(r), format, body); err != nil {
+		if !body.started {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, err)
+			return
+		}
+		// The status is long sent, so the only way to tell the client is to
+		// break off the response before the archive is complete.
+		log.Printf("[ERROR] %s %s failed after the response started: %v", r.Method, r.URL.Path, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// startedWriter records whether anything has been written to the response.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) Write(data []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type FlashcardHandler struct {
+	service *services.FlashcardService
+}
+
+func NewFlashcardHandler(service *services.FlashcardService) *FlashcardHandler {
+	return &FlashcardHandler{service: service}
+}
+
+func (h *FlashcardHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/flashcards", h.CreateFlashcard).Methods("POST")
+	router.HandleFunc("/flashcards", h.GetAllFlashcards).Methods("GET")
+	router.HandleFunc("/flashcards/{id:[0-9]+}", h.GetFlashcardByID).Methods("GET")
+	router.HandleFunc("/flashcards/{id:[0-9]+}", h.UpdateFlashcard).Methods("PUT")
+	router.HandleFunc("/flashcards/{id:[0-9]+}", h.DeleteFlashcard).Methods("DELETE")
+	router.HandleFunc("/notes/{id:[0-9]+}/flashcards", h.GetNoteFlashcards).Methods("GET")
+	router.HandleFunc("/notes/{id:[0-9]+}/flashcards", h.GenerateNoteFlashcards).Methods("POST")
+}
+
+func (h *FlashcardHandler) CreateFlashcard(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateFlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	card, err := h.service.CreateFlashcard(requestUserID(r), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, card)
+}
+
+func (h *FlashcardHandler) GetAllFlashcards(w http.ResponseWriter, r *http.Request) {
+	cards, err := h.service.GetAllFlashcards(requestUserID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, cards)
+}
+
+func (h *FlashcardHandler) GetFlashcardByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid flashcard ID"))
+		return
+	}
+
+	card, err := h.service.GetFlashcardByID(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, card)
+}
+
+func (h *FlashcardHandler) UpdateFlashcard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid flashcard ID"))
+		return
+	}
+
+	var req models.UpdateFlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	card, err := h.service.UpdateFlashcard(requestUserID(r), id, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, card)
+}
+
+func (h *FlashcardHandler) DeleteFlashcard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid flashcard ID"))
+		return
+	}
+
+	err = h.service.DeleteFlashcard(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FlashcardHandler) GetNoteFlashcards(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	cards, err := h.service.GetFlashcardsByNoteID(requestUserID(r), noteID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, cards)
+}
+
+func (h *FlashcardHandler) GenerateNoteFlashcards(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	// The body is optional, an empty request uses the default settings.
+	var req models.GenerateFlashcardsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	cards, err := h.service.GenerateFlashcardsFromNote(requestUserID(r), noteID, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, cards)
+}
+
+func (h *FlashcardHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+
+	// Time zones for the due filter, also on hosts without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// parseListOptions reads the paging, sorting and common filter parameters
+// of a list request:
+//
+//	limit, cursor, sort (createdAt or updatedAt), order (asc or desc), q,
+//	created_after, created_before, updated_after, updated_before
+//
+// Dates are RFC 3339 timestamps or plain dates. The after bounds are
+// inclusive and the before bounds exclusive.
+func parseListOptions(query url.Values) (models.ListOptions, error) {
+	opts := models.ListOptions{
+		SortBy:    query.Get("sort"),
+		SortOrder: strings.ToLower(query.Get("order")),
+		Query:     strings.TrimSpace(query.Get("q")),
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, apperr.Validation("limit", "invalid limit: %q", value)
+		}
+		opts.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := models.DecodeCursor(value)
+		if err != nil {
+			return opts, apperr.Validation("cursor", "%v", err)
+		}
+		opts.After = cursor
+	}
+
+	dates := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_after", &opts.CreatedAfter},
+		{"created_before", &opts.CreatedBefore},
+		{"updated_after", &opts.UpdatedAfter},
+		{"updated_before", &opts.UpdatedBefore},
+	}
+	for _, date := range dates {
+		value := query.Get(date.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseListDate(value)
+		if err != nil {
+			return opts, apperr.Validation(date.name, "invalid %s: %q", date.name, value)
+		}
+		*date.target = &parsed
+	}
+
+	return opts, nil
+}
+
+func parseListDate(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// parseTagFilter reads the tag filter of a list request. Tags are given as
+// repeated or comma separated tag parameters, and match=all requires every
+// tag instead of any of them.
+func parseTagFilter(query url.Values) (models.TagFilter, error) {
+	var filter models.TagFilter
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	switch match := query.Get("match"); match {
+	case "", tagMatchAny:
+	case tagMatchAll:
+		filter.MatchAll = true
+	default:
+		return filter, apperr.Validation("match", "invalid match mode %q, expected %s or %s", match, tagMatchAny, tagMatchAll)
+	}
+
+	return filter, nil
+}
+
+// parseDueFilter reads the due dates of a todo list request into opts, and
+// due=overdue or due=today into the filter. The day of due=today is that of
+// the IANA time zone tz, UTC by default.
+func parseDueFilter(query url.Values, opts *models.TodoListOptions) (models.DueFilter, error) {
+	filter := models.DueFilter{Due: query.Get("due")}
+
+	dates := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"due_after", &opts.DueAfter},
+		{"due_before", &opts.DueBefore},
+	}
+	for _, date := range dates {
+		value := query.Get(date.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseListDate(value)
+		if err != nil {
+			return filter, apperr.Validation(date.name, "invalid %s: %q", date.name, value)
+		}
+		*date.target = &parsed
+	}
+
+	if value := query.Get("tz"); value != "" {
+		location, err := time.LoadLocation(value)
+		if err != nil {
+			return filter, apperr.Validation("tz", "unknown time zone %q", value)
+		}
+		filter.Location = location
+	}
+
+	return filter, nil
+}
@@ -2,9 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"flashcards/apperr"
+	"flashcards/importer"
 	"flashcards/models"
 	"flashcards/services"
 
@@ -22,55 +29,72 @@ func NewNoteHandler(service *services.NoteService) *NoteHandler {
 func (h *NoteHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/notes", h.CreateNote).Methods("POST")
 	router.HandleFunc("/notes", h.GetAllNotes).Methods("GET")
+	router.HandleFunc("/notes/import", h.ImportNotes).Methods("POST")
 	router.HandleFunc("/notes/{id:[0-9]+}", h.GetNoteByID).Methods("GET")
 	router.HandleFunc("/notes/{id:[0-9]+}", h.UpdateNote).Methods("PUT")
+	router.HandleFunc("/notes/{id:[0-9]+}", h.PatchNote).Methods("PATCH")
 	router.HandleFunc("/notes/{id:[0-9]+}", h.DeleteNote).Methods("DELETE")
+	router.HandleFunc("/notes/{id:[0-9]+}/restore", h.RestoreNote).Methods("POST")
+	router.HandleFunc("/notes/{id:[0-9]+}/revisions", h.ListRevisions).Methods("GET")
+	router.HandleFunc("/notes/{id:[0-9]+}/revisions/diff", h.DiffRevisions).Methods("GET")
+	router.HandleFunc("/notes/{id:[0-9]+}/revisions/{revision:[0-9]+}", h.GetRevision).Methods("GET")
+	router.HandleFunc("/notes/{id:[0-9]+}/revisions/{revision:[0-9]+}/restore", h.RestoreRevision).Methods("POST")
 }
 
 func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateNoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
 		return
 	}
 
-	note, err := h.service.CreateNote(&req)
+	note, err := h.service.CreateNote(requestUserID(r), &req)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, err)
 		return
 	}
 
+	setETag(w, note.Version)
 	h.writeJSONResponse(w, http.StatusCreated, note)
 }
 
 func (h *NoteHandler) GetAllNotes(w http.ResponseWriter, r *http.Request) {
-	notes, err := h.service.GetAllNotes()
+	query := r.URL.Query()
+	opts, err := parseListOptions(query)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve notes")
+		writeError(w, r, err)
+		return
+	}
+	tags, err := parseTagFilter(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := h.service.ListNotes(requestUserID(r), models.NoteListOptions{ListOptions: opts}, tags)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, notes)
+	h.writeJSONResponse(w, http.StatusOK, page)
 }
 
 func (h *NoteHandler) GetNoteByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid note ID")
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
 		return
 	}
 
-	note, err := h.service.GetNoteByID(id)
+	note, err := h.service.GetNoteByID(requestUserID(r), id)
 	if err != nil {
-		if containsNoteNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve note")
-		}
+		writeError(w, r, err)
 		return
 	}
 
+	setETag(w, note.Version)
 	h.writeJSONResponse(w, http.StatusOK, note)
 }
 
@@ -78,26 +102,60 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid note ID")
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	var req models.UpdateNoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
 		return
 	}
 
-	note, err := h.service.UpdateNote(id, &req)
+	note, err := h.service.UpdateNote(requestUserID(r), id, &req, version)
 	if err != nil {
-		if containsNoteNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, note.Version)
+	h.writeJSONResponse(w, http.StatusOK, note)
+}
+
+// PatchNote applies a JSON merge patch (application/merge-patch+json) or a
+// JSON patch (application/json-patch+json) to the note.
+func (h *NoteHandler) PatchNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
 		return
 	}
 
+	note, err := h.service.PatchNote(requestUserID(r), id, p, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, note.Version)
 	h.writeJSONResponse(w, http.StatusOK, note)
 }
 
@@ -105,36 +163,234 @@ func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid note ID")
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
 		return
 	}
 
-	err = h.service.DeleteNote(id)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		if containsNoteNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete note")
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.service.DeleteNote(requestUserID(r), id, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *NoteHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+func (h *NoteHandler) RestoreNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	note, err := h.service.RestoreNote(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, note.Version)
+	h.writeJSONResponse(w, http.StatusOK, note)
 }
 
-func (h *NoteHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+func (h *NoteHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	revisions, err := h.service.ListNoteRevisions(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, revisions)
+}
+
+func (h *NoteHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	id, revision, err := parseRevisionVars(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	rev, err := h.service.GetNoteRevision(requestUserID(r), id, revision)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, rev)
+}
+
+// DiffRevisions compares the revisions from and to of a note, by default the
+// latest revision and the one before it. mode is line (the default) or word.
+func (h *NoteHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid note ID"))
+		return
+	}
+
+	query := r.URL.Query()
+	revisions := map[string]int{"from": 0, "to": 0}
+	for name := range revisions {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		revisions[name], err = strconv.Atoi(value)
+		if err != nil {
+			writeError(w, r, apperr.Validation(name, "invalid revision: %q", value))
+			return
+		}
+	}
+
+	result, err := h.service.DiffNoteRevisions(requestUserID(r), id, revisions["from"], revisions["to"], query.Get("mode"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, result)
+}
+
+func (h *NoteHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	id, revision, err := parseRevisionVars(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	note, err := h.service.RestoreNoteRevision(requestUserID(r), id, revision)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, note.Version)
+	h.writeJSONResponse(w, http.StatusOK, note)
+}
+
+// Limits of an upload to POST /notes/import. Files beyond maxImportMemory
+// are buffered on disk while the request is read.
+const (
+	maxImportSize   = 32 << 20
+	maxImportMemory = 8 << 20
+)
+
+// ImportNotes reads notes from the files of a multipart/form-data upload,
+// sent in the files field. The options are query or form values: format,
+// delimiter, headingLevel, tags (comma separated) and atomic.
+func (h *NoteHandler) ImportNotes(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload exceeds %d MB", maxImportSize>>20))
+			return
+		}
+		writeError(w, r, apperr.Validation("", "Expected a multipart/form-data upload"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	files, err := readImportFiles(r.MultipartForm.File["files"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	report, err := h.service.ImportNotes(requestUserID(r), files, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, report)
+}
+
+func parseImportOptions(r *http.Request) (models.NoteImportOptions, error) {
+	opts := models.NoteImportOptions{
+		Format:    r.FormValue("format"),
+		Delimiter: r.FormValue("delimiter"),
+	}
+
+	if value := r.FormValue("headingLevel"); value != "" {
+		level, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, apperr.Validation("headingLevel", "invalid heading level: %q", value)
+		}
+		opts.HeadingLevel = level
+	}
+
+	for _, tag := range strings.Split(r.FormValue("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			opts.Tags = append(opts.Tags, tag)
+		}
+	}
+
+	if value := r.FormValue("atomic"); value != "" {
+		atomic, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, apperr.Validation("atomic", "invalid atomic flag: %q", value)
+		}
+		opts.Atomic = atomic
+	}
+
+	return opts, nil
+}
+
+func readImportFiles(headers []*multipart.FileHeader) ([]importer.File, error) {
+	files := make([]importer.File, 0, len(headers))
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+		}
+
+		files = append(files, importer.File{Name: header.Filename, Data: data})
+	}
+	return files, nil
 }
 
-func containsNoteNotFound(message string) bool {
-	return len(message) > 0 && (message[len(message)-9:] == "not found" ||
-		message[:len("note with id")] == "note with id")
+func parseRevisionVars(r *http.Request) (id, revision int, err error) {
+	vars := mux.Vars(r)
+	id, err = strconv.Atoi(vars["id"])
+	if err != nil {
+		return 0, 0, apperr.Validation("id", "Invalid note ID")
+	}
+	revision, err = strconv.Atoi(vars["revision"])
+	if err != nil {
+		return 0, 0, apperr.Validation("revision", "Invalid revision")
+	}
+	return id, revision, nil
+}
+
+func (h *NoteHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
 }
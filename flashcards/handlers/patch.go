@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/patch"
+)
+
+// acceptedPatchTypes lists the patch documents PATCH routes accept, for the
+// Accept-Patch header.
+var acceptedPatchTypes = strings.Join([]string{patch.MergePatchType, patch.JSONPatchType}, ", ")
+
+// readPatch parses the body of a PATCH request as a JSON merge patch or a
+// JSON patch, depending on its Content-Type. A plain JSON body is taken as a
+// merge patch. It writes the error response itself and reports whether the
+// request can go on.
+func readPatch(w http.ResponseWriter, r *http.Request) (patch.Patch, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var parse func([]byte) (patch.Patch, error)
+	switch mediaType {
+	case patch.MergePatchType, "application/json":
+		parse = patch.ParseMergePatch
+	case patch.JSONPatchType:
+		parse = patch.ParseJSONPatch
+	default:
+		w.Header().Set("Accept-Patch", acceptedPatchTypes)
+		WriteProblem(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported patch type %q, expected one of %s", mediaType, acceptedPatchTypes))
+		return nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, apperr.Validation("", "Failed to read request body"))
+		return nil, false
+	}
+
+	p, err := parse(body)
+	if err != nil {
+		writeError(w, r, apperr.Validation("", "%v", err))
+		return nil, false
+	}
+
+	return p, true
+}
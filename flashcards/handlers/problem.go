@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"flashcards/apperr"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, the body of every error
+// response.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
+// errorStatuses maps the kinds of errors from db and services to a status.
+// Errors of any other kind are unexpected and answered with a 500.
+var errorStatuses = []struct {
+	kind   error
+	status int
+}{
+	{apperr.ErrNotFound, http.StatusNotFound},
+	{apperr.ErrValidation, http.StatusBadRequest},
+	{apperr.ErrConflict, http.StatusConflict},
+	{apperr.ErrUnauthorized, http.StatusUnauthorized},
+	{apperr.ErrQuotaExceeded, http.StatusTooManyRequests},
+	{apperr.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{apperr.ErrUpstream, http.StatusBadGateway},
+}
+
+func errorStatus(err error) int {
+	for _, mapping := range errorStatuses {
+		if errors.Is(err, mapping.kind) {
+			return mapping.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// newProblem returns the problem for status. The problem type is left as
+// about:blank, the status tells the kinds of errors apart.
+func newProblem(r *http.Request, status int, detail string) *Problem {
+	return &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// errorProblem turns err into a problem.
+func errorProblem(r *http.Request, err error) *Problem {
+	status, detail, fields := describeError(r.Method+" "+r.URL.Path, err)
+
+	problem := newProblem(r, status, detail)
+	problem.Errors = fields
+	return problem
+}
+
+// describeError returns the status and the client facing detail of err.
+// Only the messages of errors of a known kind reach the client, unexpected
+// errors are logged under op instead since they may reveal internals.
+func describeError(op string, err error) (int, string, []apperr.FieldError) {
+	status := errorStatus(err)
+
+	var appErr *apperr.Error
+	if status == http.StatusInternalServerError || !errors.As(err, &appErr) {
+		log.Printf("[ERROR] %s failed: %v", op, err)
+		return status, "An unexpected error occurred", nil
+	}
+	if status == http.StatusBadGateway {
+		log.Printf("[ERROR] %s failed upstream: %v", op, err)
+	}
+
+	return status, appErr.Message, appErr.Fields
+}
+
+// writeError answers with the problem for err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblemResponse(w, errorProblem(r, err))
+}
+
+// WriteProblem answers with a problem that is not caused by an error from
+// db or services, such as a malformed request or a failed authentication.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblemResponse(w, newProblem(r, status, detail))
+}
+
+func writeProblemResponse(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
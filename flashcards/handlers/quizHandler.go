@@ -2,19 +2,25 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 
+	"flashcards/apperr"
 	"flashcards/models"
 	"flashcards/services"
 
 	"github.com/gorilla/mux"
 )
 
+// QuizRequest selects the notes to quiz on with NoteIDs, DeckID,
+// TagExpression or any combination of them, which are joined together.
+// DeckID includes the notes of every sub-deck and TagExpression is a boolean
+// expression over note tags such as "history and not draft". Without any of
+// them, the quiz draws on all notes.
 type QuizRequest struct {
-	NoteIDs  []int            `json:"note_ids"`
-	Messages []models.Message `json:"messages"`
+	NoteIDs       []int  `json:"note_ids"`
+	DeckID        *int   `json:"deck_id,omitempty"`
+	TagExpression string `json:"tag_expression,omitempty"`
 }
 
 type QuizResponse struct {
@@ -22,33 +28,112 @@ type QuizResponse struct {
 	Messages []models.Message `json:"messages"`
 }
 
+type CreateQuizSessionRequest struct {
+	NoteIDs       []int  `json:"note_ids"`
+	DeckID        *int   `json:"deck_id,omitempty"`
+	TagExpression string `json:"tag_expression,omitempty"`
+}
+
+type QuizSessionMessageRequest struct {
+	Content string `json:"content"`
+}
+
+type QuizStreamToken struct {
+	Content string `json:"content"`
+}
+
+type QuizStreamDone struct {
+	Message   models.Message      `json:"message"`
+	NoteIDs   []int               `json:"note_ids"`
+	SessionID string              `json:"session_id,omitempty"`
+	Grade     *models.AnswerGrade `json:"grade,omitempty"`
+	Score     *models.QuizScore   `json:"score,omitempty"`
+}
+
+type QuizStreamError struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// quizNoteSources turns the deck and tag selection of a quiz request into
+// note ids.
+type quizNoteSources struct {
+	decks *services.DeckService
+	tags  *services.TagService
+}
+
+func (s quizNoteSources) resolve(userID int, noteIDs []int, deckID *int, tagExpression string) ([]int, error) {
+	noteIDs, err := s.decks.ResolveQuizNoteIDs(userID, noteIDs, deckID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tags.ResolveQuizNoteIDs(userID, noteIDs, tagExpression)
+}
+
 type QuizHandler struct {
 	service *services.QuizService
+	sources quizNoteSources
 }
 
-func NewQuizHandler(service *services.QuizService) *QuizHandler {
-	return &QuizHandler{service: service}
+func NewQuizHandler(service *services.QuizService, decks *services.DeckService, tags *services.TagService) *QuizHandler {
+	return &QuizHandler{
+		service: service,
+		sources: quizNoteSources{decks: decks, tags: tags},
+	}
 }
 
 func (h *QuizHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/quiz/generate", h.GenerateQuiz).Methods("POST")
 	router.HandleFunc("/quiz/generate/stream", h.GenerateQuizStream).Methods("POST")
+	router.HandleFunc("/quiz/sessions", h.CreateSession).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id}", h.GetSession).Methods("GET")
+	router.HandleFunc("/quiz/sessions/{id}/messages", h.AddSessionMessage).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id}/messages/stream", h.StreamSessionMessage).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id}/complete", h.CompleteSession).Methods("POST")
 }
 
+// decodeQuizRequest reads the body of /quiz/generate and
+// /quiz/generate/stream, which only ever ask the first question. A message
+// history is refused rather than ignored: a client could forge the turns of
+// the assistant, so quizzes only continue in sessions, whose history the
+// server stores itself.
+func decodeQuizRequest(r *http.Request) (*QuizRequest, error) {
+	var req struct {
+		QuizRequest
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode quiz request JSON: %v", err)
+		return nil, apperr.Validation("", "Invalid JSON payload")
+	}
+	if len(req.Messages) > 0 {
+		log.Printf("[ERROR] Quiz request with %d messages refused", len(req.Messages))
+		return nil, apperr.Validation("messages", "a message history is not accepted, start a session with POST /quiz/sessions and answer with POST /quiz/sessions/{id}/messages")
+	}
+	return &req.QuizRequest, nil
+}
+
+// GenerateQuiz asks the first question of a one-off quiz.
 func (h *QuizHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Received quiz generation request")
 
-	var req QuizRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Failed to decode quiz request JSON: %v", err)
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+	req, err := decodeQuizRequest(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	noteIDs, err := h.sources.resolve(requestUserID(r), req.NoteIDs, req.DeckID, req.TagExpression)
+	if err != nil {
+		log.Printf("[ERROR] Failed to resolve quiz notes: %v", err)
+		writeError(w, r, err)
 		return
 	}
 
-	result, err := h.service.GenerateQuizResponse(req.NoteIDs, req.Messages)
+	result, err := h.service.GenerateQuizResponse(requestUserID(r), noteIDs)
 	if err != nil {
 		log.Printf("[ERROR] Quiz generation failed: %v", err)
-		h.writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, err)
 		return
 	}
 
@@ -61,51 +146,162 @@ func (h *QuizHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// GenerateQuizStream works like GenerateQuiz but streams the question.
 func (h *QuizHandler) GenerateQuizStream(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Received streaming quiz generation request")
 
-	// Set SSE headers
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	req, err := decodeQuizRequest(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	var req QuizRequest
+	noteIDs, err := h.sources.resolve(requestUserID(r), req.NoteIDs, req.DeckID, req.TagExpression)
+	if err != nil {
+		log.Printf("[ERROR] Failed to resolve quiz notes: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	h.streamQuizReply(w, r, func(onToken services.TokenCallback) (*QuizStreamDone, error) {
+		result, err := h.service.GenerateQuizResponseStream(r.Context(), requestUserID(r), noteIDs, onToken)
+		if err != nil {
+			return nil, err
+		}
+		return &QuizStreamDone{
+			Message: result.Messages[len(result.Messages)-1],
+			NoteIDs: result.NoteIDs,
+		}, nil
+	})
+}
+
+func (h *QuizHandler) StreamSessionMessage(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Received streaming quiz session message")
+
+	var req QuizSessionMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Failed to decode streaming quiz request JSON: %v", err)
-		fmt.Fprintf(w, "Error: Invalid JSON payload\n\n")
+		log.Printf("[ERROR] Failed to decode quiz session message JSON: %v", err)
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
 		return
 	}
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		log.Printf("[ERROR] Streaming not supported")
-		fmt.Fprintf(w, "Error: Streaming not supported\n\n")
+	id := mux.Vars(r)["id"]
+	h.streamQuizReply(w, r, func(onToken services.TokenCallback) (*QuizStreamDone, error) {
+		session, err := h.service.AnswerSessionStream(r.Context(), requestUserID(r), id, req.Content, onToken)
+		if err != nil {
+			return nil, err
+		}
+		return &QuizStreamDone{
+			Message:   session.Messages[len(session.Messages)-1],
+			NoteIDs:   session.NoteIDs,
+			SessionID: session.ID,
+			Grade:     session.Messages[len(session.Messages)-2].Grade,
+			Score:     session.Score,
+		}, nil
+	})
+}
+
+// streamQuizReply runs generate while streaming its tokens as SSE token
+// events, and finishes with a single done or error event. Generation is
+// cancelled through the request context when the client goes away.
+func (h *QuizHandler) streamQuizReply(w http.ResponseWriter, r *http.Request, generate func(services.TokenCallback) (*QuizStreamDone, error)) {
+	stream, err := newSSEWriter(w)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	err := h.service.GenerateQuizResponseStream(req.NoteIDs, req.Messages, func(token string) {
-		fmt.Fprintf(w, "%s", token)
-		flusher.Flush()
+	stopHeartbeat := stream.StartHeartbeat(sseHeartbeatInterval)
+	done, err := generate(func(token string) error {
+		return stream.Event("token", QuizStreamToken{Content: token})
 	})
+	stopHeartbeat()
+
+	if r.Context().Err() != nil {
+		log.Printf("[INFO] Client disconnected, streaming quiz generation stopped")
+		return
+	}
 
 	if err != nil {
 		log.Printf("[ERROR] Streaming quiz generation failed: %v", err)
-		fmt.Fprintf(w, "Error: %s", err.Error())
+		status, detail, _ := describeError(r.Method+" "+r.URL.Path, err)
+		stream.Event("error", QuizStreamError{Error: detail, Status: status})
 		return
 	}
 
+	stream.Event("done", done)
 	log.Printf("[INFO] Streaming quiz generation completed successfully")
 }
 
-func (h *QuizHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+func (h *QuizHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Received quiz session creation request")
+
+	var req CreateQuizSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode quiz session request JSON: %v", err)
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	noteIDs, err := h.sources.resolve(requestUserID(r), req.NoteIDs, req.DeckID, req.TagExpression)
+	if err != nil {
+		log.Printf("[ERROR] Failed to resolve quiz notes: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	session, err := h.service.StartSession(requestUserID(r), noteIDs)
+	if err != nil {
+		log.Printf("[ERROR] Quiz session creation failed: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, session)
+}
+
+func (h *QuizHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.GetSession(requestUserID(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
 }
 
-func (h *QuizHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+func (h *QuizHandler) AddSessionMessage(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Received quiz session message")
+
+	var req QuizSessionMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode quiz session message JSON: %v", err)
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	session, err := h.service.AnswerSession(requestUserID(r), mux.Vars(r)["id"], req.Content)
+	if err != nil {
+		log.Printf("[ERROR] Quiz session message failed: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+func (h *QuizHandler) CompleteSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.CompleteSession(requestUserID(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+func (h *QuizHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	json.NewEncoder(w).Encode(data)
 }
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Per-connection limits for the quiz WebSocket.
+const (
+	quizSocketMaxFrameBytes     = 16 << 10
+	quizSocketMaxTurns          = 200
+	quizSocketIdleTimeout       = 5 * time.Minute
+	quizSocketGenerationTimeout = 2 * time.Minute
+	quizSocketPongWait          = 60 * time.Second
+	quizSocketPingInterval      = 25 * time.Second
+	quizSocketWriteWait         = 10 * time.Second
+)
+
+// Frame types exchanged over the quiz WebSocket.
+const (
+	quizFrameStart     = "start"
+	quizFrameResume    = "resume"
+	quizFrameAnswer    = "answer"
+	quizFrameTyping    = "typing"
+	quizFrameCancel    = "cancel"
+	quizFrameSession   = "session"
+	quizFrameToken     = "token"
+	quizFrameMessage   = "message"
+	quizFrameCancelled = "cancelled"
+	quizFrameError     = "error"
+)
+
+// QuizSocketClientFrame is a frame sent by the client. Start opens a new
+// session for note_ids, deck_id and tag_expression, resume attaches to
+// session_id, answer submits content, typing keeps the connection alive and
+// cancel stops the reply that is currently being generated.
+type QuizSocketClientFrame struct {
+	Type          string `json:"type"`
+	NoteIDs       []int  `json:"note_ids,omitempty"`
+	DeckID        *int   `json:"deck_id,omitempty"`
+	TagExpression string `json:"tag_expression,omitempty"`
+	SessionID     string `json:"session_id,omitempty"`
+	Content       string `json:"content,omitempty"`
+}
+
+// QuizSocketServerFrame is a frame sent by the server.
+type QuizSocketServerFrame struct {
+	Type      string              `json:"type"`
+	SessionID string              `json:"session_id,omitempty"`
+	Content   string              `json:"content,omitempty"`
+	Session   *models.QuizSession `json:"session,omitempty"`
+	Message   *models.Message     `json:"message,omitempty"`
+	Grade     *models.AnswerGrade `json:"grade,omitempty"`
+	Score     *models.QuizScore   `json:"score,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Status    int                 `json:"status,omitempty"`
+}
+
+type QuizSocketHandler struct {
+	service  *services.QuizService
+	sources  quizNoteSources
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	conns   map[*quizSocketConn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func NewQuizSocketHandler(service *services.QuizService, decks *services.DeckService, tags *services.TagService) *QuizSocketHandler {
+	return &QuizSocketHandler{
+		service: service,
+		sources: quizNoteSources{decks: decks, tags: tags},
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// CORS is open for the rest of the API as well.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		conns: make(map[*quizSocketConn]struct{}),
+	}
+}
+
+func (h *QuizSocketHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/quiz/ws", h.Serve).Methods("GET")
+}
+
+func (h *QuizSocketHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	closing := h.closing
+	h.mu.Unlock()
+	if closing {
+		WriteProblem(w, r, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to upgrade quiz WebSocket: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &quizSocketConn{
+		service:      h.service,
+		sources:      h.sources,
+		userID:       requestUserID(r),
+		conn:         conn,
+		ctx:          ctx,
+		cancel:       cancel,
+		lastActivity: time.Now(),
+	}
+
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+		return
+	}
+	h.conns[c] = struct{}{}
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	log.Printf("[INFO] Quiz WebSocket connected from %s", r.RemoteAddr)
+	go func() {
+		defer h.wg.Done()
+		c.run()
+
+		h.mu.Lock()
+		delete(h.conns, c)
+		h.mu.Unlock()
+		log.Printf("[INFO] Quiz WebSocket from %s closed", r.RemoteAddr)
+	}()
+}
+
+// Shutdown stops accepting connections, sends a going-away close frame to
+// every open connection and cancels their in-flight generations. It blocks
+// until all connection goroutines have returned.
+func (h *QuizSocketHandler) Shutdown() {
+	h.mu.Lock()
+	h.closing = true
+	conns := make([]*quizSocketConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	log.Printf("[INFO] Closing %d quiz WebSocket connections", len(conns))
+	for _, c := range conns {
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+	}
+	h.wg.Wait()
+}
+
+// quizSocketConn is a single quiz conversation. Reads happen on the run
+// goroutine, replies are generated on their own goroutine so that cancel
+// frames can still be read, and writes are serialized by writeMu.
+type quizSocketConn struct {
+	service *services.QuizService
+	sources quizNoteSources
+	userID  int
+	conn    *websocket.Conn
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	writeMu sync.Mutex
+
+	mu           sync.Mutex
+	sessionID    string
+	turns        int
+	lastActivity time.Time
+	generating   context.CancelFunc
+	generation   sync.WaitGroup
+	closeOnce    sync.Once
+}
+
+func (c *quizSocketConn) run() {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	c.conn.SetReadLimit(quizSocketMaxFrameBytes)
+	c.conn.SetReadDeadline(time.Now().Add(quizSocketPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(quizSocketPongWait))
+	})
+
+	go c.keepAlive()
+
+	for {
+		var frame QuizSocketClientFrame
+		if err := c.conn.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.sendError("invalid frame", http.StatusBadRequest)
+				continue
+			}
+			if errors.Is(err, websocket.ErrReadLimit) {
+				c.close(websocket.CloseMessageTooBig, "frame too large")
+			}
+			return
+		}
+
+		c.conn.SetReadDeadline(time.Now().Add(quizSocketPongWait))
+		c.mu.Lock()
+		c.lastActivity = time.Now()
+		c.mu.Unlock()
+
+		c.handleFrame(frame)
+	}
+}
+
+func (c *quizSocketConn) handleFrame(frame QuizSocketClientFrame) {
+	switch frame.Type {
+	case quizFrameStart:
+		noteIDs, err := c.sources.resolve(c.userID, frame.NoteIDs, frame.DeckID, frame.TagExpression)
+		if err != nil {
+			c.sendServiceError(err)
+			return
+		}
+		c.generate(func(ctx context.Context, onToken services.TokenCallback) (*QuizSocketServerFrame, error) {
+			session, err := c.service.StartSessionStream(ctx, c.userID, noteIDs, onToken)
+			if err != nil {
+				return nil, err
+			}
+			c.setSessionID(session.ID)
+			return &QuizSocketServerFrame{Type: quizFrameSession, SessionID: session.ID, Session: session}, nil
+		})
+	case quizFrameResume:
+		session, err := c.service.GetSession(c.userID, frame.SessionID)
+		if err != nil {
+			c.sendServiceError(err)
+			return
+		}
+		c.setSessionID(session.ID)
+		c.send(QuizSocketServerFrame{Type: quizFrameSession, SessionID: session.ID, Session: session})
+	case quizFrameAnswer:
+		sessionID := c.currentSessionID()
+		if sessionID == "" {
+			c.sendError("no quiz session, send a start or resume frame first", http.StatusBadRequest)
+			return
+		}
+		c.generate(func(ctx context.Context, onToken services.TokenCallback) (*QuizSocketServerFrame, error) {
+			session, err := c.service.AnswerSessionStream(ctx, c.userID, sessionID, frame.Content, onToken)
+			if err != nil {
+				return nil, err
+			}
+			message := session.Messages[len(session.Messages)-1]
+			return &QuizSocketServerFrame{
+				Type:      quizFrameMessage,
+				SessionID: session.ID,
+				Message:   &message,
+				Grade:     session.Messages[len(session.Messages)-2].Grade,
+				Score:     session.Score,
+			}, nil
+		})
+	case quizFrameTyping:
+		// Activity has already been recorded; nothing else to do.
+	case quizFrameCancel:
+		c.mu.Lock()
+		if c.generating != nil {
+			c.generating()
+		}
+		c.mu.Unlock()
+	default:
+		c.sendError("unknown frame type "+frame.Type, http.StatusBadRequest)
+	}
+}
+
+// generate runs one assistant reply in the background. Only one reply may
+// be in flight per connection, and the number of replies per connection is
+// capped at quizSocketMaxTurns.
+func (c *quizSocketConn) generate(fn func(context.Context, services.TokenCallback) (*QuizSocketServerFrame, error)) {
+	c.mu.Lock()
+	if c.generating != nil {
+		c.mu.Unlock()
+		c.sendError("a reply is already being generated", http.StatusConflict)
+		return
+	}
+	if c.turns >= quizSocketMaxTurns {
+		c.mu.Unlock()
+		c.sendError("turn limit reached for this connection", http.StatusTooManyRequests)
+		return
+	}
+	c.turns++
+	ctx, cancel := context.WithTimeout(c.ctx, quizSocketGenerationTimeout)
+	c.generating = cancel
+	c.generation.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.generation.Done()
+		defer func() {
+			cancel()
+			c.mu.Lock()
+			c.generating = nil
+			c.lastActivity = time.Now()
+			c.mu.Unlock()
+		}()
+
+		c.send(QuizSocketServerFrame{Type: quizFrameTyping})
+		done, err := fn(ctx, func(token string) error {
+			return c.send(QuizSocketServerFrame{Type: quizFrameToken, Content: token})
+		})
+
+		switch {
+		case c.ctx.Err() != nil:
+			return
+		case errors.Is(ctx.Err(), context.Canceled):
+			log.Printf("[INFO] Quiz WebSocket generation cancelled by client")
+			c.send(QuizSocketServerFrame{Type: quizFrameCancelled})
+		case err != nil:
+			log.Printf("[ERROR] Quiz WebSocket generation failed: %v", err)
+			c.sendServiceError(err)
+		default:
+			c.send(*done)
+		}
+	}()
+}
+
+// keepAlive pings the client and closes the connection once it has been
+// idle for longer than quizSocketIdleTimeout. Time spent generating a reply
+// does not count as idle.
+func (c *quizSocketConn) keepAlive() {
+	ticker := time.NewTicker(quizSocketPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			idle := c.generating == nil && time.Since(c.lastActivity) > quizSocketIdleTimeout
+			c.mu.Unlock()
+			if idle {
+				log.Printf("[INFO] Closing idle quiz WebSocket")
+				c.close(websocket.ClosePolicyViolation, "idle timeout")
+				return
+			}
+
+			c.writeMu.Lock()
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(quizSocketWriteWait))
+			c.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *quizSocketConn) send(frame QuizSocketServerFrame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(quizSocketWriteWait))
+	return c.conn.WriteJSON(frame)
+}
+
+func (c *quizSocketConn) sendError(message string, status int) {
+	c.send(QuizSocketServerFrame{Type: quizFrameError, Error: message, Status: status})
+}
+
+// sendServiceError sends the status and detail of an error from the quiz
+// service, the same ones a problem response would carry.
+func (c *quizSocketConn) sendServiceError(err error) {
+	status, detail, _ := describeError("quiz WebSocket", err)
+	c.sendError(detail, status)
+}
+
+func (c *quizSocketConn) setSessionID(id string) {
+	c.mu.Lock()
+	c.sessionID = id
+	c.mu.Unlock()
+}
+
+func (c *quizSocketConn) currentSessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// close cancels any in-flight generation, waits for it to stop, sends a
+// close frame and closes the underlying connection. It is safe to call more
+// than once and from any goroutine.
+func (c *quizSocketConn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.generation.Wait()
+
+		c.writeMu.Lock()
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(quizSocketWriteWait))
+		c.writeMu.Unlock()
+		c.conn.Close()
+	})
+}
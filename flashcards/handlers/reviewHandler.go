@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ReviewHandler struct {
+	service *services.ReviewService
+}
+
+func NewReviewHandler(service *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+func (h *ReviewHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/review/due", h.GetDueItems).Methods("GET")
+	router.HandleFunc("/review/{id:[0-9]+}/grade", h.GradeItem).Methods("POST")
+}
+
+func (h *ReviewHandler) GetDueItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 0
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			writeError(w, r, apperr.Validation("limit", "Invalid limit"))
+			return
+		}
+		limit = parsed
+	}
+
+	includeNew := true
+	if rawIncludeNew := query.Get("includeNew"); rawIncludeNew != "" {
+		parsed, err := strconv.ParseBool(rawIncludeNew)
+		if err != nil {
+			writeError(w, r, apperr.Validation("includeNew", "Invalid includeNew value"))
+			return
+		}
+		includeNew = parsed
+	}
+
+	items, err := h.service.GetDueItems(requestUserID(r), query.Get("type"), limit, includeNew)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, items)
+}
+
+func (h *ReviewHandler) GradeItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid item ID"))
+		return
+	}
+
+	var req models.GradeReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	state, err := h.service.GradeItem(requestUserID(r), req.ItemType, id, req.Grade)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, state)
+}
+
+func (h *ReviewHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
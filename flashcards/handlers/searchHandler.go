@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"flashcards/apperr"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type SearchHandler struct {
+	service *services.SearchService
+}
+
+func NewSearchHandler(service *services.SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+func (h *SearchHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notes/search", h.SearchNotes).Methods("GET")
+}
+
+func (h *SearchHandler) SearchNotes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, r, apperr.Validation("limit", "Invalid limit"))
+			return
+		}
+		limit = parsed
+	}
+
+	response, err := h.service.SearchNotes(r.Context(), requestUserID(r), query.Get("q"), query.Get("mode"), limit)
+	if err != nil {
+		log.Printf("[ERROR] Note search failed: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+func (h *SearchHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
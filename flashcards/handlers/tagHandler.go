@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	tagMatchAny = "any"
+	tagMatchAll = "all"
+)
+
+type TagHandler struct {
+	service *services.TagService
+}
+
+func NewTagHandler(service *services.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+func (h *TagHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/tags", h.GetAllTags).Methods("GET")
+	router.HandleFunc("/tags/merge", h.MergeTags).Methods("POST")
+	router.HandleFunc("/tags/{name}", h.RenameTag).Methods("PUT")
+}
+
+func (h *TagHandler) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.GetAllTags(requestUserID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, tags)
+}
+
+func (h *TagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	var req models.RenameTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	tag, err := h.service.RenameTag(requestUserID(r), mux.Vars(r)["name"], &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, tag)
+}
+
+func (h *TagHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	var req models.MergeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
+		return
+	}
+
+	tag, err := h.service.MergeTags(requestUserID(r), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, tag)
+}
+
+func (h *TagHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"flashcards/apperr"
 	"flashcards/models"
 	"flashcards/services"
 
@@ -24,53 +25,82 @@ func (h *TodoHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/todos", h.GetAllTodos).Methods("GET")
 	router.HandleFunc("/todos/{id:[0-9]+}", h.GetTodoByID).Methods("GET")
 	router.HandleFunc("/todos/{id:[0-9]+}", h.UpdateTodo).Methods("PUT")
+	router.HandleFunc("/todos/{id:[0-9]+}", h.PatchTodo).Methods("PATCH")
 	router.HandleFunc("/todos/{id:[0-9]+}", h.DeleteTodo).Methods("DELETE")
+	router.HandleFunc("/todos/{id:[0-9]+}/restore", h.RestoreTodo).Methods("POST")
 }
 
 func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateTodoRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
 		return
 	}
 
-	todo, err := h.service.CreateTodo(&req)
+	todo, err := h.service.CreateTodo(requestUserID(r), &req)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, err)
 		return
 	}
 
+	setETag(w, todo.Version)
 	h.writeJSONResponse(w, http.StatusCreated, todo)
 }
 
 func (h *TodoHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
-	todos, err := h.service.GetAllTodos()
+	query := r.URL.Query()
+	opts, err := parseListOptions(query)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todos")
+		writeError(w, r, err)
+		return
+	}
+	tags, err := parseTagFilter(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	todoOpts := models.TodoListOptions{ListOptions: opts}
+	if value := query.Get("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, r, apperr.Validation("completed", "Invalid completed flag"))
+			return
+		}
+		todoOpts.Completed = &completed
+	}
+	todoOpts.Priority = query.Get("priority")
+
+	due, err := parseDueFilter(query, &todoOpts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := h.service.ListTodos(requestUserID(r), todoOpts, tags, due)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, todos)
+	h.writeJSONResponse(w, http.StatusOK, page)
 }
 
 func (h *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid todo ID")
+		writeError(w, r, apperr.Validation("id", "Invalid todo ID"))
 		return
 	}
 
-	todo, err := h.service.GetTodoByID(id)
+	todo, err := h.service.GetTodoByID(requestUserID(r), id)
 	if err != nil {
-		if containsNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo")
-		}
+		writeError(w, r, err)
 		return
 	}
 
+	setETag(w, todo.Version)
 	h.writeJSONResponse(w, http.StatusOK, todo)
 }
 
@@ -78,26 +108,60 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid todo ID")
+		writeError(w, r, apperr.Validation("id", "Invalid todo ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	var req models.UpdateTodoRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		writeError(w, r, apperr.Validation("", "Invalid JSON payload"))
 		return
 	}
 
-	todo, err := h.service.UpdateTodo(id, &req)
+	todo, err := h.service.UpdateTodo(requestUserID(r), id, &req, version)
 	if err != nil {
-		if containsNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, todo.Version)
+	h.writeJSONResponse(w, http.StatusOK, todo)
+}
+
+// PatchTodo applies a JSON merge patch (application/merge-patch+json) or a
+// JSON patch (application/json-patch+json) to the todo.
+func (h *TodoHandler) PatchTodo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid todo ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
+		return
+	}
+
+	todo, err := h.service.PatchTodo(requestUserID(r), id, p, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	setETag(w, todo.Version)
 	h.writeJSONResponse(w, http.StatusOK, todo)
 }
 
@@ -105,36 +169,45 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid todo ID")
+		writeError(w, r, apperr.Validation("id", "Invalid todo ID"))
 		return
 	}
 
-	err = h.service.DeleteTodo(id)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		if containsNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete todo")
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.service.DeleteTodo(requestUserID(r), id, version)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *TodoHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+func (h *TodoHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeError(w, r, apperr.Validation("id", "Invalid todo ID"))
+		return
+	}
+
+	todo, err := h.service.RestoreTodo(requestUserID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setETag(w, todo.Version)
+	h.writeJSONResponse(w, http.StatusOK, todo)
 }
 
-func (h *TodoHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+func (h *TodoHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
-}
-
-func containsNotFound(message string) bool {
-	return len(message) > 0 && (message[len(message)-9:] == "not found" ||
-		message[:len("todo with id")] == "todo with id")
+	json.NewEncoder(w).Encode(data)
 }
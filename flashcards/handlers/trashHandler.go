@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type TrashHandler struct {
+	service *services.TrashService
+}
+
+func NewTrashHandler(service *services.TrashService) *TrashHandler {
+	return &TrashHandler{service: service}
+}
+
+func (h *TrashHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/trash", h.GetTrash).Methods("GET")
+}
+
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	trash, err := h.service.GetTrash(requestUserID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, trash)
+}
+
+func (h *TrashHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// maxAnkiCollectionSize bounds the unpacked collection of an Anki package,
+// which is far larger than the package itself.
+const maxAnkiCollectionSize = 256 << 20
+
+// ankiCollections are the names of the collection in an Anki package, from
+// the newest to the oldest format that can be read.
+var ankiCollections = []string{"collection.anki21", "collection.anki2"}
+
+// parseAnkiPackage reads the notes of an Anki package (.apkg), a zip file
+// holding the SQLite database of a collection. The fields of a note, such as
+// the front and back of a card, are joined by blank lines.
+func parseAnkiPackage(data []byte) ([]Item, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an Anki package: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	// Packages of Anki 23.10 and later hold a compressed collection, with
+	// a collection.anki2 that only asks to update Anki.
+	if files["collection.anki21b"] != nil && files["collection.anki21"] == nil {
+		return nil, errors.New(`Anki package is in the format of Anki 23.10 or later, export it again with "Support older Anki versions"`)
+	}
+
+	for _, name := range ankiCollections {
+		if file := files[name]; file != nil {
+			return readAnkiCollection(file)
+		}
+	}
+	return nil, errors.New("Anki package holds no collection")
+}
+
+// readAnkiCollection unpacks the collection to a temporary file, which
+// SQLite needs to open it.
+func readAnkiCollection(file *zip.File) ([]Item, error) {
+	packed, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack Anki collection: %w", err)
+	}
+	defer packed.Close()
+
+	tmp, err := os.CreateTemp("", "anki-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack Anki collection: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(packed, maxAnkiCollectionSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack Anki collection: %w", err)
+	}
+	if n > maxAnkiCollectionSize {
+		return nil, fmt.Errorf("Anki collection exceeds %d MB", maxAnkiCollectionSize>>20)
+	}
+
+	collection, err := sql.Open("sqlite", tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Anki collection: %w", err)
+	}
+	defer collection.Close()
+
+	return readAnkiNotes(collection)
+}
+
+// readAnkiNotes reads the notes of a collection in the order they were
+// added. Fields are separated by 0x1f and tags by spaces.
+func readAnkiNotes(collection *sql.DB) ([]Item, error) {
+	rows, err := collection.Query("SELECT flds, tags FROM notes ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("not an Anki collection: %w", err)
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var fields, tags string
+		if err := rows.Scan(&fields, &tags); err != nil {
+			return nil, fmt.Errorf("failed to read Anki note: %w", err)
+		}
+
+		var parts []string
+		for _, value := range strings.Split(fields, "\x1f") {
+			if value = strings.TrimSpace(htmlToText(value)); value != "" {
+				parts = append(parts, value)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		items = append(items, Item{
+			Content: strings.Join(parts, "\n\n"),
+			Tags:    strings.Fields(tags),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Anki notes: %w", err)
+	}
+
+	return items, nil
+}
+
+var (
+	ankiClozes     = regexp.MustCompile(`\{\{c\d+::(.*?)(?:::.*?)?\}\}`)
+	ankiMedia      = regexp.MustCompile(`\[sound:[^\]]*\]|(?i)<img[^>]*>`)
+	htmlLineBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</(?:div|p|li|tr|h[1-6])>`)
+	htmlTags       = regexp.MustCompile(`<[^>]*>`)
+	blankLines     = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText turns an Anki field, which is HTML, into plain text. Clozes
+// keep their answer, and sounds and images are dropped.
+func htmlToText(value string) string {
+	value = ankiClozes.ReplaceAllString(value, "$1")
+	value = ankiMedia.ReplaceAllString(value, "")
+	value = htmlLineBreaks.ReplaceAllString(value, "\n")
+	value = htmlTags.ReplaceAllString(value, "")
+	value = strings.ReplaceAll(html.UnescapeString(value), "\u00a0", " ")
+
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
@@ -0,0 +1,183 @@
+// Package importer reads notes from uploaded files: Markdown split by
+// headings, plain text split by a delimiter line, CSV and TSV tables, and
+// Anki exports, both packages (.apkg) and notes in plain text.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats of the files Parse reads.
+const (
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatAnki     = "apkg"
+)
+
+// Defaults of Options.
+const (
+	DefaultDelimiter    = "---"
+	DefaultHeadingLevel = 2
+)
+
+var formatsByExtension = map[string]string{
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".txt":      FormatText,
+	".text":     FormatText,
+	".csv":      FormatCSV,
+	".tsv":      FormatTSV,
+	".tab":      FormatTSV,
+	".apkg":     FormatAnki,
+}
+
+// Formats lists the formats Parse reads.
+var Formats = []string{FormatMarkdown, FormatText, FormatCSV, FormatTSV, FormatAnki}
+
+// ValidFormat reports whether format is one of Formats.
+func ValidFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// File is an uploaded file.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Options control how files are split into notes.
+type Options struct {
+	// Format is the format of every file. When empty, it is taken from the
+	// extension of each file.
+	Format string
+	// Delimiter is the line that separates notes in plain text.
+	Delimiter string
+	// HeadingLevel is the deepest Markdown heading that starts a new note,
+	// so 2 splits at "#" and "##" but not at "###".
+	HeadingLevel int
+}
+
+// Item is a note read from a file.
+type Item struct {
+	// Index is the position of the note in its file, from 1.
+	Index int
+	// Line is the line the note starts on, 0 in an Anki package.
+	Line    int
+	Content string
+	Tags    []string
+	// Err tells why the note could not be read, such as a malformed CSV
+	// row. The rest of the file is still read.
+	Err error
+}
+
+// Parse reads the notes of file. It fails when the file as a whole cannot be
+// read, such as an unknown format or a broken Anki package.
+func Parse(file File, opts Options) ([]Item, error) {
+	if opts.Delimiter == "" {
+		opts.Delimiter = DefaultDelimiter
+	}
+	if opts.HeadingLevel == 0 {
+		opts.HeadingLevel = DefaultHeadingLevel
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = formatsByExtension[strings.ToLower(path.Ext(file.Name))]
+		if format == "" {
+			return nil, fmt.Errorf("unknown file type %q, set the format", path.Ext(file.Name))
+		}
+	}
+
+	var items []Item
+	var err error
+	if format == FormatAnki {
+		items, err = parseAnkiPackage(file.Data)
+	} else {
+		items, err = parseText(format, file.Data, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Index = i + 1
+	}
+	return items, nil
+}
+
+func parseText(format string, data []byte, opts Options) ([]Item, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("file is not UTF-8 text")
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	// Anki exports notes in plain text as .txt, with headers that tell
+	// them apart from other text.
+	if header, ok := parseAnkiHeader(text); ok {
+		return parseTable(header.body, header.separator, &header)
+	}
+
+	switch format {
+	case FormatMarkdown:
+		return parseMarkdown(text, opts.HeadingLevel), nil
+	case FormatText:
+		return splitText(text, opts.Delimiter), nil
+	case FormatCSV:
+		return parseTable(text, ',', nil)
+	case FormatTSV:
+		return parseTable(text, '\t', nil)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// splitText splits text into notes at lines that consist of delimiter.
+func splitText(text, delimiter string) []Item {
+	var notes noteBuilder
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == delimiter {
+			notes.flush()
+			continue
+		}
+		notes.addLine(i+1, line)
+	}
+	notes.flush()
+	return notes.items
+}
+
+// noteBuilder collects the lines of notes in a text file.
+type noteBuilder struct {
+	items []Item
+	lines []string
+	start int
+}
+
+// addLine adds a line to the current note. Blank lines before a note are
+// dropped, so that it starts on its first line with text.
+func (b *noteBuilder) addLine(number int, line string) {
+	if len(b.lines) == 0 {
+		if strings.TrimSpace(line) == "" {
+			return
+		}
+		b.start = number
+	}
+	b.lines = append(b.lines, line)
+}
+
+// flush ends the current note.
+func (b *noteBuilder) flush() {
+	content := strings.TrimSpace(strings.Join(b.lines, "\n"))
+	if content != "" {
+		b.items = append(b.items, Item{Line: b.start, Content: content})
+	}
+	b.lines = nil
+}
@@ -0,0 +1,93 @@
+package importer
+
+import "strings"
+
+// parseMarkdown splits text into notes at headings down to level, each note
+// starting with its heading. Text before the first heading is a note of its
+// own, except for YAML front matter, which is dropped. Headings in fenced
+// code blocks do not count.
+func parseMarkdown(text string, level int) []Item {
+	lines := strings.Split(text, "\n")
+	first := frontMatterEnd(lines)
+
+	var notes noteBuilder
+	fence := ""
+	for i := first; i < len(lines); i++ {
+		line := lines[i]
+		marker := fenceMarker(line)
+
+		switch {
+		case fence != "":
+			if marker != "" && marker[0] == fence[0] && len(marker) >= len(fence) &&
+				strings.TrimSpace(strings.TrimLeft(line, " "+marker[:1])) == "" {
+				fence = ""
+			}
+		case marker != "":
+			fence = marker
+		default:
+			if n := headingLevel(line); n > 0 && n <= level {
+				notes.flush()
+			}
+		}
+
+		notes.addLine(i+1, line)
+	}
+	notes.flush()
+	return notes.items
+}
+
+// frontMatterEnd returns the index of the first line after the YAML front
+// matter at the top of lines, or 0 when there is none.
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimSpace(lines[i]); line == "---" || line == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// fenceMarker returns the ``` or ~~~ run that opens or closes a fenced code
+// block on line, or "" if line is no fence.
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+	if trimmed[0] != '`' && trimmed[0] != '~' {
+		return ""
+	}
+
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// headingLevel returns the level of the ATX heading on line, or 0 if line is
+// no heading.
+func headingLevel(line string) int {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0
+	}
+
+	n := 0
+	for n < len(trimmed) && trimmed[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 {
+		return 0
+	}
+	if n < len(trimmed) && trimmed[n] != ' ' && trimmed[n] != '\t' {
+		return 0
+	}
+	return n
+}
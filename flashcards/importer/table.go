@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ankiHeader holds the "#key:value" lines at the top of notes that Anki
+// exported in plain text.
+type ankiHeader struct {
+	separator rune
+	html      bool
+	// tagsColumn is the column with the tags of a note, -1 when there is
+	// none, and tags are added to every note.
+	tagsColumn int
+	tags       []string
+	// skip holds columns that are not part of a note, such as its deck.
+	skip map[int]bool
+
+	body  string
+	lines int
+}
+
+var ankiSeparators = map[string]rune{
+	"comma":     ',',
+	"semicolon": ';',
+	"tab":       '\t',
+	"space":     ' ',
+	"pipe":      '|',
+	"colon":     ':',
+}
+
+// parseAnkiHeader reads the header of an Anki export in plain text. It
+// reports false if text does not start with one.
+func parseAnkiHeader(text string) (ankiHeader, bool) {
+	header := ankiHeader{separator: '\t', tagsColumn: -1, skip: make(map[int]bool)}
+
+	rest := text
+	for strings.HasPrefix(rest, "#") {
+		line, next, _ := strings.Cut(rest, "\n")
+		key, value, ok := strings.Cut(line[1:], ":")
+		if !ok {
+			break
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(key) {
+		case "separator":
+			if separator, ok := ankiSeparators[strings.ToLower(value)]; ok {
+				header.separator = separator
+			} else if r, size := utf8.DecodeRuneInString(value); size > 0 && size == len(value) {
+				header.separator = r
+			}
+		case "html":
+			header.html = value == "true"
+		case "tags":
+			header.tags = strings.Fields(value)
+		case "tags column":
+			header.tagsColumn = ankiColumn(value)
+		case "guid column", "notetype column", "deck column":
+			header.skip[ankiColumn(value)] = true
+		case "notetype", "deck", "columns", "if matches", "match scope":
+		default:
+			// A line like "# Title: subtitle" starts Markdown, not a header.
+			return ankiHeader{}, false
+		}
+
+		header.lines++
+		rest = next
+	}
+
+	header.body = rest
+	return header, header.lines > 0
+}
+
+// ankiColumn turns the 1-based column of an Anki header into an index, -1
+// when it is not a column.
+func ankiColumn(value string) int {
+	column, err := strconv.Atoi(value)
+	if err != nil || column < 1 {
+		return -1
+	}
+	return column - 1
+}
+
+// headerNames are the column names that make the first row of a table a
+// header, when all of its fields are one of them.
+var headerNames = map[string]bool{
+	"content": true, "tags": true, "front": true, "back": true,
+	"question": true, "answer": true, "term": true, "definition": true,
+	"title": true, "text": true, "note": true, "notes": true,
+}
+
+// tableColumns are the columns of a table with a header row. content is -1
+// when the header has no "content" column.
+type tableColumns struct {
+	content int
+	tags    int
+}
+
+// parseTable reads a note from every row of a CSV or TSV table. The first
+// row is a header when every field of it is a column name such as "content",
+// "front" or "tags". With a "content" column only that column is the note,
+// otherwise the fields of a row, such as the front and back of a card, are
+// joined by blank lines, leaving out the "tags" column. Rows that are not
+// well-formed, such as a field with an unterminated quote, are reported as
+// failed. anki is the header of an Anki export, or nil.
+func parseTable(text string, separator rune, anki *ankiHeader) ([]Item, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+
+	offset := 0
+	if anki != nil {
+		offset = anki.lines
+	}
+
+	var items []Item
+	var columns *tableColumns
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			items = append(items, Item{Line: parseErr.StartLine + offset, Err: fmt.Errorf("malformed row: %v", parseErr.Err)})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table: %w", err)
+		}
+
+		if first && anki == nil {
+			if columns = headerColumns(record); columns != nil {
+				continue
+			}
+		}
+
+		line, _ := reader.FieldPos(0)
+		item := Item{Line: line + offset}
+		switch {
+		case columns != nil:
+			if columns.content >= 0 {
+				item.Content = strings.TrimSpace(field(record, columns.content))
+			} else {
+				item.Content = joinFields(record, func(column int) bool { return column == columns.tags }, false)
+			}
+			item.Tags = strings.FieldsFunc(field(record, columns.tags), func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})
+		case anki != nil:
+			item.Content = joinFields(record, func(column int) bool {
+				return anki.skip[column] || column == anki.tagsColumn
+			}, anki.html)
+			item.Tags = append(strings.Fields(field(record, anki.tagsColumn)), anki.tags...)
+		default:
+			item.Content = joinFields(record, nil, false)
+		}
+
+		if item.Content != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// headerColumns returns the columns named by record, or nil if one of its
+// fields is no column name and so it is no header.
+func headerColumns(record []string) *tableColumns {
+	columns := &tableColumns{content: -1, tags: -1}
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !headerNames[name] {
+			return nil
+		}
+		switch name {
+		case "content":
+			columns.content = i
+		case "tags":
+			columns.tags = i
+		}
+	}
+	return columns
+}
+
+func field(record []string, column int) string {
+	if column < 0 || column >= len(record) {
+		return ""
+	}
+	return record[column]
+}
+
+// joinFields joins the fields of a row that are not empty by blank lines,
+// leaving out the columns that skip reports, which may be nil. html turns
+// the fields from HTML into text.
+func joinFields(record []string, skip func(column int) bool, html bool) string {
+	var fields []string
+	for i, value := range record {
+		if skip != nil && skip(i) {
+			continue
+		}
+		if html {
+			value = htmlToText(value)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			fields = append(fields, value)
+		}
+	}
+	return strings.Join(fields, "\n\n")
+}
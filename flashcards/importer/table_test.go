@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	// want lists the items of a file as "line: content [tags]", or
+	// "line: error" for an item that failed.
+	tests := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{
+			name: "rows without a header",
+			file: "Magna Carta,1215\nBill of Rights,1689\n",
+			want: []string{"1: Magna Carta\n\n1215 []", "2: Bill of Rights\n\n1689 []"},
+		},
+		{
+			name: "content and tags header",
+			file: "tags,content\n\"history law\",Magna Carta\n",
+			want: []string{"2: Magna Carta [history law]"},
+		},
+		{
+			name: "card header",
+			file: "Front,Back,Tags\nMagna Carta,1215,history\n",
+			want: []string{"2: Magna Carta\n\n1215 [history]"},
+		},
+		{
+			name: "first row that is not all column names",
+			file: "front,1215\n",
+			want: []string{"1: front\n\n1215 []"},
+		},
+		{
+			name: "bare quote",
+			file: "good,row\nba\"d,row\nalso,good\n",
+			want: []string{"1: good\n\nrow []", "2: error", "3: also\n\ngood []"},
+		},
+		{
+			name: "unterminated quote",
+			file: "good,row\n\"bad,\n",
+			want: []string{"1: good\n\nrow []", "2: error"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			items, err := Parse(File{Name: "notes.csv", Data: []byte(tc.file)}, Options{})
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			got := make([]string, len(items))
+			for i, item := range items {
+				if item.Err != nil {
+					got[i] = fmt.Sprintf("%d: error", item.Line)
+					continue
+				}
+				got[i] = fmt.Sprintf("%d: %s [%s]", item.Line, item.Content, strings.Join(item.Tags, " "))
+			}
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("got items %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
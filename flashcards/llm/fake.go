@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"flashcards/search"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// FakeRule answers every prompt that contains Contains with Response.
+type FakeRule struct {
+	Contains string
+	Response string
+}
+
+// defaultFakeRules give well-formed answers to the JSON requests made by the
+// quiz service, so the fake provider can drive the whole API offline.
+var defaultFakeRules = []FakeRule{
+	{
+		Contains: `"expected_answer"`,
+		Response: `{"correct": true, "score": 1, "explanation": "The answer matches the notes.", "expected_answer": "The answer from the notes."}`,
+	},
+	{
+		Contains: `{"flashcards":`,
+		Response: `{"flashcards": [{"front": "What is this note about?", "back": "The content of the note."}]}`,
+	},
+}
+
+const defaultFakeResponse = "This is a fake response from the offline model."
+
+const fakeEmbeddingDimensions = 256
+
+type fakeResponse struct {
+	text string
+	err  error
+}
+
+// FakeModel is a deterministic llms.Model that never touches the network.
+// Each call is answered by the first queued response, then by the first
+// matching rule, then by the built-in rules and finally by a fixed fallback.
+// Streaming calls receive the response word by word.
+type FakeModel struct {
+	mu       sync.Mutex
+	queue    []fakeResponse
+	rules    []FakeRule
+	fallback string
+	prompts  []string
+}
+
+func NewFakeModel(responses ...string) *FakeModel {
+	model := &FakeModel{fallback: defaultFakeResponse}
+	for _, response := range responses {
+		model.queue = append(model.queue, fakeResponse{text: response})
+	}
+	return model
+}
+
+// QueueResponse adds a response that is returned once, before any rule applies.
+func (m *FakeModel) QueueResponse(response string) *FakeModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queue = append(m.queue, fakeResponse{text: response})
+	return m
+}
+
+// QueueError makes the next unanswered call fail with err.
+func (m *FakeModel) QueueError(err error) *FakeModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queue = append(m.queue, fakeResponse{err: err})
+	return m
+}
+
+func (m *FakeModel) AddRule(contains, response string) *FakeModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = append(m.rules, FakeRule{Contains: contains, Response: response})
+	return m
+}
+
+func (m *FakeModel) SetFallback(response string) *FakeModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fallback = response
+	return m
+}
+
+// Prompts returns every prompt the model received, in order.
+func (m *FakeModel) Prompts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string{}, m.prompts...)
+}
+
+func (m *FakeModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	response, err := m.respond(promptText(messages))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.StreamingFunc != nil {
+		for _, chunk := range strings.SplitAfter(response, " ") {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := opts.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: response, StopReason: "stop"}},
+	}, nil
+}
+
+func (m *FakeModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// CreateEmbedding returns hashed bag-of-words vectors, so texts sharing words
+// are similar and the fake provider can drive semantic search offline.
+func (m *FakeModel) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		vector := make([]float32, fakeEmbeddingDimensions)
+		for _, word := range search.Tokenize(text) {
+			hash := fnv.New32a()
+			hash.Write([]byte(word))
+			vector[hash.Sum32()%fakeEmbeddingDimensions]++
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (m *FakeModel) respond(prompt string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prompts = append(m.prompts, prompt)
+
+	if len(m.queue) > 0 {
+		next := m.queue[0]
+		m.queue = m.queue[1:]
+		return next.text, next.err
+	}
+
+	for _, rules := range [][]FakeRule{m.rules, defaultFakeRules} {
+		for _, rule := range rules {
+			if strings.Contains(prompt, rule.Contains) {
+				return rule.Response, nil
+			}
+		}
+	}
+
+	if m.fallback == "" {
+		return "", errors.New("fake model has no response configured")
+	}
+	return m.fallback, nil
+}
+
+func promptText(messages []llms.MessageContent) string {
+	var prompt strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompt.WriteString(text.Text)
+			}
+		}
+	}
+	return prompt.String()
+}
@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+	ProviderLlamaCpp  = "llamacpp"
+	ProviderFake      = "fake"
+)
+
+// ProviderConfig holds the settings shared by all providers. Empty fields
+// fall back to the provider's defaults.
+type ProviderConfig struct {
+	Model          string
+	EmbeddingModel string
+	BaseURL        string
+	APIKey         string
+}
+
+type ProviderFactory func(cfg ProviderConfig) (llms.Model, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		ProviderOpenAI:    newOpenAI,
+		ProviderAnthropic: newAnthropic,
+		ProviderOllama:    newOllama,
+		ProviderLlamaCpp:  newLlamaCpp,
+		ProviderFake: func(ProviderConfig) (llms.Model, error) {
+			return NewFakeModel(), nil
+		},
+	}
+)
+
+// Register adds or replaces the factory used for the named provider.
+func Register(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	providers[name] = factory
+}
+
+func New(name string, cfg ProviderConfig) (llms.Model, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported LLM provider %q, available providers: %v", name, Providers())
+	}
+
+	model, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s provider: %w", name, err)
+	}
+
+	return model, nil
+}
+
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newOpenAI(cfg ProviderConfig) (llms.Model, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("an API key is required")
+	}
+
+	opts := []openai.Option{
+		openai.WithModel(withDefault(cfg.Model, "gpt-4o-mini")),
+		openai.WithEmbeddingModel(withDefault(cfg.EmbeddingModel, "text-embedding-3-small")),
+		openai.WithToken(cfg.APIKey),
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+	}
+
+	return openai.New(opts...)
+}
+
+func newAnthropic(cfg ProviderConfig) (llms.Model, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("an API key is required")
+	}
+
+	opts := []anthropic.Option{
+		anthropic.WithModel(withDefault(cfg.Model, "claude-3-5-haiku-latest")),
+		anthropic.WithToken(cfg.APIKey),
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, anthropic.WithBaseURL(cfg.BaseURL))
+	}
+
+	return anthropic.New(opts...)
+}
+
+func newOllama(cfg ProviderConfig) (llms.Model, error) {
+	opts := []ollama.Option{
+		ollama.WithModel(withDefault(cfg.Model, "llama3.1")),
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, ollama.WithServerURL(cfg.BaseURL))
+	}
+
+	return ollama.New(opts...)
+}
+
+// newLlamaCpp talks to a llama.cpp server through its OpenAI compatible API.
+// The server ignores the token, but the OpenAI client refuses to start without one.
+func newLlamaCpp(cfg ProviderConfig) (llms.Model, error) {
+	return openai.New(
+		openai.WithModel(withDefault(cfg.Model, "local")),
+		openai.WithEmbeddingModel(withDefault(cfg.EmbeddingModel, "local")),
+		openai.WithBaseURL(withDefault(cfg.BaseURL, "http://localhost:8081/v1")),
+		openai.WithToken(withDefault(cfg.APIKey, "llamacpp")),
+	)
+}
+
+// NewEmbedder returns an embedder backed by model, or an error when the
+// provider behind model cannot compute embeddings.
+func NewEmbedder(model llms.Model) (embeddings.Embedder, error) {
+	client, ok := model.(embeddings.EmbedderClient)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support embeddings", model)
+	}
+
+	return embeddings.NewEmbedder(client)
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"log"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const DefaultTokenEncoding = "cl100k_base"
+
+// TokenCounter measures text in tiktoken tokens. tiktoken-go downloads the
+// encoding on first use unless it is cached in TIKTOKEN_CACHE_DIR, so when
+// the encoding cannot be loaded the counter falls back to an estimate of
+// four characters per token.
+type TokenCounter struct {
+	encoding string
+	once     sync.Once
+	tiktoken *tiktoken.Tiktoken
+}
+
+func NewTokenCounter(encoding string) *TokenCounter {
+	return &TokenCounter{encoding: withDefault(encoding, DefaultTokenEncoding)}
+}
+
+func (c *TokenCounter) Count(text string) int {
+	c.once.Do(func() {
+		encoder, err := tiktoken.GetEncoding(c.encoding)
+		if err != nil {
+			log.Printf("[ERROR] Failed to load %s token encoding, estimating token counts instead: %v", c.encoding, err)
+			return
+		}
+		c.tiktoken = encoder
+	})
+
+	if c.tiktoken == nil {
+		return (utf8.RuneCountInString(text) + 3) / 4
+	}
+	return len(c.tiktoken.Encode(text, nil, nil))
+}
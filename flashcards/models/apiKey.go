@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential for scripts and CI jobs. Only a hash of
+// the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"-" db:"userId"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt" db:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt" db:"createdAt"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreatedAPIKey is returned once, when the key is created. The key itself
+// cannot be retrieved again.
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type Deck struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	ParentID    *int      `json:"parentId" db:"parentId"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type CreateDeckRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ParentID    *int   `json:"parentId,omitempty"`
+}
+
+// UpdateDeckRequest changes the given fields of a deck. A ParentID of 0
+// moves the deck to the top level.
+type UpdateDeckRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *int    `json:"parentId,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type Flashcard struct {
+	ID        int       `json:"id" db:"id"`
+	Front     string    `json:"front" db:"front"`
+	Back      string    `json:"back" db:"back"`
+	NoteID    *int      `json:"noteId" db:"noteId"`
+	Tags      []string  `json:"tags" db:"tags"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type CreateFlashcardRequest struct {
+	Front  string   `json:"front"`
+	Back   string   `json:"back"`
+	NoteID *int     `json:"noteId,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+type UpdateFlashcardRequest struct {
+	Front *string   `json:"front,omitempty"`
+	Back  *string   `json:"back,omitempty"`
+	Tags  *[]string `json:"tags,omitempty"`
+}
+
+type GenerateFlashcardsRequest struct {
+	MaxCards int      `json:"maxCards,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// ListOptions selects a page of a list. Zero values mean no filter, sorting
+// by createdAt descending and, for Limit, no limit at all.
+type ListOptions struct {
+	Limit     int
+	After     *Cursor
+	SortBy    string
+	SortOrder string
+
+	// Query matches a case-insensitive substring of the text fields.
+	Query string
+	// IDs restricts the list to these ids when it is not nil.
+	IDs []int
+
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+}
+
+type NoteListOptions struct {
+	ListOptions
+}
+
+type TodoListOptions struct {
+	ListOptions
+	Completed *bool
+	Priority  string
+	// DueAfter and DueBefore keep the todos due in [DueAfter, DueBefore).
+	// Todos without a due date are left out by either of them.
+	DueAfter  *time.Time
+	DueBefore *time.Time
+}
+
+// Due filters of a todo list.
+const (
+	DueOverdue = "overdue"
+	DueToday   = "today"
+)
+
+// DueFilter keeps the todos that are overdue, which are never completed, or
+// due today in Location. An empty filter keeps everything.
+type DueFilter struct {
+	Due      string
+	Location *time.Location
+}
+
+// TagFilter keeps items tagged with any of Tags, or with all of them when
+// MatchAll is set. An empty filter keeps everything.
+type TagFilter struct {
+	Tags     []string
+	MatchAll bool
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// Cursor is the sort key of the last item of a page. Pages are keyed on the
+// sort timestamp and the id, so items created or deleted between requests do
+// not shift later pages.
+type Cursor struct {
+	SortBy    string    `json:"s"`
+	SortOrder string    `json:"o"`
+	Time      time.Time `json:"t"`
+	ID        int       `json:"i"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &cursor, nil
+}
@@ -3,16 +3,21 @@ package models
 import "time"
 
 type Note struct {
-	ID        int       `json:"id" db:"id"`
-	Content   string    `json:"content" db:"content"`
-	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
+	ID        int        `json:"id" db:"id"`
+	Content   string     `json:"content" db:"content"`
+	Tags      []string   `json:"tags"`
+	Version   int        `json:"version" db:"version"`
+	CreatedAt time.Time  `json:"createdAt" db:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deletedAt"`
 }
 
 type CreateNoteRequest struct {
-	Content string `json:"content"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
 }
 
 type UpdateNoteRequest struct {
-	Content *string `json:"content,omitempty"`
+	Content *string   `json:"content,omitempty"`
+	Tags    *[]string `json:"tags,omitempty"`
 }
\ No newline at end of file
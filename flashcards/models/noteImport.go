@@ -0,0 +1,40 @@
+package models
+
+// NoteImportOptions control how uploaded files become notes.
+type NoteImportOptions struct {
+	// Format is the format of every file, taken from the file extensions
+	// when empty.
+	Format       string
+	Delimiter    string
+	HeadingLevel int
+	// Tags are added to every imported note.
+	Tags []string
+	// Atomic saves all notes in a single transaction instead of in chunks.
+	Atomic bool
+}
+
+// Statuses of a note in an import.
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusDuplicate = "duplicate"
+	ImportStatusFailed    = "failed"
+)
+
+// NoteImportItem is the outcome for one note of an import. A file that
+// could not be read at all has a single failed item without an index.
+type NoteImportItem struct {
+	File   string `json:"file"`
+	Index  int    `json:"index,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Status string `json:"status"`
+	NoteID int    `json:"noteId,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NoteImportReport lists what became of every note in an import.
+type NoteImportReport struct {
+	Created    int               `json:"created"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Items      []*NoteImportItem `json:"items"`
+}
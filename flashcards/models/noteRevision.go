@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// NoteRevision is the content of a note as it was saved, numbered from 1
+// for the note as it was created. Revisions are never changed afterwards.
+type NoteRevision struct {
+	NoteID    int       `json:"noteId" db:"noteId"`
+	Revision  int       `json:"revision" db:"revision"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+}
+
+// Diff modes, comparing revisions line by line or word by word.
+const (
+	DiffModeLine = "line"
+	DiffModeWord = "word"
+)
+
+// NoteDiff lists the changes from one revision of a note to another. The
+// text of the equal and deleted chunks makes up the from revision, the
+// text of the equal and inserted chunks the to revision.
+type NoteDiff struct {
+	NoteID     int         `json:"noteId"`
+	From       int         `json:"from"`
+	To         int         `json:"to"`
+	Mode       string      `json:"mode"`
+	Insertions int         `json:"insertions"`
+	Deletions  int         `json:"deletions"`
+	Chunks     []DiffChunk `json:"chunks"`
+}
+
+// DiffChunk is a run of text that is equal in both revisions, or only in
+// the from revision (delete) or the to revision (insert).
+type DiffChunk struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
@@ -1,6 +1,38 @@
 package models
 
+import "time"
+
+const (
+	QuizSessionActive    = "active"
+	QuizSessionCompleted = "completed"
+)
+
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
\ No newline at end of file
+	Role    string       `json:"role"`
+	Content string       `json:"content"`
+	Grade   *AnswerGrade `json:"grade,omitempty"`
+}
+
+// AnswerGrade is the machine-readable verdict on a user's answer.
+type AnswerGrade struct {
+	Correct        bool    `json:"correct"`
+	Score          float64 `json:"score"`
+	Explanation    string  `json:"explanation"`
+	ExpectedAnswer string  `json:"expected_answer"`
+}
+
+type QuizScore struct {
+	Answered int     `json:"answered"`
+	Correct  int     `json:"correct"`
+	Average  float64 `json:"average"`
+}
+
+type QuizSession struct {
+	ID        string     `json:"id" db:"id"`
+	NoteIDs   []int      `json:"note_ids" db:"noteIds"`
+	Messages  []Message  `json:"messages"`
+	Status    string     `json:"status" db:"status"`
+	Score     *QuizScore `json:"score,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" db:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updatedAt"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	ReviewItemNote      = "note"
+	ReviewItemFlashcard = "flashcard"
+)
+
+const (
+	GradeAgain = "again"
+	GradeHard  = "hard"
+	GradeGood  = "good"
+	GradeEasy  = "easy"
+)
+
+type ReviewState struct {
+	ID             int        `json:"id" db:"id"`
+	ItemType       string     `json:"itemType" db:"itemType"`
+	ItemID         int        `json:"itemId" db:"itemId"`
+	Algorithm      string     `json:"algorithm" db:"algorithm"`
+	Repetitions    int        `json:"repetitions" db:"repetitions"`
+	Lapses         int        `json:"lapses" db:"lapses"`
+	EaseFactor     float64    `json:"easeFactor" db:"easeFactor"`
+	IntervalDays   int        `json:"intervalDays" db:"intervalDays"`
+	Stability      float64    `json:"stability" db:"stability"`
+	Difficulty     float64    `json:"difficulty" db:"difficulty"`
+	LastGrade      string     `json:"lastGrade" db:"lastGrade"`
+	DueAt          time.Time  `json:"dueAt" db:"dueAt"`
+	LastReviewedAt *time.Time `json:"lastReviewedAt" db:"lastReviewedAt"`
+	CreatedAt      time.Time  `json:"createdAt" db:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updatedAt"`
+}
+
+type ReviewLog struct {
+	ID           int       `json:"id" db:"id"`
+	StateID      int       `json:"stateId" db:"stateId"`
+	Grade        string    `json:"grade" db:"grade"`
+	IntervalDays int       `json:"intervalDays" db:"intervalDays"`
+	ReviewedAt   time.Time `json:"reviewedAt" db:"reviewedAt"`
+}
+
+type GradeReviewRequest struct {
+	Grade    string `json:"grade"`
+	ItemType string `json:"itemType,omitempty"`
+}
+
+type DueReviewItem struct {
+	ItemType  string       `json:"itemType"`
+	ItemID    int          `json:"itemId"`
+	New       bool         `json:"new"`
+	State     *ReviewState `json:"state,omitempty"`
+	Note      *Note        `json:"note,omitempty"`
+	Flashcard *Flashcard   `json:"flashcard,omitempty"`
+}
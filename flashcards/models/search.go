@@ -0,0 +1,22 @@
+package models
+
+const (
+	SearchModeText     = "text"
+	SearchModeSemantic = "semantic"
+)
+
+// NoteSearchResult is a note matching a search query. Snippet is an excerpt
+// of the note with the matching words wrapped in <mark> tags. Score is the
+// full-text rank in text mode and the cosine similarity in semantic mode,
+// higher is better in both.
+type NoteSearchResult struct {
+	Note    *Note   `json:"note"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type NoteSearchResponse struct {
+	Query   string              `json:"query"`
+	Mode    string              `json:"mode"`
+	Results []*NoteSearchResult `json:"results"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// Tag is a tag together with the number of notes and todos that use it.
+type Tag struct {
+	Name      string `json:"name"`
+	NoteCount int    `json:"noteCount"`
+	TodoCount int    `json:"todoCount"`
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name"`
+}
+
+// MergeTagsRequest moves every note and todo tagged with one of Sources to
+// Target and deletes the source tags.
+type MergeTagsRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
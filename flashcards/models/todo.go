@@ -2,22 +2,53 @@ package models
 
 import "time"
 
+// Priorities of a todo.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// Priorities lists the priorities of a todo, from the lowest.
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh}
+
 type Todo struct {
-	ID          int       `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	Completed   bool      `json:"completed" db:"completed"`
-	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt" db:"updatedAt"`
+	ID          int        `json:"id" db:"id"`
+	Title       string     `json:"title" db:"title"`
+	Description string     `json:"description" db:"description"`
+	Completed   bool       `json:"completed" db:"completed"`
+	DueAt       *time.Time `json:"dueAt,omitempty" db:"dueAt"`
+	Priority    string     `json:"priority" db:"priority"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" db:"completedAt"`
+	// Recurrence is an RRULE such as "FREQ=WEEKLY;BYDAY=MO", repeating
+	// from DueAt. Completing the todo creates the next one.
+	Recurrence string     `json:"recurrence,omitempty" db:"recurrence"`
+	Tags       []string   `json:"tags"`
+	Version    int        `json:"version" db:"version"`
+	CreatedAt  time.Time  `json:"createdAt" db:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updatedAt"`
+	DeletedAt  *time.Time `json:"deletedAt,omitempty" db:"deletedAt"`
 }
 
+// CreateTodoRequest creates a todo. DueAt is an RFC 3339 time or a date,
+// which is midnight UTC.
 type CreateTodoRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	DueAt       string   `json:"dueAt,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Recurrence  string   `json:"recurrence,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
+// UpdateTodoRequest changes the fields that are given. An empty dueAt or
+// recurrence clears it.
 type UpdateTodoRequest struct {
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Completed   *bool   `json:"completed,omitempty"`
+	Title       *string   `json:"title,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Completed   *bool     `json:"completed,omitempty"`
+	DueAt       *string   `json:"dueAt,omitempty"`
+	Priority    *string   `json:"priority,omitempty"`
+	Recurrence  *string   `json:"recurrence,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
 }
\ No newline at end of file
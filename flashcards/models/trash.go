@@ -0,0 +1,9 @@
+package models
+
+// Trash holds the soft deleted notes and todos of a user, most recently
+// deleted first. They are purged for good once the retention period after
+// their deletion has passed.
+type Trash struct {
+	Notes []*Note `json:"notes"`
+	Todos []*Todo `json:"todos"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LLMQuota is how much of their LLM token quota a user has used in the
+// current period. Limit is zero when there is no quota.
+type LLMQuota struct {
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resetsAt"`
+}
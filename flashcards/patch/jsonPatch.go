@@ -0,0 +1,344 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotApplicable is returned by Apply when a JSON patch does not fit the
+// document: a path does not exist or a test operation fails.
+var ErrNotApplicable = errors.New("patch cannot be applied")
+
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+
+	path  []string
+	from  []string
+	value any
+}
+
+type jsonPatch []operation
+
+// ParseJSONPatch parses an RFC 6902 JSON patch: a list of add, remove,
+// replace, move, copy and test operations on JSON pointers, applied in
+// order.
+func ParseJSONPatch(data []byte) (Patch, error) {
+	var ops jsonPatch
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+
+	for i := range ops {
+		if err := ops[i].parse(); err != nil {
+			return nil, fmt.Errorf("invalid JSON patch operation %d: %w", i, err)
+		}
+	}
+	return ops, nil
+}
+
+func (op *operation) parse() error {
+	switch op.Op {
+	case "add", "remove", "replace", "move", "copy", "test":
+	case "":
+		return errors.New("op is required")
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+
+	if op.Path == nil {
+		return errors.New("path is required")
+	}
+	var err error
+	if op.path, err = parsePointer(*op.Path); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		// A null value is a value, only a missing one is an error.
+		if len(op.Value) == 0 {
+			return fmt.Errorf("%s needs a value", op.Op)
+		}
+		if op.value, err = decode(op.Value); err != nil {
+			return fmt.Errorf("invalid value: %w", err)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return fmt.Errorf("%s needs a from path", op.Op)
+		}
+		if op.from, err = parsePointer(*op.From); err != nil {
+			return fmt.Errorf("invalid from path: %w", err)
+		}
+		if op.Op == "move" && len(op.from) < len(op.path) && isPrefix(op.from, op.path) {
+			return errors.New("cannot move a value into itself")
+		}
+	}
+	return nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%q has an invalid escape", pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, tokens []string) bool {
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p jsonPatch) Apply(doc []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	for i, op := range p {
+		root, err = op.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s %s): %v", ErrNotApplicable, i, op.Op, *op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func (op *operation) apply(root any) (any, error) {
+	switch op.Op {
+	case "add":
+		return add(root, op.path, clone(op.value))
+	case "remove":
+		root, _, err := remove(root, op.path)
+		return root, err
+	case "replace":
+		return replace(root, op.path, clone(op.value))
+	case "move":
+		root, value, err := remove(root, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, op.path, value)
+	case "copy":
+		value, err := get(root, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, op.path, clone(value))
+	default:
+		value, err := get(root, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(value, op.value) {
+			return nil, errors.New("test failed")
+		}
+		return root, nil
+	}
+}
+
+func get(root any, path []string) (any, error) {
+	node := root
+	for _, token := range path {
+		var err error
+		if node, err = child(node, token); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func add(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			if token == "-" {
+				return append(container, value), nil
+			}
+			i, err := index(token, len(container)+1)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[i+1:], container[i:])
+			container[i] = value
+			return container, nil
+		default:
+			return nil, fmt.Errorf("cannot add %q to a value that is not an object or array", token)
+		}
+	})
+}
+
+func remove(root any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+
+	var removed any
+	root, err := update(root, path, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			removed = value
+			delete(container, token)
+			return container, nil
+		case []any:
+			i, err := index(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+			removed = container[i]
+			return append(container[:i], container[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove %q from a value that is not an object or array", token)
+		}
+	})
+	return root, removed, err
+}
+
+func replace(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(container any, token string) (any, error) {
+		if _, err := child(container, token); err != nil {
+			return nil, err
+		}
+		return setChild(container, token, value), nil
+	})
+}
+
+// update calls change with the container of the last token of path and
+// returns root with the container that change returns in its place. Arrays
+// may be reallocated when they grow or shrink, so every container on the
+// path is written back into its parent.
+func update(node any, path []string, change func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return change(node, path[0])
+	}
+
+	next, err := child(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	changed, err := update(next, path[1:], change)
+	if err != nil {
+		return nil, err
+	}
+	return setChild(node, path[0], changed), nil
+}
+
+func child(node any, token string) (any, error) {
+	switch node := node.(type) {
+	case map[string]any:
+		value, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q does not exist", token)
+		}
+		return value, nil
+	case []any:
+		i, err := index(token, len(node))
+		if err != nil {
+			return nil, err
+		}
+		return node[i], nil
+	default:
+		return nil, fmt.Errorf("%q does not exist in a value that is not an object or array", token)
+	}
+}
+
+// setChild replaces an existing member or element of node, which child has
+// already found.
+func setChild(node any, token string, value any) any {
+	switch node := node.(type) {
+	case map[string]any:
+		node[token] = value
+	case []any:
+		i, _ := strconv.Atoi(token)
+		node[i] = value
+	}
+	return node
+}
+
+// index parses an array index token, which must be below limit.
+func index(token string, limit int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i >= limit {
+		return 0, fmt.Errorf("array index %d is out of bounds", i)
+	}
+	return i, nil
+}
+
+// equal compares two decoded JSON values, numbers by their value.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return errA == nil && errB == nil && x == y
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,245 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// canonical writes a JSON document with its object members sorted.
+func canonical(t *testing.T, doc string) string {
+	t.Helper()
+
+	value, err := decode([]byte(doc))
+	if err != nil {
+		t.Fatalf("invalid JSON %q: %v", doc, err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to encode %q: %v", doc, err)
+	}
+	return string(data)
+}
+
+func TestJSONPatch(t *testing.T) {
+	// The cases up to A.16 are the examples of RFC 6902 Appendix A. want is
+	// the patched document, or empty when the patch fails: wantInvalid
+	// when it does not parse, otherwise when it does not apply.
+	tests := []struct {
+		name        string
+		doc         string
+		patch       string
+		want        string
+		wantInvalid bool
+	}{
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:  "A.8 testing a value: success",
+			doc:   `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value: error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:  `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+		},
+		{
+			// A duplicate member is not detected, the last op is used, and
+			// the patch still fails as it removes a member that is missing.
+			name:  "A.13 invalid JSON patch document",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "op": "remove"}]`,
+		},
+		{
+			name:  "A.14 ~ escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:  "copying a value",
+			doc:   `{"foo": {"bar": [1, 2]}}`,
+			patch: `[{"op": "copy", "from": "/foo/bar", "path": "/baz"}, {"op": "add", "path": "/baz/-", "value": 3}]`,
+			want:  `{"foo": {"bar": [1, 2]}, "baz": [1, 2, 3]}`,
+		},
+		{
+			name:  "~1 escapes a slash",
+			doc:   `{"a/b": 1}`,
+			patch: `[{"op": "replace", "path": "/a~1b", "value": 2}]`,
+			want:  `{"a/b": 2}`,
+		},
+		{
+			name:  "replacing the whole document",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "", "value": ["baz"]}]`,
+			want:  `["baz"]`,
+		},
+		{
+			name:  "numbers are kept as they are",
+			doc:   `{"big": 12345678901234567890}`,
+			patch: `[{"op": "add", "path": "/small", "value": 1.50}]`,
+			want:  `{"big": 12345678901234567890, "small": 1.50}`,
+		},
+		{
+			name:  "test compares numbers by value",
+			doc:   `{"foo": 1.0}`,
+			patch: `[{"op": "test", "path": "/foo", "value": 1}]`,
+			want:  `{"foo": 1.0}`,
+		},
+		{
+			name:  "operations apply in order",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/baz", "value": "qux"}, {"op": "remove", "path": "/foo"}]`,
+			want:  `{"baz": "qux"}`,
+		},
+		{
+			name:  "replacing a missing member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "qux"}]`,
+		},
+		{
+			name:  "removing past the end of an array",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+		},
+		{
+			name:  "array index with a leading zero",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "replace", "path": "/foo/01", "value": "qux"}]`,
+		},
+		{
+			name:  "removing the whole document",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "remove", "path": ""}]`,
+		},
+		{
+			name:        "unknown op",
+			doc:         `{"foo": "bar"}`,
+			patch:       `[{"op": "merge", "path": "/foo", "value": "baz"}]`,
+			wantInvalid: true,
+		},
+		{
+			name:        "add without a value",
+			doc:         `{"foo": "bar"}`,
+			patch:       `[{"op": "add", "path": "/baz"}]`,
+			wantInvalid: true,
+		},
+		{
+			name:        "path without a slash",
+			doc:         `{"foo": "bar"}`,
+			patch:       `[{"op": "remove", "path": "foo"}]`,
+			wantInvalid: true,
+		},
+		{
+			name:        "invalid escape",
+			doc:         `{"foo": "bar"}`,
+			patch:       `[{"op": "remove", "path": "/fo~2o"}]`,
+			wantInvalid: true,
+		},
+		{
+			name:        "moving a value into itself",
+			doc:         `{"foo": {"bar": 1}}`,
+			patch:       `[{"op": "move", "from": "/foo", "path": "/foo/bar"}]`,
+			wantInvalid: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseJSONPatch([]byte(tc.patch))
+			if tc.wantInvalid {
+				if err == nil {
+					t.Errorf("parsed an invalid patch")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJSONPatch: %v", err)
+			}
+
+			patched, err := p.Apply([]byte(tc.doc))
+			if tc.want == "" {
+				if !errors.Is(err, ErrNotApplicable) {
+					t.Errorf("got %s and error %v, want %v", patched, err, ErrNotApplicable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got, want := canonical(t, string(patched)), canonical(t, tc.want); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule allows Requests requests per Per, with bursts of up to Requests.
+type Rule struct {
+	Requests int
+	Per      time.Duration
+}
+
+var ruleUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// ParseRule parses rules such as "20/m" or "1000/h". "0" and "off" disable
+// limiting and return a zero Rule.
+func ParseRule(value string) (Rule, error) {
+	value = strings.TrimSpace(value)
+	if value == "0" || value == "off" {
+		return Rule{}, nil
+	}
+
+	count, unit, found := strings.Cut(value, "/")
+	per, ok := ruleUnits[unit]
+	requests, err := strconv.Atoi(count)
+	if !found || !ok || err != nil || requests <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate limit %q, expected requests per s, m, h or d such as \"20/m\"", value)
+	}
+
+	return Rule{Requests: requests, Per: per}, nil
+}
+
+// Disabled reports whether the rule lets every request through.
+func (r Rule) Disabled() bool {
+	return r.Requests == 0
+}
+
+func (r Rule) String() string {
+	if r.Disabled() {
+		return "off"
+	}
+	for unit, per := range ruleUnits {
+		if per == r.Per {
+			return fmt.Sprintf("%d/%s", r.Requests, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", r.Requests, r.Per)
+}
+
+// Result describes the bucket of a key after a call to Allow.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, zero when
+	// Allowed is true.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter keeps a token bucket per key. Each bucket holds up to
+// Rule.Requests tokens and refills at Rule.Requests per Rule.Per, every
+// request takes one token.
+type Limiter struct {
+	rule Rule
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rule Rule) *Limiter {
+	return &Limiter{
+		rule:    rule,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *Limiter) Rule() Rule {
+	return l.rule
+}
+
+// Allow takes a token from the bucket of key if there is one.
+func (l *Limiter) Allow(key string) Result {
+	if l.rule.Disabled() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(l.rule.Requests)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*l.ratePerSecond())
+	b.updated = now
+
+	result := Result{Limit: l.rule.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		missing := (1 - b.tokens) / l.ratePerSecond()
+		result.RetryAfter = time.Duration(math.Ceil(missing * float64(time.Second)))
+	}
+	result.Remaining = int(b.tokens)
+
+	return result
+}
+
+func (l *Limiter) ratePerSecond() float64 {
+	return float64(l.rule.Requests) / l.rule.Per.Seconds()
+}
+
+// sweep drops the buckets that have refilled completely, which behave the
+// same as missing ones, so idle clients do not pile up. Callers must hold
+// the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rule.Per {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.rule.Per {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package recurrence reads the recurrence rules of todos, a subset of the
+// RRULE property of RFC 5545 such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// A rule repeats from the due date of a todo, which is its first
+// occurrence. Days, weekdays and months are those of UTC.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of a rule.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxInterval bounds INTERVAL, far beyond any useful rule.
+const maxInterval = 1000
+
+// maxSteps bounds the periods Next looks at, for rules whose weekdays never
+// fall on their days.
+const maxSteps = 100000
+
+// weekdays are the BYDAY values, in the order of a week starting on Monday
+// as the default WKST of RFC 5545 does.
+var weekdays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// Rule is a parsed recurrence rule. Count is 0 and Until nil when the rule
+// repeats forever.
+type Rule struct {
+	Freq     string
+	Interval int
+	// ByDay holds the weekdays of a daily or weekly rule, Monday first.
+	ByDay []time.Weekday
+	Count int
+	Until *time.Time
+}
+
+// Parse reads a rule, with or without the "RRULE:" prefix. Parts are
+// separated by semicolons and may come in any order.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 6 && strings.EqualFold(value[:6], "RRULE:") {
+		value = value[6:]
+	}
+	if value == "" {
+		return nil, errors.New("rule is empty")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		arg = strings.ToUpper(strings.TrimSpace(arg))
+		if !ok || name == "" || arg == "" {
+			return nil, fmt.Errorf("invalid rule part %q, expected NAME=VALUE", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		if err := rule.set(name, arg); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("COUNT and UNTIL cannot both be given")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Daily && rule.Freq != Weekly {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=%s or FREQ=%s", Daily, Weekly)
+	}
+
+	return rule, nil
+}
+
+func (r *Rule) set(name, arg string) error {
+	switch name {
+	case "FREQ":
+		switch arg {
+		case Daily, Weekly, Monthly, Yearly:
+			r.Freq = arg
+		default:
+			return fmt.Errorf("unsupported FREQ %s, expected %s, %s, %s or %s", arg, Daily, Weekly, Monthly, Yearly)
+		}
+	case "INTERVAL":
+		interval, err := strconv.Atoi(arg)
+		if err != nil || interval < 1 || interval > maxInterval {
+			return fmt.Errorf("INTERVAL must be a number between 1 and %d", maxInterval)
+		}
+		r.Interval = interval
+	case "COUNT":
+		count, err := strconv.Atoi(arg)
+		if err != nil || count < 1 {
+			return errors.New("COUNT must be a positive number")
+		}
+		r.Count = count
+	case "UNTIL":
+		until, err := parseUntil(arg)
+		if err != nil {
+			return err
+		}
+		r.Until = &until
+	case "BYDAY":
+		for _, day := range strings.Split(arg, ",") {
+			index := slices.Index(weekdays, strings.TrimSpace(day))
+			if index < 0 {
+				return fmt.Errorf("invalid BYDAY %s, expected weekdays such as MO,WE,FR", day)
+			}
+			weekday := time.Weekday((index + 1) % 7)
+			if !slices.Contains(r.ByDay, weekday) {
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		}
+		slices.SortFunc(r.ByDay, func(a, b time.Weekday) int { return weekdayIndex(a) - weekdayIndex(b) })
+	case "WKST":
+		if arg != "MO" {
+			return errors.New("only WKST=MO is supported")
+		}
+	default:
+		return fmt.Errorf("unsupported rule part %s", name)
+	}
+	return nil
+}
+
+// parseUntil reads a date, which includes the whole day, or a time in UTC.
+func parseUntil(arg string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", arg); err == nil {
+		return until, nil
+	}
+	if until, err := time.Parse("20060102", arg); err == nil {
+		return until.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %s, expected a date such as 20250131 or a time such as 20250131T090000Z", arg)
+}
+
+// String writes the rule in a canonical form, which Parse reads back.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdays[weekdayIndex(day)]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence of the rule that starts at start and is
+// later than both start and after. It returns false once the rule ends at
+// its UNTIL. COUNT is left to the caller, which knows how many occurrences
+// there have been.
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	start = start.UTC()
+	if after.Before(start) {
+		after = start
+	}
+
+	// Skip the periods that end before after, which matters when a todo is
+	// completed long after it was due.
+	step := r.firstStep(start, after)
+	for ; step < maxSteps; step++ {
+		next, ok := r.occurrence(start, step, after)
+		if !ok {
+			continue
+		}
+		if r.Until != nil && next.After(*r.Until) {
+			return time.Time{}, false
+		}
+		return next, true
+	}
+	return time.Time{}, false
+}
+
+// firstStep estimates the first period that may hold an occurrence after
+// after, erring on the early side.
+func (r *Rule) firstStep(start, after time.Time) int {
+	days := int(after.Sub(start).Hours() / 24)
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = days / r.Interval
+	case Weekly:
+		periods = days / 7 / r.Interval
+	case Monthly:
+		periods = days / 31 / r.Interval
+	case Yearly:
+		periods = days / 366 / r.Interval
+	}
+	return max(periods-1, 0)
+}
+
+// occurrence returns the first occurrence in the period step intervals
+// after the one of start that is later than after, if there is one.
+func (r *Rule) occurrence(start time.Time, step int, after time.Time) (time.Time, bool) {
+	n := step * r.Interval
+	switch r.Freq {
+	case Daily:
+		next := start.AddDate(0, 0, n)
+		if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, next.Weekday()) {
+			return time.Time{}, false
+		}
+		return next, next.After(after)
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		monday := start.AddDate(0, 0, -weekdayIndex(start.Weekday())+7*n)
+		for _, day := range days {
+			next := monday.AddDate(0, 0, weekdayIndex(day))
+			if next.After(after) {
+				return next, true
+			}
+		}
+		return time.Time{}, false
+	case Monthly:
+		// Months without the day of start, such as a 31st, are skipped.
+		next := start.AddDate(0, n, 0)
+		return next, next.Day() == start.Day() && next.After(after)
+	default:
+		next := start.AddDate(n, 0, 0)
+		return next, next.Day() == start.Day() && next.After(after)
+	}
+}
+
+// weekdayIndex counts the days since Monday.
+func weekdayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
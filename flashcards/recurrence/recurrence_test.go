@@ -0,0 +1,138 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// want is the rule as String writes it.
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=fr,mo,fr", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"BYDAY=SU,MO;FREQ=WEEKLY;INTERVAL=2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU"},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR"},
+		{"FREQ=MONTHLY;INTERVAL=1;COUNT=3", "FREQ=MONTHLY;COUNT=3"},
+		{"FREQ=YEARLY;UNTIL=20300228", "FREQ=YEARLY;UNTIL=20300228T235959Z"},
+		{"FREQ=DAILY;UNTIL=20250607T090000Z;WKST=MO", "FREQ=DAILY;UNTIL=20250607T090000Z"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rule, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := rule.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			again, err := Parse(rule.String())
+			if err != nil {
+				t.Fatalf("Parse of %q: %v", rule.String(), err)
+			}
+			if again.String() != tc.want {
+				t.Errorf("got %q after a round trip, want %q", again.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"empty", ""},
+		{"empty after the prefix", "RRULE:"},
+		{"no FREQ", "INTERVAL=2"},
+		{"unsupported FREQ", "FREQ=HOURLY"},
+		{"part without a value", "FREQ=DAILY;COUNT"},
+		{"empty part", "FREQ=DAILY;"},
+		{"part given twice", "FREQ=DAILY;FREQ=WEEKLY"},
+		{"COUNT and UNTIL", "FREQ=DAILY;COUNT=2;UNTIL=20250601"},
+		{"monthly BYDAY", "FREQ=MONTHLY;BYDAY=MO"},
+		{"yearly BYDAY", "FREQ=YEARLY;BYDAY=MO"},
+		{"INTERVAL of 0", "FREQ=DAILY;INTERVAL=0"},
+		{"INTERVAL too large", "FREQ=DAILY;INTERVAL=1001"},
+		{"INTERVAL not a number", "FREQ=DAILY;INTERVAL=two"},
+		{"COUNT of 0", "FREQ=DAILY;COUNT=0"},
+		{"invalid UNTIL", "FREQ=DAILY;UNTIL=2025-06-01"},
+		{"invalid BYDAY", "FREQ=WEEKLY;BYDAY=XX"},
+		{"BYDAY with an ordinal", "FREQ=WEEKLY;BYDAY=1MO"},
+		{"WKST other than MO", "FREQ=WEEKLY;WKST=SU"},
+		{"unsupported part", "FREQ=DAILY;BYMONTH=1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if rule, err := Parse(tc.rule); err == nil {
+				t.Errorf("Parse(%q) = %q, want an error", tc.rule, rule)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	// An after of zero means the rule starts at start and nothing later has
+	// happened. A zero want means the rule has ended.
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  time.Time
+	}{
+		{"daily", "FREQ=DAILY", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 7, 7)},
+		{"every third day, completed late", "FREQ=DAILY;INTERVAL=3", date(2025, 6, 6, 7), date(2025, 6, 20, 12), date(2025, 6, 21, 7)},
+		{"weekdays from a Friday", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 9, 7)},
+		{"weekly", "FREQ=WEEKLY", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 13, 7)},
+		{"BYDAY later in the week", "FREQ=WEEKLY;BYDAY=MO,FR", date(2025, 6, 2, 7), time.Time{}, date(2025, 6, 6, 7)},
+		{"BYDAY in the next week", "FREQ=WEEKLY;BYDAY=MO,FR", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 9, 7)},
+		{"BYDAY from a Sunday", "FREQ=WEEKLY;BYDAY=MO,SU", date(2025, 6, 8, 7), time.Time{}, date(2025, 6, 9, 7)},
+		{"BYDAY every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 16, 7)},
+		{"BYDAY without the day of start", "FREQ=WEEKLY;BYDAY=MO", date(2025, 6, 4, 7), time.Time{}, date(2025, 6, 9, 7)},
+		{"BYDAY completed on a Saturday", "FREQ=WEEKLY;BYDAY=MO,FR", date(2025, 6, 6, 7), date(2025, 6, 7, 12), date(2025, 6, 9, 7)},
+		{"BYDAY across the year", "FREQ=WEEKLY;BYDAY=TH", date(2025, 12, 26, 7), time.Time{}, date(2026, 1, 1, 7)},
+		{"monthly", "FREQ=MONTHLY", date(2025, 1, 15, 7), time.Time{}, date(2025, 2, 15, 7)},
+		{"monthly on the 31st skips February", "FREQ=MONTHLY", date(2025, 1, 31, 7), time.Time{}, date(2025, 3, 31, 7)},
+		{"monthly on the 31st skips April", "FREQ=MONTHLY", date(2025, 1, 31, 7), date(2025, 3, 31, 7), date(2025, 5, 31, 7)},
+		{"monthly on the 30th in a leap year", "FREQ=MONTHLY", date(2024, 1, 30, 7), time.Time{}, date(2024, 3, 30, 7)},
+		{"yearly", "FREQ=YEARLY", date(2025, 6, 6, 7), time.Time{}, date(2026, 6, 6, 7)},
+		{"yearly on February 29", "FREQ=YEARLY", date(2024, 2, 29, 7), time.Time{}, date(2028, 2, 29, 7)},
+		{"yearly on February 29, completed late", "FREQ=YEARLY", date(2024, 2, 29, 7), date(2030, 1, 1, 0), date(2032, 2, 29, 7)},
+		{"before a date UNTIL", "FREQ=DAILY;UNTIL=20250607", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 7, 7)},
+		{"after a date UNTIL", "FREQ=DAILY;UNTIL=20250607", date(2025, 6, 6, 7), date(2025, 6, 7, 7), time.Time{}},
+		{"after a time UNTIL", "FREQ=DAILY;UNTIL=20250607T060000Z", date(2025, 6, 6, 7), time.Time{}, time.Time{}},
+		{"at a time UNTIL", "FREQ=DAILY;UNTIL=20250607T070000Z", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 7, 7)},
+		{"COUNT is left to the caller", "FREQ=DAILY;COUNT=1", date(2025, 6, 6, 7), time.Time{}, date(2025, 6, 7, 7)},
+		{"start in another zone", "FREQ=DAILY", time.Date(2025, 6, 6, 1, 0, 0, 0, time.FixedZone("UTC-6", -6*60*60)), time.Time{}, date(2025, 6, 7, 7)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			got, ok := rule.Next(tc.start, tc.after)
+			if tc.want.IsZero() {
+				if ok {
+					t.Errorf("got %v, want the rule to have ended", got)
+				}
+				return
+			}
+			if !ok || !got.Equal(tc.want) {
+				t.Errorf("got %v (%t), want %v", got, ok, tc.want)
+			}
+		})
+	}
+}
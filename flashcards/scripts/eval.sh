@@ -1,9 +1,14 @@
 #!/bin/bash
 
 # Quiz API Evaluation Script
-# Runs test scenarios against the quiz endpoint and accumulates results
-
-API_URL="http://localhost:8080/quiz/generate"
+# Runs test scenarios against quiz sessions and accumulates results. Each
+# scenario starts a session, whose first question the server asks, and sends
+# the student turns one after another.
+
+API_URL="http://localhost:8080/quiz/sessions"
+# Access token or API key with the quiz:generate scope of the user whose
+# notes are quizzed, see POST /auth/login and POST /auth/keys
+TOKEN="${TOKEN:?set TOKEN to an access token or API key}"
 OUTPUT_FILE="quiz_evaluation_results.txt"
 
 # Check if jq is available for JSON formatting
@@ -23,30 +28,42 @@ EOF
 
 # Function to call the quiz API
 call_quiz_api() {
-    local payload="$1"
-    curl -s -X POST "$API_URL" \
+    local url="$1"
+    local payload="$2"
+    curl -s -X POST "$url" \
         -H "Content-Type: application/json" \
+        -H "Authorization: Bearer $TOKEN" \
         -d "$payload"
 }
 
-# Function to run a test scenario
+# Function to run a test scenario: a new session, then every answer in turn
 run_test_scenario() {
     local description="$1"
-    local payload="$2"
-    
+    shift
+
     echo "Running: $description"
-    
+
     # Add scenario header to output file
     cat >> "$OUTPUT_FILE" << EOF
 SCENARIO: $description
-REQUEST: $payload
+ANSWERS: $(printf '%s | ' "$@")
 RESPONSE:
 EOF
-    
-    # Call API and format response
-    local response=$(call_quiz_api "$payload")
-    
-    if [ $? -eq 0 ] && [ -n "$response" ]; then
+
+    local session_id=$(call_quiz_api "$API_URL" '{}' | jq -r '.id // empty' 2>/dev/null)
+    if [ -z "$session_id" ]; then
+        echo "Could not start a quiz session" >> "$OUTPUT_FILE"
+        echo -e "\n---\n" >> "$OUTPUT_FILE"
+        return
+    fi
+
+    # Call API for every answer and format the final session
+    local response=$(curl -s "$API_URL/$session_id" -H "Authorization: Bearer $TOKEN")
+    for answer in "$@"; do
+        response=$(call_quiz_api "$API_URL/$session_id/messages" "$(jq -n --arg content "$answer" '{content: $content}')")
+    done
+
+    if [ -n "$response" ]; then
         echo "$response" | jq '.' >> "$OUTPUT_FILE" 2>/dev/null
         if [ $? -ne 0 ]; then
             echo "Raw response (JSON parsing failed): $response" >> "$OUTPUT_FILE"
@@ -54,7 +71,7 @@ EOF
     else
         echo "API call failed or returned empty response" >> "$OUTPUT_FILE"
     fi
-    
+
     # Add separator
     echo -e "\n---\n" >> "$OUTPUT_FILE"
 }
@@ -64,68 +81,45 @@ clear_results_file
 
 echo "Starting quiz API evaluation..."
 
-# Test 1: Initial quiz generation (empty messages)
+# Test 1: Initial quiz generation (no answers)
 run_test_scenario \
-    "Fresh quiz start - should generate first question based on notes" \
-    '{"messages": []}'
+    "Fresh quiz start - should generate first question based on notes"
 
-# Test 2: Correct answer follow-up
+# Test 2: Detailed answer
 run_test_scenario \
-    "Student provides correct answer - should ask follow-up question" \
-    '{"messages": [
-        {"role": "assistant", "content": "What were the major Anglo-Saxon kingdoms established in England?"},
-        {"role": "user", "content": "The major Anglo-Saxon kingdoms were Wessex, Mercia, Northumbria, and East Anglia, collectively known as the Heptarchy."}
-    ]}'
+    "Student provides a detailed answer - should grade it and ask a follow-up question" \
+    "The major Anglo-Saxon kingdoms were Wessex, Mercia, Northumbria, and East Anglia, collectively known as the Heptarchy."
 
-# Test 3: Incorrect answer follow-up
+# Test 3: Wrong date
 run_test_scenario \
-    "Student provides wrong answer - should guide/correct and continue" \
-    '{"messages": [
-        {"role": "assistant", "content": "In what year did the Norman Conquest occur?"},
-        {"role": "user", "content": "The Norman Conquest happened in 1067."}
-    ]}'
+    "Student provides a wrong answer - should guide/correct and continue" \
+    "The Norman Conquest happened in 1067."
 
 # Test 4: Off-topic response
 run_test_scenario \
     "Student goes completely off-topic - should redirect to quiz content" \
-    '{"messages": [
-        {"role": "assistant", "content": "Who was King Alfred the Great and what was he known for?"},
-        {"role": "user", "content": "I think pizza is the best food ever invented. What do you think about Italian cuisine?"}
-    ]}'
+    "I think pizza is the best food ever invented. What do you think about Italian cuisine?"
 
 # Test 5: Partial/vague answer
 run_test_scenario \
     "Student gives incomplete answer - should probe for more detail" \
-    '{"messages": [
-        {"role": "assistant", "content": "What was the significance of the Magna Carta?"},
-        {"role": "user", "content": "It was important for limiting power."}
-    ]}'
+    "It was important for limiting power."
 
 # Test 6: Multi-turn conversation
 run_test_scenario \
     "Extended quiz conversation - should maintain context and progression" \
-    '{"messages": [
-        {"role": "assistant", "content": "What were the Wars of the Roses?"},
-        {"role": "user", "content": "Civil wars between the Houses of Lancaster and York for control of the English throne."},
-        {"role": "assistant", "content": "Excellent! Can you tell me how these wars ended?"},
-        {"role": "user", "content": "Henry Tudor defeated Richard III at the Battle of Bosworth Field in 1487."}
-    ]}'
+    "Civil wars between the Houses of Lancaster and York for control of the English throne." \
+    "Henry Tudor defeated Richard III at the Battle of Bosworth Field in 1485."
 
 # Test 7: Student asks a question back
 run_test_scenario \
     "Student asks a question instead of answering - should handle gracefully" \
-    '{"messages": [
-        {"role": "assistant", "content": "What led to the English Reformation under Henry VIII?"},
-        {"role": "user", "content": "Can you tell me more about Catherine of Aragon first?"}
-    ]}'
+    "Can you tell me more about Catherine of Aragon first?"
 
 # Test 8: Very short/minimal response
 run_test_scenario \
     "Student gives minimal one-word answer - should encourage elaboration" \
-    '{"messages": [
-        {"role": "assistant", "content": "What was significant about Elizabeth I'\''s reign?"},
-        {"role": "user", "content": "Good."}
-    ]}'
+    "Good."
 
 echo "Quiz API evaluation completed. Results saved to $OUTPUT_FILE"
 echo "Review the output file to analyze AI behavior across different scenarios."
\ No newline at end of file
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+	sentenceEnd    = regexp.MustCompile(`[.!?]["')\]]*\s+`)
+)
+
+// ChunkText splits text into chunks of at most maxTokens tokens as measured
+// by count. Paragraphs are kept together when they fit, otherwise they are
+// split between sentences, and sentences that are still too long are split
+// between words.
+func ChunkText(text string, maxTokens int, count func(string) int) []string {
+	var chunks []string
+	var current []string
+	currentTokens := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, " "))
+			current, currentTokens = nil, 0
+		}
+	}
+	add := func(piece string, tokens int) {
+		if currentTokens+tokens > maxTokens {
+			flush()
+		}
+		current = append(current, piece)
+		currentTokens += tokens
+	}
+
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		if paragraph == "" {
+			continue
+		}
+
+		if tokens := count(paragraph); tokens <= maxTokens {
+			add(paragraph, tokens)
+			continue
+		}
+
+		for _, sentence := range splitSentences(paragraph) {
+			if tokens := count(sentence); tokens <= maxTokens {
+				add(sentence, tokens)
+				continue
+			}
+			for _, word := range strings.Fields(sentence) {
+				add(word, count(word+" "))
+			}
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	start := 0
+	for _, end := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+		sentences = append(sentences, strings.TrimSpace(paragraph[start:end[1]]))
+		start = end[1]
+	}
+	if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Highlight markers used in snippets. They match the markers produced by
+// Postgres ts_headline and SQLite snippet() so that every storage driver
+// returns the same format.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+const snippetRadius = 80
+
+// Tokenize splits text into lowercase words made of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Document is a piece of text that can be ranked by RankDocuments.
+type Document struct {
+	ID      int
+	Content string
+}
+
+// TextMatch is a document matching a query, with its relevance score.
+type TextMatch struct {
+	ID    int
+	Score float64
+}
+
+// RankDocuments scores docs against query with BM25 and returns the ones that
+// contain every query term, best first. Query terms match words that start
+// with them, so "goroutine" also finds "goroutines".
+func RankDocuments(docs []Document, query string) []TextMatch {
+	terms := uniqueTerms(Tokenize(query))
+	if len(terms) == 0 || len(docs) == 0 {
+		return nil
+	}
+
+	const k1, b = 1.2, 0.75
+
+	tokenized := make([][]string, len(docs))
+	totalLength := 0
+	docFreq := make(map[string]int, len(terms))
+	for i, doc := range docs {
+		tokenized[i] = Tokenize(doc.Content)
+		totalLength += len(tokenized[i])
+		for _, term := range terms {
+			if countPrefixed(tokenized[i], term) > 0 {
+				docFreq[term]++
+			}
+		}
+	}
+	avgLength := float64(totalLength) / float64(len(docs))
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	var matches []TextMatch
+	for i, doc := range docs {
+		score := 0.0
+		matchedAll := true
+		for _, term := range terms {
+			tf := float64(countPrefixed(tokenized[i], term))
+			if tf == 0 {
+				matchedAll = false
+				break
+			}
+			n := float64(docFreq[term])
+			idf := math.Log(1 + (float64(len(docs))-n+0.5)/(n+0.5))
+			length := float64(len(tokenized[i]))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/avgLength))
+		}
+		if matchedAll {
+			matches = append(matches, TextMatch{ID: doc.ID, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// Snippet returns an excerpt of content around the first word matching one of
+// the query terms, with every matching word wrapped in HighlightStart and
+// HighlightStop. Without a match the start of content is returned.
+func Snippet(content, query string) string {
+	terms := uniqueTerms(Tokenize(query))
+	runes := []rune(content)
+
+	first := -1
+	for _, word := range wordSpans(runes) {
+		if matchesAny(string(runes[word[0]:word[1]]), terms) {
+			first = word[0]
+			break
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first >= 0 {
+		start = max(0, first-snippetRadius)
+		end = min(len(runes), first+snippetRadius)
+	} else if end > 2*snippetRadius {
+		end = 2 * snippetRadius
+	}
+	start, end = expandToWords(runes, start, end)
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	pos := start
+	for _, word := range wordSpans(runes[start:end]) {
+		wordStart, wordEnd := start+word[0], start+word[1]
+		if !matchesAny(string(runes[wordStart:wordEnd]), terms) {
+			continue
+		}
+		sb.WriteString(string(runes[pos:wordStart]))
+		sb.WriteString(HighlightStart)
+		sb.WriteString(string(runes[wordStart:wordEnd]))
+		sb.WriteString(HighlightStop)
+		pos = wordEnd
+	}
+	sb.WriteString(string(runes[pos:end]))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func uniqueTerms(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+func countPrefixed(tokens []string, term string) int {
+	count := 0
+	for _, token := range tokens {
+		if strings.HasPrefix(token, term) {
+			count++
+		}
+	}
+	return count
+}
+
+func matchesAny(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// wordSpans returns the [start, end) rune offsets of every word in runes.
+func wordSpans(runes []rune) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range runes {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(runes)})
+	}
+	return spans
+}
+
+// expandToWords moves start and end outwards so they do not cut a word.
+func expandToWords(runes []rune, start, end int) (int, int) {
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isWordRune(runes[end]) {
+		end++
+	}
+	return start, end
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// VectorMatch is an indexed vector close to a query, scored by cosine
+// similarity in [-1, 1].
+type VectorMatch struct {
+	ID    int
+	Score float64
+}
+
+// VectorIndex is an in-process, exact nearest neighbour index over
+// embedding vectors. Vectors are normalized on insert so that a search is a
+// dot product against every entry, which is fast enough for a few thousand
+// notes.
+type VectorIndex struct {
+	mu      sync.RWMutex
+	vectors map[int][]float32
+}
+
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{vectors: make(map[int][]float32)}
+}
+
+func (idx *VectorIndex) Upsert(id int, vector []float32) {
+	normalized := normalize(vector)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.vectors[id] = normalized
+}
+
+func (idx *VectorIndex) Delete(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	delete(idx.vectors, id)
+}
+
+func (idx *VectorIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.vectors)
+}
+
+func (idx *VectorIndex) Has(id int) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	_, ok := idx.vectors[id]
+	return ok
+}
+
+// Search returns up to limit vectors most similar to query, best first.
+// Vectors with a different dimension than query are skipped.
+func (idx *VectorIndex) Search(query []float32, limit int) []VectorMatch {
+	normalized := normalize(query)
+
+	idx.mu.RLock()
+	matches := make([]VectorMatch, 0, len(idx.vectors))
+	for id, vector := range idx.vectors {
+		if len(vector) != len(normalized) {
+			continue
+		}
+		matches = append(matches, VectorMatch{ID: id, Score: dot(vector, normalized)})
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score == matches[j].Score {
+			return matches[i].ID > matches[j].ID
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+
+	normalized := make([]float32, len(vector))
+	if sum == 0 {
+		return normalized
+	}
+	norm := math.Sqrt(sum)
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
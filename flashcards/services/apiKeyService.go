@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/auth"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const maxAPIKeyNameLength = 100
+
+// apiKeyTouchInterval limits how often the last used time of a key is
+// written, so a busy script does not cause a write per request.
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyService struct {
+	repo db.APIKeyRepository
+	now  func() time.Time
+}
+
+func NewAPIKeyService(repo db.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+func (s *APIKeyService) CreateAPIKey(userID int, req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	log.Printf("[INFO] Starting API key creation for user ID %d", userID)
+
+	if req == nil {
+		return nil, apperr.Validation("", "request cannot be nil")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	scopes, err := s.validateAPIKey(name, req)
+	if err != nil {
+		log.Printf("[ERROR] API key validation failed: %v", err)
+		return nil, err
+	}
+
+	secret, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate API key: %v", err)
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	key := &models.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.CreateAPIKey(userID, key, auth.HashAPIKey(secret)); err != nil {
+		log.Printf("[ERROR] Failed to create API key: %v", err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully created API key with ID %d", key.ID)
+	return &models.CreatedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+func (s *APIKeyService) GetAllAPIKeys(userID int) ([]*models.APIKey, error) {
+	keys, err := s.repo.GetAllAPIKeys(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get API keys: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(userID, id int) error {
+	log.Printf("[INFO] Revoking API key with ID %d", id)
+
+	if err := s.repo.DeleteAPIKey(userID, id); err != nil {
+		log.Printf("[ERROR] Failed to revoke API key with ID %d: %v", id, err)
+		return err
+	}
+
+	log.Printf("[INFO] Successfully revoked API key with ID %d", id)
+	return nil
+}
+
+// Authenticate returns the stored key for secret and records that it was
+// used. Unknown and expired keys fail with auth.ErrInvalidToken.
+func (s *APIKeyService) Authenticate(secret string) (*models.APIKey, error) {
+	key, err := s.repo.GetAPIKeyByHash(auth.HashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+		log.Printf("[ERROR] Failed to look up API key: %v", err)
+		return nil, fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	now := s.now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, auth.ErrInvalidToken
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		// A failed write only loses usage tracking, the key is still valid.
+		if err := s.repo.TouchAPIKey(key.ID, now); err != nil {
+			log.Printf("[ERROR] Failed to record use of API key with ID %d: %v", key.ID, err)
+		}
+	}
+
+	return key, nil
+}
+
+// validateAPIKey checks the request and returns its scopes without
+// duplicates.
+func (s *APIKeyService) validateAPIKey(name string, req *models.CreateAPIKeyRequest) ([]string, error) {
+	if name == "" {
+		return nil, apperr.Validation("name", "name is required")
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return nil, apperr.Validation("name", "name must be at most %d characters", maxAPIKeyNameLength)
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, apperr.Validation("scopes", "at least one scope is required")
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !auth.ValidScope(scope) {
+			return nil, apperr.Validation("scopes", "unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, apperr.Validation("expiresAt", "expiresAt must be in the future")
+	}
+
+	return scopes, nil
+}
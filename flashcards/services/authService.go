@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/auth"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	maxEmailLength    = 255
+	minPasswordLength = 8
+	// maxPasswordBytes is the most bcrypt looks at.
+	maxPasswordBytes = 72
+)
+
+// errInvalidCredentials does not tell whether the email or the password was
+// wrong, so login cannot be used to find out which emails are registered.
+var errInvalidCredentials = apperr.Unauthorized("invalid email or password")
+
+type AuthService struct {
+	users  db.UserRepository
+	tokens *auth.TokenManager
+}
+
+func NewAuthService(users db.UserRepository, tokens *auth.TokenManager) *AuthService {
+	return &AuthService{
+		users:  users,
+		tokens: tokens,
+	}
+}
+
+func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+	log.Printf("[INFO] Starting user registration")
+
+	if req == nil {
+		return nil, apperr.Validation("", "request cannot be nil")
+	}
+
+	email := normalizeEmail(req.Email)
+	if err := validateCredentials(email, req.Password); err != nil {
+		log.Printf("[ERROR] User registration validation failed: %v", err)
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("[ERROR] Failed to hash password: %v", err)
+		return nil, fmt.Errorf("failed to register user: %w", err)
+	}
+
+	user := &models.User{Email: email, PasswordHash: hash}
+	if err := s.users.CreateUser(user); err != nil {
+		log.Printf("[ERROR] Failed to create user: %v", err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully registered user with ID %d", user.ID)
+	return s.issueToken(user)
+}
+
+func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+	log.Printf("[INFO] Starting user login")
+
+	if req == nil {
+		return nil, apperr.Validation("", "request cannot be nil")
+	}
+
+	user, err := s.users.GetUserByEmail(normalizeEmail(req.Email))
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			log.Printf("[ERROR] Login failed: unknown email")
+			return nil, errInvalidCredentials
+		}
+		log.Printf("[ERROR] Failed to get user for login: %v", err)
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, req.Password) {
+		log.Printf("[ERROR] Login failed for user ID %d: wrong password", user.ID)
+		return nil, errInvalidCredentials
+	}
+
+	log.Printf("[INFO] Successfully logged in user with ID %d", user.ID)
+	return s.issueToken(user)
+}
+
+func (s *AuthService) GetUser(userID int) (*models.User, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user ID %d: %v", userID, err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AuthService) issueToken(user *models.User) (*models.AuthResponse, error) {
+	token, expiresAt, err := s.tokens.Issue(user.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to issue token for user ID %d: %v", user.ID, err)
+		return nil, fmt.Errorf("failed to issue token: %w", err)
+	}
+
+	return &models.AuthResponse{Token: token, ExpiresAt: expiresAt, User: user}, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func validateCredentials(email, password string) error {
+	if email == "" {
+		return apperr.Validation("email", "email is required")
+	}
+	if len(email) > maxEmailLength {
+		return apperr.Validation("email", "email must be at most %d characters", maxEmailLength)
+	}
+	if at := strings.Index(email, "@"); at <= 0 || at == len(email)-1 {
+		return apperr.Validation("email", "email must be a valid email address")
+	}
+
+	if len(password) < minPasswordLength {
+		return apperr.Validation("password", "password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return apperr.Validation("password", "password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	return nil
+}
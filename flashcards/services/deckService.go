@@ -0,0 +1,337 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const maxDeckNameLength = 100
+
+type DeckService struct {
+	repo        db.DeckRepository
+	noteService *NoteService
+}
+
+func NewDeckService(repo db.DeckRepository, noteService *NoteService) *DeckService {
+	return &DeckService{
+		repo:        repo,
+		noteService: noteService,
+	}
+}
+
+func (s *DeckService) CreateDeck(userID int, req *models.CreateDeckRequest) (*models.Deck, error) {
+	log.Printf("[INFO] Starting deck creation")
+
+	if err := s.validateCreateRequest(req); err != nil {
+		log.Printf("[ERROR] Deck creation validation failed: %v", err)
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		if _, err := s.GetDeckByID(userID, *req.ParentID); err != nil {
+			log.Printf("[ERROR] Parent deck %d not available: %v", *req.ParentID, err)
+			return nil, err
+		}
+	}
+
+	deck := &models.Deck{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		ParentID:    req.ParentID,
+	}
+
+	if err := s.repo.CreateDeck(userID, deck); err != nil {
+		log.Printf("[ERROR] Failed to create deck in repository: %v", err)
+		return nil, fmt.Errorf("failed to create deck: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully created deck with ID %d", deck.ID)
+	return deck, nil
+}
+
+func (s *DeckService) GetDeckByID(userID, id int) (*models.Deck, error) {
+	log.Printf("[INFO] Starting get deck by ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid deck ID provided: %d", id)
+		return nil, apperr.Validation("id", "invalid deck ID: %d", id)
+	}
+
+	deck, err := s.repo.GetDeckByID(userID, id)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get deck by ID %d: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully retrieved deck with ID %d", id)
+	return deck, nil
+}
+
+func (s *DeckService) GetAllDecks(userID int) ([]*models.Deck, error) {
+	log.Printf("[INFO] Starting get all decks")
+
+	decks, err := s.repo.GetAllDecks(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get all decks: %v", err)
+		return nil, fmt.Errorf("failed to get decks: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d decks", len(decks))
+	return decks, nil
+}
+
+func (s *DeckService) UpdateDeck(userID, id int, req *models.UpdateDeckRequest) (*models.Deck, error) {
+	log.Printf("[INFO] Starting update deck with ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid deck ID provided for update: %d", id)
+		return nil, apperr.Validation("id", "invalid deck ID: %d", id)
+	}
+
+	if err := s.validateUpdateRequest(req); err != nil {
+		log.Printf("[ERROR] Deck update validation failed for ID %d: %v", id, err)
+		return nil, err
+	}
+
+	if _, err := s.GetDeckByID(userID, id); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if err := validateDeckName(name); err != nil {
+			return nil, err
+		}
+		updates["name"] = name
+	}
+
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			updates["parentId"] = nil
+		} else {
+			if err := s.validateParent(userID, id, *req.ParentID); err != nil {
+				log.Printf("[ERROR] Invalid parent %d for deck %d: %v", *req.ParentID, id, err)
+				return nil, err
+			}
+			updates["parentId"] = *req.ParentID
+		}
+	}
+
+	if err := s.repo.UpdateDeck(userID, id, updates); err != nil {
+		log.Printf("[ERROR] Failed to update deck ID %d in repository: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully updated deck with ID %d", id)
+	return s.repo.GetDeckByID(userID, id)
+}
+
+func (s *DeckService) DeleteDeck(userID, id int) error {
+	log.Printf("[INFO] Starting delete deck with ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid deck ID provided for deletion: %d", id)
+		return apperr.Validation("id", "invalid deck ID: %d", id)
+	}
+
+	if err := s.repo.DeleteDeck(userID, id); err != nil {
+		log.Printf("[ERROR] Failed to delete deck ID %d: %v", id, err)
+		return err
+	}
+
+	log.Printf("[INFO] Successfully deleted deck with ID %d", id)
+	return nil
+}
+
+func (s *DeckService) AddNote(userID, deckID, noteID int) error {
+	log.Printf("[INFO] Starting add note %d to deck %d", noteID, deckID)
+
+	if _, err := s.GetDeckByID(userID, deckID); err != nil {
+		return err
+	}
+	if _, err := s.noteService.GetNoteByID(userID, noteID); err != nil {
+		return err
+	}
+
+	if err := s.repo.AddNoteToDeck(userID, deckID, noteID); err != nil {
+		log.Printf("[ERROR] Failed to add note %d to deck %d: %v", noteID, deckID, err)
+		return err
+	}
+
+	log.Printf("[INFO] Successfully added note %d to deck %d", noteID, deckID)
+	return nil
+}
+
+func (s *DeckService) RemoveNote(userID, deckID, noteID int) error {
+	log.Printf("[INFO] Starting remove note %d from deck %d", noteID, deckID)
+
+	if _, err := s.GetDeckByID(userID, deckID); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveNoteFromDeck(userID, deckID, noteID); err != nil {
+		log.Printf("[ERROR] Failed to remove note %d from deck %d: %v", noteID, deckID, err)
+		return err
+	}
+
+	log.Printf("[INFO] Successfully removed note %d from deck %d", noteID, deckID)
+	return nil
+}
+
+// GetDeckNotes returns the notes in a deck, newest first. With
+// includeSubDecks the notes of all decks nested below it are included too.
+func (s *DeckService) GetDeckNotes(userID, id int, includeSubDecks bool) ([]*models.Note, error) {
+	log.Printf("[INFO] Starting get notes for deck %d", id)
+
+	noteIDs, err := s.deckNoteIDs(userID, id, includeSubDecks)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := s.noteService.GetAllNotes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deckNotes := filterNotes(notes, noteIDs)
+
+	log.Printf("[INFO] Successfully retrieved %d notes for deck %d", len(deckNotes), id)
+	return deckNotes, nil
+}
+
+// ResolveQuizNoteIDs adds the notes of deckID and its sub-decks to noteIDs.
+// A deck without notes is an error, since an empty selection would make the
+// quiz fall back to every note.
+func (s *DeckService) ResolveQuizNoteIDs(userID int, noteIDs []int, deckID *int) ([]int, error) {
+	if deckID == nil {
+		return noteIDs, nil
+	}
+
+	notes, err := s.GetDeckNotes(userID, *deckID, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, apperr.Validation("deck_id", "deck %d has no notes, at least one note is required", *deckID)
+	}
+
+	resolved := append([]int{}, noteIDs...)
+	for _, note := range notes {
+		resolved = append(resolved, note.ID)
+	}
+	return resolved, nil
+}
+
+func (s *DeckService) deckNoteIDs(userID, id int, includeSubDecks bool) ([]int, error) {
+	if _, err := s.GetDeckByID(userID, id); err != nil {
+		return nil, err
+	}
+
+	deckIDs := []int{id}
+	if includeSubDecks {
+		decks, err := s.repo.GetAllDecks(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get decks: %v", err)
+			return nil, fmt.Errorf("failed to get decks: %w", err)
+		}
+		deckIDs = append(deckIDs, descendantDeckIDs(decks, id)...)
+	}
+
+	noteIDs, err := s.repo.GetDeckNoteIDs(userID, deckIDs)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get note IDs for deck %d: %v", id, err)
+		return nil, fmt.Errorf("failed to get deck notes: %w", err)
+	}
+	return noteIDs, nil
+}
+
+// validateParent makes sure parentID exists and is neither the deck itself
+// nor one of its sub-decks, which would create a cycle.
+func (s *DeckService) validateParent(userID, id, parentID int) error {
+	if parentID == id {
+		return apperr.Validation("parentId", "invalid parent deck: a deck cannot be its own parent")
+	}
+
+	if _, err := s.GetDeckByID(userID, parentID); err != nil {
+		return err
+	}
+
+	decks, err := s.repo.GetAllDecks(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get decks: %w", err)
+	}
+	for _, descendantID := range descendantDeckIDs(decks, id) {
+		if descendantID == parentID {
+			return apperr.Validation("parentId", "invalid parent deck: deck %d is nested inside deck %d", parentID, id)
+		}
+	}
+
+	return nil
+}
+
+// descendantDeckIDs returns the ids of every deck nested below id.
+func descendantDeckIDs(decks []*models.Deck, id int) []int {
+	children := make(map[int][]int)
+	for _, deck := range decks {
+		if deck.ParentID != nil {
+			children[*deck.ParentID] = append(children[*deck.ParentID], deck.ID)
+		}
+	}
+
+	var descendants []int
+	visited := map[int]bool{id: true}
+	pending := []int{id}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		for _, childID := range children[current] {
+			if !visited[childID] {
+				visited[childID] = true
+				descendants = append(descendants, childID)
+				pending = append(pending, childID)
+			}
+		}
+	}
+	return descendants
+}
+
+func validateDeckName(name string) error {
+	if name == "" {
+		return apperr.Validation("name", "name is required")
+	}
+	if len(name) > maxDeckNameLength {
+		return apperr.Validation("name", "name must be at most %d characters", maxDeckNameLength)
+	}
+	return nil
+}
+
+func (s *DeckService) validateCreateRequest(req *models.CreateDeckRequest) error {
+	if req == nil {
+		return apperr.Validation("", "request cannot be nil")
+	}
+
+	return validateDeckName(strings.TrimSpace(req.Name))
+}
+
+func (s *DeckService) validateUpdateRequest(req *models.UpdateDeckRequest) error {
+	if req == nil {
+		return apperr.Validation("", "request cannot be nil")
+	}
+
+	if req.Name == nil && req.Description == nil && req.ParentID == nil {
+		return apperr.Validation("", "at least one field must be provided for update")
+	}
+
+	return nil
+}
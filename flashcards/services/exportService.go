@@ -0,0 +1,236 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/exporter"
+	"flashcards/models"
+)
+
+// exportPageSize is how many items an export reads per query. Reading page
+// by page keeps memory flat and never holds a database connection while the
+// client is slow to take the archive.
+const exportPageSize = 200
+
+// ExportService writes all data of a user as a ZIP archive, see
+// exporter.Archive. Items in the trash are left out.
+type ExportService struct {
+	notes      db.NoteRepository
+	todos      db.TodoRepository
+	tags       db.TagRepository
+	decks      db.DeckRepository
+	flashcards db.FlashcardRepository
+	quizzes    db.QuizSessionRepository
+}
+
+func NewExportService(notes db.NoteRepository, todos db.TodoRepository, tags db.TagRepository, decks db.DeckRepository, flashcards db.FlashcardRepository, quizzes db.QuizSessionRepository) *ExportService {
+	return &ExportService{
+		notes:      notes,
+		todos:      todos,
+		tags:       tags,
+		decks:      decks,
+		flashcards: flashcards,
+		quizzes:    quizzes,
+	}
+}
+
+// ValidateFormat checks the format of the dump before anything is written.
+func (s *ExportService) ValidateFormat(format string) error {
+	if !exporter.ValidFormat(format) {
+		return apperr.Validation("format", "invalid format %q, expected one of %s", format, strings.Join(exporter.Formats, ", "))
+	}
+	return nil
+}
+
+// Export writes the archive to w. Notes, todos and flashcards are read
+// twice, once for their own files and once for the dump, since the entries
+// of a ZIP archive are written one at a time.
+func (s *ExportService) Export(userID int, format string, w io.Writer) error {
+	log.Printf("[INFO] Starting export")
+
+	if err := s.ValidateFormat(format); err != nil {
+		return err
+	}
+
+	archive, err := exporter.NewArchive(w, format, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = s.exportFiles(userID, archive)
+	if err == nil {
+		err = s.exportDump(userID, archive)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Printf("[ERROR] Export failed: %v", err)
+		return fmt.Errorf("failed to export: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully exported data of user ID %d", userID)
+	return nil
+}
+
+// exportFiles writes the Markdown notes, todos.csv and flashcards.tsv.
+func (s *ExportService) exportFiles(userID int, archive *exporter.Archive) error {
+	if err := s.eachNote(userID, archive.WriteNote); err != nil {
+		return err
+	}
+
+	if err := archive.StartTodos(); err != nil {
+		return err
+	}
+	if err := s.eachTodo(userID, archive.WriteTodo); err != nil {
+		return err
+	}
+
+	if err := archive.StartFlashcards(); err != nil {
+		return err
+	}
+	return s.eachFlashcard(userID, archive.WriteFlashcard)
+}
+
+// exportDump writes every kind of item to the dump.
+func (s *ExportService) exportDump(userID int, archive *exporter.Archive) error {
+	if err := archive.StartDump(); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		kind string
+		each func(userID int, write func(any) error) error
+	}{
+		{exporter.KindNote, func(userID int, write func(any) error) error {
+			return s.eachNote(userID, func(note *models.Note) error { return write(note) })
+		}},
+		{exporter.KindTodo, func(userID int, write func(any) error) error {
+			return s.eachTodo(userID, func(todo *models.Todo) error { return write(todo) })
+		}},
+		{exporter.KindDeck, s.eachDeck},
+		{exporter.KindFlashcard, func(userID int, write func(any) error) error {
+			return s.eachFlashcard(userID, func(card *models.Flashcard) error { return write(card) })
+		}},
+		{exporter.KindQuizSession, s.eachQuizSession},
+	}
+	for _, section := range sections {
+		if err := archive.StartSection(section.kind); err != nil {
+			return err
+		}
+		if err := section.each(userID, archive.WriteItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) eachNote(userID int, write func(*models.Note) error) error {
+	list := func(opts models.ListOptions) (*models.Page[*models.Note], error) {
+		page, err := s.notes.ListNotes(userID, models.NoteListOptions{ListOptions: opts})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notes: %w", err)
+		}
+		return page, attachNoteTags(s.tags, userID, page.Items...)
+	}
+	return eachPage(list, write)
+}
+
+func (s *ExportService) eachTodo(userID int, write func(*models.Todo) error) error {
+	list := func(opts models.ListOptions) (*models.Page[*models.Todo], error) {
+		page, err := s.todos.ListTodos(userID, models.TodoListOptions{ListOptions: opts})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list todos: %w", err)
+		}
+		return page, attachTodoTags(s.tags, userID, page.Items...)
+	}
+	return eachPage(list, write)
+}
+
+func (s *ExportService) eachFlashcard(userID int, write func(*models.Flashcard) error) error {
+	list := func(opts models.ListOptions) (*models.Page[*models.Flashcard], error) {
+		page, err := s.flashcards.ListFlashcards(userID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list flashcards: %w", err)
+		}
+		return page, nil
+	}
+	return eachPage(list, write)
+}
+
+// eachDeck writes the decks with the ids of the notes directly in them.
+// Users have few decks, so they are read at once.
+func (s *ExportService) eachDeck(userID int, write func(any) error) error {
+	decks, err := s.decks.GetAllDecks(userID)
+	if err != nil {
+		return fmt.Errorf("failed to list decks: %w", err)
+	}
+
+	for _, deck := range decks {
+		noteIDs, err := s.decks.GetDeckNoteIDs(userID, []int{deck.ID})
+		if err != nil {
+			return fmt.Errorf("failed to list notes of deck %d: %w", deck.ID, err)
+		}
+
+		err = write(struct {
+			*models.Deck
+			NoteIDs []int `json:"noteIds"`
+		}{deck, noteIDs})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) eachQuizSession(userID int, write func(any) error) error {
+	after := ""
+	for {
+		sessions, err := s.quizzes.ListQuizSessions(userID, after, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list quiz sessions: %w", err)
+		}
+
+		for _, session := range sessions {
+			if err := write(session); err != nil {
+				return err
+			}
+		}
+
+		if len(sessions) < exportPageSize {
+			return nil
+		}
+		after = sessions[len(sessions)-1].ID
+	}
+}
+
+// eachPage calls write with every item of a list, oldest first, reading it
+// a page at a time.
+func eachPage[T any](list func(models.ListOptions) (*models.Page[T], error), write func(T) error) error {
+	opts := models.ListOptions{Limit: exportPageSize, SortOrder: models.SortOrderAsc}
+	for {
+		page, err := list(opts)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Items {
+			if err := write(item); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		if opts.After, err = models.DecodeCursor(page.NextCursor); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	defaultGeneratedFlashcards = 5
+	maxGeneratedFlashcards     = 20
+)
+
+type FlashcardService struct {
+	repo        db.FlashcardRepository
+	noteService *NoteService
+	quizService *QuizService
+}
+
+func NewFlashcardService(repo db.FlashcardRepository, noteService *NoteService, quizService *QuizService) *FlashcardService {
+	return &FlashcardService{
+		repo:        repo,
+		noteService: noteService,
+		quizService: quizService,
+	}
+}
+
+func (s *FlashcardService) CreateFlashcard(userID int, req *models.CreateFlashcardRequest) (*models.Flashcard, error) {
+	log.Printf("[INFO] Starting flashcard creation")
+
+	if err := s.validateCreateRequest(req); err != nil {
+		log.Printf("[ERROR] Flashcard creation validation failed: %v", err)
+		return nil, err
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		log.Printf("[ERROR] Flashcard creation validation failed: %v", err)
+		return nil, err
+	}
+
+	if req.NoteID != nil {
+		if _, err := s.noteService.GetNoteByID(userID, *req.NoteID); err != nil {
+			log.Printf("[ERROR] Source note %d for flashcard not available: %v", *req.NoteID, err)
+			return nil, err
+		}
+	}
+
+	card := &models.Flashcard{
+		Front:  strings.TrimSpace(req.Front),
+		Back:   strings.TrimSpace(req.Back),
+		NoteID: req.NoteID,
+		Tags:   tags,
+	}
+
+	if err := s.repo.CreateFlashcard(userID, card); err != nil {
+		log.Printf("[ERROR] Failed to create flashcard in repository: %v", err)
+		return nil, fmt.Errorf("failed to create flashcard: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully created flashcard with ID %d", card.ID)
+	return card, nil
+}
+
+func (s *FlashcardService) GetFlashcardByID(userID, id int) (*models.Flashcard, error) {
+	log.Printf("[INFO] Starting get flashcard by ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid flashcard ID provided: %d", id)
+		return nil, apperr.Validation("id", "invalid flashcard ID: %d", id)
+	}
+
+	card, err := s.repo.GetFlashcardByID(userID, id)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get flashcard by ID %d: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully retrieved flashcard with ID %d", id)
+	return card, nil
+}
+
+func (s *FlashcardService) GetAllFlashcards(userID int) ([]*models.Flashcard, error) {
+	log.Printf("[INFO] Starting get all flashcards")
+
+	cards, err := s.repo.GetAllFlashcards(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get all flashcards: %v", err)
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d flashcards", len(cards))
+	return cards, nil
+}
+
+func (s *FlashcardService) GetFlashcardsByNoteID(userID, noteID int) ([]*models.Flashcard, error) {
+	log.Printf("[INFO] Starting get flashcards for note ID %d", noteID)
+
+	if _, err := s.noteService.GetNoteByID(userID, noteID); err != nil {
+		return nil, err
+	}
+
+	cards, err := s.repo.GetFlashcardsByNoteID(userID, noteID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get flashcards for note ID %d: %v", noteID, err)
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d flashcards for note ID %d", len(cards), noteID)
+	return cards, nil
+}
+
+func (s *FlashcardService) UpdateFlashcard(userID, id int, req *models.UpdateFlashcardRequest) (*models.Flashcard, error) {
+	log.Printf("[INFO] Starting update flashcard with ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid flashcard ID provided for update: %d", id)
+		return nil, apperr.Validation("id", "invalid flashcard ID: %d", id)
+	}
+
+	if err := s.validateUpdateRequest(req); err != nil {
+		log.Printf("[ERROR] Flashcard update validation failed for ID %d: %v", id, err)
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+
+	if req.Front != nil {
+		trimmedFront := strings.TrimSpace(*req.Front)
+		if trimmedFront == "" {
+			return nil, apperr.Validation("front", "front cannot be empty")
+		}
+		updates["front"] = trimmedFront
+	}
+
+	if req.Back != nil {
+		trimmedBack := strings.TrimSpace(*req.Back)
+		if trimmedBack == "" {
+			return nil, apperr.Validation("back", "back cannot be empty")
+		}
+		updates["back"] = trimmedBack
+	}
+
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		updates["tags"] = tags
+	}
+
+	if err := s.repo.UpdateFlashcard(userID, id, updates); err != nil {
+		log.Printf("[ERROR] Failed to update flashcard ID %d in repository: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully updated flashcard with ID %d", id)
+	return s.repo.GetFlashcardByID(userID, id)
+}
+
+func (s *FlashcardService) DeleteFlashcard(userID, id int) error {
+	log.Printf("[INFO] Starting delete flashcard with ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid flashcard ID provided for deletion: %d", id)
+		return apperr.Validation("id", "invalid flashcard ID: %d", id)
+	}
+
+	if err := s.repo.DeleteFlashcard(userID, id); err != nil {
+		log.Printf("[ERROR] Failed to delete flashcard ID %d: %v", id, err)
+		return err
+	}
+
+	log.Printf("[INFO] Successfully deleted flashcard with ID %d", id)
+	return nil
+}
+
+func (s *FlashcardService) GenerateFlashcardsFromNote(userID, noteID int, req *models.GenerateFlashcardsRequest) ([]*models.Flashcard, error) {
+	log.Printf("[INFO] Starting flashcard generation for note ID %d", noteID)
+
+	if req == nil {
+		req = &models.GenerateFlashcardsRequest{}
+	}
+
+	maxCards := req.MaxCards
+	if maxCards == 0 {
+		maxCards = defaultGeneratedFlashcards
+	}
+	if maxCards < 0 || maxCards > maxGeneratedFlashcards {
+		return nil, apperr.Validation("maxCards", "maxCards must be between 1 and %d", maxGeneratedFlashcards)
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.noteService.GetNoteByID(userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := s.quizService.ExtractFlashcardPairs(userID, note.Content, maxCards)
+	if err != nil {
+		log.Printf("[ERROR] Flashcard extraction failed for note ID %d: %v", noteID, err)
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		log.Printf("[ERROR] No flashcards could be extracted from note ID %d", noteID)
+		return nil, apperr.Upstream(nil, "no flashcards could be extracted from note %d", noteID)
+	}
+
+	cards := make([]*models.Flashcard, 0, len(pairs))
+	for _, pair := range pairs {
+		cards = append(cards, &models.Flashcard{
+			Front:  pair.Front,
+			Back:   pair.Back,
+			NoteID: &note.ID,
+			Tags:   append([]string{}, tags...),
+		})
+	}
+
+	if err := s.repo.CreateFlashcards(userID, cards); err != nil {
+		log.Printf("[ERROR] Failed to save generated flashcards for note ID %d: %v", noteID, err)
+		return nil, fmt.Errorf("failed to save flashcards: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully generated %d flashcards for note ID %d", len(cards), noteID)
+	return cards, nil
+}
+
+func (s *FlashcardService) validateCreateRequest(req *models.CreateFlashcardRequest) error {
+	if req == nil {
+		return apperr.Validation("", "request cannot be nil")
+	}
+
+	if strings.TrimSpace(req.Front) == "" {
+		return apperr.Validation("front", "front is required")
+	}
+
+	if strings.TrimSpace(req.Back) == "" {
+		return apperr.Validation("back", "back is required")
+	}
+
+	return nil
+}
+
+func (s *FlashcardService) validateUpdateRequest(req *models.UpdateFlashcardRequest) error {
+	if req == nil {
+		return apperr.Validation("", "request cannot be nil")
+	}
+
+	if req.Front == nil && req.Back == nil && req.Tags == nil {
+		return apperr.Validation("", "at least one field must be provided for update")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	fsrsDecay            = -0.5
+	fsrsFactor           = 19.0 / 81.0
+	fsrsDesiredRetention = 0.9
+	fsrsMaximumInterval  = 36500
+)
+
+// Default FSRS-4.5 model weights.
+var fsrsDefaultWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031, 1.6474,
+	0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// FSRSScheduler implements the Free Spaced Repetition Scheduler (FSRS-4.5)
+// with its default weights and a desired retention of 90%.
+type FSRSScheduler struct {
+	weights   [17]float64
+	retention float64
+}
+
+func NewFSRSScheduler() *FSRSScheduler {
+	return &FSRSScheduler{
+		weights:   fsrsDefaultWeights,
+		retention: fsrsDesiredRetention,
+	}
+}
+
+func (s *FSRSScheduler) Name() string {
+	return AlgorithmFSRS
+}
+
+func (s *FSRSScheduler) Schedule(state models.ReviewState, grade string, now time.Time) models.ReviewState {
+	rating := fsrsRating(grade)
+	w := s.weights
+
+	if state.Stability == 0 || state.LastReviewedAt == nil {
+		state.Stability = w[rating-1]
+		state.Difficulty = s.initialDifficulty(rating)
+	} else {
+		elapsedDays := math.Max(now.Sub(*state.LastReviewedAt).Hours()/24, 0)
+		retrievability := math.Pow(1+fsrsFactor*elapsedDays/state.Stability, fsrsDecay)
+
+		if rating == 1 {
+			state.Stability = w[11] * math.Pow(state.Difficulty, -w[12]) *
+				(math.Pow(state.Stability+1, w[13]) - 1) * math.Exp(w[14]*(1-retrievability))
+		} else {
+			hardPenalty, easyBonus := 1.0, 1.0
+			if rating == 2 {
+				hardPenalty = w[15]
+			}
+			if rating == 4 {
+				easyBonus = w[16]
+			}
+			state.Stability *= 1 + math.Exp(w[8])*(11-state.Difficulty)*math.Pow(state.Stability, -w[9])*
+				(math.Exp(w[10]*(1-retrievability))-1)*hardPenalty*easyBonus
+		}
+
+		difficulty := state.Difficulty - w[6]*float64(rating-3)
+		state.Difficulty = clamp(w[7]*s.initialDifficulty(3)+(1-w[7])*difficulty, 1, 10)
+	}
+
+	if rating == 1 {
+		state.Repetitions = 0
+		state.Lapses++
+	} else {
+		state.Repetitions++
+	}
+
+	interval := state.Stability / fsrsFactor * (math.Pow(s.retention, 1/fsrsDecay) - 1)
+	state.IntervalDays = int(clamp(math.Round(interval), 1, fsrsMaximumInterval))
+
+	state.Algorithm = AlgorithmFSRS
+	state.LastGrade = grade
+	state.DueAt = addDays(now, state.IntervalDays)
+	state.LastReviewedAt = &now
+
+	return state
+}
+
+func (s *FSRSScheduler) initialDifficulty(rating int) float64 {
+	return clamp(s.weights[4]-float64(rating-3)*s.weights[5], 1, 10)
+}
+
+func fsrsRating(grade string) int {
+	switch grade {
+	case models.GradeHard:
+		return 2
+	case models.GradeGood:
+		return 3
+	case models.GradeEasy:
+		return 4
+	default:
+		return 1
+	}
+}
+
+func clamp(value, minimum, maximum float64) float64 {
+	return math.Min(math.Max(value, minimum), maximum)
+}
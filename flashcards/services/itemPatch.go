@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/patch"
+)
+
+// maxPatchAttempts bounds how often a patch without a required version is
+// applied again when the item changes between reading and writing it.
+const maxPatchAttempts = 3
+
+// applyPatch applies p to doc, the fields of a note or todo that can be
+// changed, and decodes the result into patched. Fields that are not in doc
+// cannot be added.
+func applyPatch(p patch.Patch, doc, patched any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	result, err := p.Apply(data)
+	if errors.Is(err, patch.ErrNotApplicable) {
+		return apperr.Conflict("%v", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		return patchedDocumentError(err)
+	}
+
+	return nil
+}
+
+// patchedDocumentError describes why a patched document does not decode
+// without the Go type names of encoding/json errors.
+func patchedDocumentError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return apperr.Validation("", "the patched document must be an object, not %s", typeErr.Value)
+		}
+		return apperr.Validation(typeErr.Field, "%s cannot be %s", typeErr.Field, typeErr.Value)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return apperr.Validation(field, "%s cannot be patched", field)
+	}
+	return apperr.Validation("", "invalid patched document: %v", err)
+}
+
+// patchedTags returns the tags of a patched document, where a removed or
+// null list clears them.
+func patchedTags(tags *[]string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return *tags
+}
+
+// optionalString returns the value of a field of a patched document, where
+// a removed or null value is empty.
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
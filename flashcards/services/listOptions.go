@@ -0,0 +1,54 @@
+package services
+
+import (
+	"flashcards/apperr"
+	"flashcards/models"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// normalizeListOptions validates the paging options of an API request and
+// fills in the defaults. Unlike the repositories, a zero limit means the
+// default page size here.
+func normalizeListOptions(opts *models.ListOptions) error {
+	switch {
+	case opts.Limit < 0:
+		return apperr.Validation("limit", "invalid limit: %d", opts.Limit)
+	case opts.Limit == 0:
+		opts.Limit = defaultListLimit
+	}
+	opts.Limit = min(opts.Limit, maxListLimit)
+
+	switch opts.SortBy {
+	case "":
+		opts.SortBy = models.SortByCreatedAt
+	case models.SortByCreatedAt, models.SortByUpdatedAt:
+	default:
+		return apperr.Validation("sort", "invalid sort field %q, expected %s or %s", opts.SortBy, models.SortByCreatedAt, models.SortByUpdatedAt)
+	}
+
+	switch opts.SortOrder {
+	case "":
+		opts.SortOrder = models.SortOrderDesc
+	case models.SortOrderAsc, models.SortOrderDesc:
+	default:
+		return apperr.Validation("order", "invalid sort order %q, expected %s or %s", opts.SortOrder, models.SortOrderAsc, models.SortOrderDesc)
+	}
+
+	// A cursor is only meaningful for the order it was created in.
+	if opts.After != nil && (opts.After.SortBy != opts.SortBy || opts.After.SortOrder != opts.SortOrder) {
+		return apperr.Validation("cursor", "invalid cursor: it belongs to a list sorted by %s %s", opts.After.SortBy, opts.After.SortOrder)
+	}
+
+	if opts.CreatedAfter != nil && opts.CreatedBefore != nil && !opts.CreatedAfter.Before(*opts.CreatedBefore) {
+		return apperr.Validation("created_after", "invalid date range: created_after must be before created_before")
+	}
+	if opts.UpdatedAfter != nil && opts.UpdatedBefore != nil && !opts.UpdatedAfter.Before(*opts.UpdatedBefore) {
+		return apperr.Validation("updated_after", "invalid date range: updated_after must be before updated_before")
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/importer"
+	"flashcards/models"
+)
+
+// maxImportNotes bounds the notes of a single import.
+const maxImportNotes = 5000
+
+// importChunkSize is how many notes an import saves per transaction, unless
+// it is atomic.
+const importChunkSize = 100
+
+// importedNote is a note of an import on its way to the repository.
+type importedNote struct {
+	item *models.NoteImportItem
+	note *models.Note
+}
+
+// ImportNotes reads notes from files and saves those whose content the user
+// has no note with yet. Notes that cannot be read or have invalid tags are
+// reported as failed without stopping the import. Notes are saved with their
+// tags in chunks, each in its own transaction, so that a failure keeps what
+// was saved before it and reports the rest as failed; since duplicates are
+// skipped, uploading the same files again resumes the import. An atomic
+// import saves all notes or none.
+func (s *NoteService) ImportNotes(userID int, files []importer.File, opts models.NoteImportOptions) (*models.NoteImportReport, error) {
+	log.Printf("[INFO] Starting import of %d files", len(files))
+
+	opts.Delimiter = strings.TrimSpace(opts.Delimiter)
+	if err := validateImportOptions(files, opts); err != nil {
+		log.Printf("[ERROR] Note import validation failed: %v", err)
+		return nil, err
+	}
+
+	tags, err := normalizeTags(opts.Tags)
+	if err != nil {
+		log.Printf("[ERROR] Note import validation failed: %v", err)
+		return nil, err
+	}
+
+	report := &models.NoteImportReport{Items: make([]*models.NoteImportItem, 0)}
+	pending := readImportFiles(report, files, opts, tags)
+	if len(pending) > maxImportNotes {
+		return nil, apperr.Validation("files", "an import cannot exceed %d notes, the files hold %d", maxImportNotes, len(pending))
+	}
+
+	chunkSize := importChunkSize
+	if opts.Atomic {
+		chunkSize = max(len(pending), 1)
+	}
+	for start := 0; start < len(pending); start += chunkSize {
+		chunk := pending[start:min(start+chunkSize, len(pending))]
+
+		notes := make([]*models.Note, len(chunk))
+		for i, imported := range chunk {
+			notes[i] = imported.note
+		}
+
+		created, err := s.repo.ImportNotes(userID, notes)
+		if err != nil && opts.Atomic {
+			log.Printf("[ERROR] Failed to import notes in repository: %v", err)
+			return nil, fmt.Errorf("failed to import notes: %w", err)
+		}
+		if err != nil {
+			log.Printf("[ERROR] Note import stopped after %d of %d notes: %v", start, len(pending), err)
+			for _, imported := range pending[start:] {
+				imported.item.Status = models.ImportStatusFailed
+				imported.item.Error = "not saved, the import stopped: upload the files again to resume"
+			}
+			break
+		}
+
+		finishImportChunk(chunk, created)
+	}
+
+	for _, item := range report.Items {
+		switch item.Status {
+		case models.ImportStatusCreated:
+			report.Created++
+		case models.ImportStatusDuplicate:
+			report.Duplicates++
+		default:
+			report.Failed++
+		}
+	}
+
+	log.Printf("[INFO] Imported %d notes, skipped %d duplicates, %d failed", report.Created, report.Duplicates, report.Failed)
+	return report, nil
+}
+
+// readImportFiles adds an item to report for every note in files, and for
+// every file that cannot be read, and returns the notes to save.
+func readImportFiles(report *models.NoteImportReport, files []importer.File, opts models.NoteImportOptions, tags []string) []importedNote {
+	parseOpts := importer.Options{
+		Format:       opts.Format,
+		Delimiter:    opts.Delimiter,
+		HeadingLevel: opts.HeadingLevel,
+	}
+
+	var pending []importedNote
+	for _, file := range files {
+		items, err := importer.Parse(file, parseOpts)
+		if err != nil {
+			report.Items = append(report.Items, &models.NoteImportItem{
+				File:   file.Name,
+				Status: models.ImportStatusFailed,
+				Error:  err.Error(),
+			})
+			continue
+		}
+
+		for _, item := range items {
+			result := &models.NoteImportItem{File: file.Name, Index: item.Index, Line: item.Line}
+			report.Items = append(report.Items, result)
+			if item.Err != nil {
+				result.Status = models.ImportStatusFailed
+				result.Error = item.Err.Error()
+				continue
+			}
+
+			noteTags, err := normalizeTags(append(item.Tags, tags...))
+			if err != nil {
+				result.Status = models.ImportStatusFailed
+				result.Error = err.Error()
+				continue
+			}
+
+			pending = append(pending, importedNote{
+				item: result,
+				note: &models.Note{Content: item.Content, Tags: noteTags},
+			})
+		}
+	}
+	return pending
+}
+
+// finishImportChunk records the saved notes of a chunk. The repository
+// tagged the new ones in the same transaction, duplicates keep the tags of
+// the note they duplicate.
+func finishImportChunk(chunk []importedNote, created []bool) {
+	for i, imported := range chunk {
+		imported.item.NoteID = imported.note.ID
+		if created[i] {
+			imported.item.Status = models.ImportStatusCreated
+		} else {
+			imported.item.Status = models.ImportStatusDuplicate
+		}
+	}
+}
+
+func validateImportOptions(files []importer.File, opts models.NoteImportOptions) error {
+	if len(files) == 0 {
+		return apperr.Validation("files", "at least one file is required")
+	}
+	if opts.Format != "" && !importer.ValidFormat(opts.Format) {
+		return apperr.Validation("format", "invalid format %q, expected one of %s", opts.Format, strings.Join(importer.Formats, ", "))
+	}
+	if opts.HeadingLevel < 0 || opts.HeadingLevel > 6 {
+		return apperr.Validation("headingLevel", "headingLevel must be between 1 and 6")
+	}
+	if strings.Contains(opts.Delimiter, "\n") {
+		return apperr.Validation("delimiter", "delimiter must be a single line")
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/search"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+const indexChunkTokens = 200
+
+// NoteChunk is a piece of a note, as indexed and as selected for quiz
+// context.
+type NoteChunk struct {
+	NoteID   int
+	Position int
+	Content  string
+	Tokens   int
+
+	id int
+}
+
+type indexedNote struct {
+	userID    int
+	updatedAt time.Time
+	chunkIDs  []int
+}
+
+// NoteIndex splits notes into chunks and embeds them into an in-process
+// vector index. Quiz retrieval and semantic search share one index, so every
+// note is embedded once, and only notes that changed since they were indexed
+// are chunked and embedded again. The chunks of all users share the index,
+// but callers only rank the chunks of the notes of one user. The embedding
+// provider is called without holding the lock, so a slow call does not hold
+// up the requests of other users.
+type NoteIndex struct {
+	embedder    embeddings.Embedder
+	countTokens func(string) int
+	vectors     *search.VectorIndex
+
+	mu          sync.Mutex
+	notes       map[int]*indexedNote
+	chunks      map[int]*NoteChunk
+	nextChunkID int
+}
+
+// NewNoteIndex creates an index. embedder may be nil, in which case notes are
+// only chunked.
+func NewNoteIndex(embedder embeddings.Embedder, countTokens func(string) int) *NoteIndex {
+	return &NoteIndex{
+		embedder:    embedder,
+		countTokens: countTokens,
+		vectors:     search.NewVectorIndex(),
+		notes:       make(map[int]*indexedNote),
+		chunks:      make(map[int]*NoteChunk),
+		nextChunkID: 1,
+	}
+}
+
+// SemanticEnabled reports whether chunks can be ranked by similarity.
+func (x *NoteIndex) SemanticEnabled() bool {
+	return x.embedder != nil
+}
+
+// sync re-chunks notes that are new or changed, drops the chunks of notes of
+// userID that are no longer in allNotes and returns the chunks of notes, in
+// note and reading order.
+func (x *NoteIndex) sync(userID int, notes, allNotes []*models.Note) []NoteChunk {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, note := range notes {
+		indexed, ok := x.notes[note.ID]
+		if ok && indexed.updatedAt.Equal(note.UpdatedAt) {
+			continue
+		}
+		if ok {
+			x.removeNote(note.ID)
+		}
+
+		indexed = &indexedNote{userID: userID, updatedAt: note.UpdatedAt}
+		for position, content := range search.ChunkText(note.Content, indexChunkTokens, x.countTokens) {
+			id := x.nextChunkID
+			x.nextChunkID++
+			x.chunks[id] = &NoteChunk{NoteID: note.ID, Position: position, Content: content, Tokens: x.countTokens(content), id: id}
+			indexed.chunkIDs = append(indexed.chunkIDs, id)
+		}
+		x.notes[note.ID] = indexed
+	}
+
+	current := make(map[int]bool, len(allNotes))
+	for _, note := range allNotes {
+		current[note.ID] = true
+	}
+	for id, indexed := range x.notes {
+		if !current[id] && indexed.userID == userID {
+			x.removeNote(id)
+		}
+	}
+
+	var chunks []NoteChunk
+	for _, note := range notes {
+		for _, id := range x.notes[note.ID].chunkIDs {
+			chunks = append(chunks, *x.chunks[id])
+		}
+	}
+	return chunks
+}
+
+// removeNote drops the chunks of a note. Callers must hold the lock.
+func (x *NoteIndex) removeNote(noteID int) {
+	for _, id := range x.notes[noteID].chunkIDs {
+		x.vectors.Delete(id)
+		delete(x.chunks, id)
+	}
+	delete(x.notes, noteID)
+}
+
+// embed adds vectors for the chunks that are not indexed yet. Chunks get new
+// ids when their note changes, so indexed vectors are never out of date, and
+// the vectors of chunks dropped while they were embedded are not added.
+func (x *NoteIndex) embed(ctx context.Context, chunks []NoteChunk) error {
+	var pending []NoteChunk
+	for _, chunk := range chunks {
+		if !x.vectors.Has(chunk.id) {
+			pending = append(pending, chunk)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(pending))
+	for i, chunk := range pending {
+		texts[i] = chunk.Content
+	}
+
+	log.Printf("[INFO] Embedding %d note chunks", len(pending))
+	vectors, err := x.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return apperr.Upstream(err, "failed to embed note chunks")
+	}
+	if len(vectors) != len(pending) {
+		return fmt.Errorf("failed to embed note chunks: got %d vectors for %d chunks", len(vectors), len(pending))
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for i, chunk := range pending {
+		if _, ok := x.chunks[chunk.id]; ok {
+			x.vectors.Upsert(chunk.id, vectors[i])
+		}
+	}
+	return nil
+}
+
+// rank embeds query and returns the chunks most similar to it, best first,
+// with their scores. Only chunks are ranked, which embed must have indexed.
+func (x *NoteIndex) rank(ctx context.Context, query string, chunks []NoteChunk) ([]NoteChunk, []float64, error) {
+	vector, err := x.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, nil, apperr.Upstream(err, "failed to embed query")
+	}
+
+	byID := make(map[int]NoteChunk, len(chunks))
+	for _, chunk := range chunks {
+		byID[chunk.id] = chunk
+	}
+
+	ranked := make([]NoteChunk, 0, len(chunks))
+	scores := make([]float64, 0, len(chunks))
+	for _, match := range x.vectors.Search(vector, 0) {
+		if chunk, ok := byID[match.ID]; ok {
+			ranked = append(ranked, chunk)
+			scores = append(scores, match.Score)
+		}
+	}
+	return ranked, scores, nil
+}
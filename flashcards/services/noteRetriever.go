@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/search"
+)
+
+// retrievalHistoryMessages is how many of the latest messages make up the
+// retrieval query, enough to cover the question and its answer.
+const retrievalHistoryMessages = 3
+
+// NoteRetriever picks the parts of the notes that are most relevant to a quiz
+// conversation. The chunks of the notes are ranked by embedding similarity in
+// the note index shared with semantic search, or with BM25 without an
+// embedder. Selected chunks never exceed the token budget.
+type NoteRetriever struct {
+	noteService *NoteService
+	index       *NoteIndex
+	tokenBudget int
+}
+
+func NewNoteRetriever(noteService *NoteService, index *NoteIndex, tokenBudget int) *NoteRetriever {
+	return &NoteRetriever{
+		noteService: noteService,
+		index:       index,
+		tokenBudget: tokenBudget,
+	}
+}
+
+// Retrieve returns the chunks to use as context for the next message of the
+// conversation, in note and reading order. Only the notes of userID in
+// noteIDs are considered, or all of their notes when noteIDs is empty.
+// Without messages there is nothing to rank against yet, so the opening
+// chunks of each note are taken first to spread the first question across
+// the notes.
+func (r *NoteRetriever) Retrieve(ctx context.Context, userID int, noteIDs []int, messages []models.Message) ([]NoteChunk, error) {
+	allNotes, err := r.noteService.GetAllNotes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve notes: %w", err)
+	}
+
+	notes := allNotes
+	if len(noteIDs) > 0 {
+		selected := make(map[int]bool, len(noteIDs))
+		for _, id := range noteIDs {
+			selected[id] = true
+		}
+		filtered := make([]*models.Note, 0, len(noteIDs))
+		for _, note := range allNotes {
+			if selected[note.ID] {
+				filtered = append(filtered, note)
+			}
+		}
+		if len(filtered) == 0 {
+			return nil, apperr.Validation("note_ids", "at least one valid note id is required")
+		}
+		notes = filtered
+	}
+	if len(notes) == 0 {
+		return nil, apperr.Validation("note_ids", "at least one note is required")
+	}
+
+	candidates := r.index.sync(userID, notes, allNotes)
+
+	query := retrievalQuery(messages)
+	var ranked []NoteChunk
+	switch {
+	case query == "":
+		ranked = breadthFirst(candidates)
+	case r.index.SemanticEnabled():
+		if err := r.index.embed(ctx, candidates); err != nil {
+			return nil, err
+		}
+		ranked, _, err = r.index.rank(ctx, query, candidates)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		ranked = rankByKeywords(query, candidates)
+	}
+
+	selected := r.pack(ranked)
+	log.Printf("[INFO] Retrieved %d of %d note chunks from %d notes as quiz context", len(selected), len(candidates), len(notes))
+	return selected, nil
+}
+
+// rankByKeywords puts chunks matching the query first, best match first,
+// followed by the remaining chunks in reading order.
+func rankByKeywords(query string, candidates []NoteChunk) []NoteChunk {
+	docs := make([]search.Document, len(candidates))
+	for i, chunk := range candidates {
+		docs[i] = search.Document{ID: chunk.id, Content: chunk.Content}
+	}
+
+	// Any word may match, the query is a whole conversation turn.
+	scores := make(map[int]float64)
+	for _, term := range search.Tokenize(query) {
+		for _, match := range search.RankDocuments(docs, term) {
+			scores[match.ID] += match.Score
+		}
+	}
+
+	ranked := append([]NoteChunk{}, candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].id] > scores[ranked[j].id]
+	})
+	return ranked
+}
+
+// pack takes chunks in ranked order while they fit in the token budget and
+// returns them in note and reading order.
+func (r *NoteRetriever) pack(ranked []NoteChunk) []NoteChunk {
+	remaining := r.tokenBudget
+	var selected []NoteChunk
+	for _, chunk := range ranked {
+		if chunk.Tokens > remaining {
+			continue
+		}
+		selected = append(selected, chunk)
+		remaining -= chunk.Tokens
+	}
+
+	order := make(map[int]int)
+	for _, chunk := range selected {
+		if _, ok := order[chunk.NoteID]; !ok {
+			order[chunk.NoteID] = len(order)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].NoteID != selected[j].NoteID {
+			return order[selected[i].NoteID] < order[selected[j].NoteID]
+		}
+		return selected[i].Position < selected[j].Position
+	})
+	return selected
+}
+
+// breadthFirst orders chunks by their position within the note, so the
+// first chunk of every note comes before the second chunk of any note.
+func breadthFirst(candidates []NoteChunk) []NoteChunk {
+	ranked := append([]NoteChunk{}, candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Position < ranked[j].Position
+	})
+	return ranked
+}
+
+func retrievalQuery(messages []models.Message) string {
+	start := max(0, len(messages)-retrievalHistoryMessages)
+
+	parts := make([]string, 0, len(messages)-start)
+	for _, message := range messages[start:] {
+		parts = append(parts, message.Content)
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
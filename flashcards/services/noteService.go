@@ -1,23 +1,31 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
+	"flashcards/apperr"
 	"flashcards/db"
+	"flashcards/diff"
 	"flashcards/models"
+	"flashcards/patch"
 )
 
 type NoteService struct {
 	repo db.NoteRepository
+	tags db.TagRepository
 }
 
-func NewNoteService(repo db.NoteRepository) *NoteService {
-	return &NoteService{repo: repo}
+func NewNoteService(repo db.NoteRepository, tags db.TagRepository) *NoteService {
+	return &NoteService{
+		repo: repo,
+		tags: tags,
+	}
 }
 
-func (s *NoteService) CreateNote(req *models.CreateNoteRequest) (*models.Note, error) {
+func (s *NoteService) CreateNote(userID int, req *models.CreateNoteRequest) (*models.Note, error) {
 	log.Printf("[INFO] Starting note creation")
 
 	if err := s.validateCreateRequest(req); err != nil {
@@ -25,11 +33,18 @@ func (s *NoteService) CreateNote(req *models.CreateNoteRequest) (*models.Note, e
 		return nil, err
 	}
 
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		log.Printf("[ERROR] Note creation validation failed: %v", err)
+		return nil, err
+	}
+
 	note := &models.Note{
 		Content: strings.TrimSpace(req.Content),
+		Tags:    sortedTags(tags),
 	}
 
-	if err := s.repo.CreateNote(note); err != nil {
+	if err := s.repo.CreateNote(userID, note); err != nil {
 		log.Printf("[ERROR] Failed to create note in repository: %v", err)
 		return nil, fmt.Errorf("failed to create note: %w", err)
 	}
@@ -38,43 +53,89 @@ func (s *NoteService) CreateNote(req *models.CreateNoteRequest) (*models.Note, e
 	return note, nil
 }
 
-func (s *NoteService) GetNoteByID(id int) (*models.Note, error) {
+func (s *NoteService) GetNoteByID(userID, id int) (*models.Note, error) {
 	log.Printf("[INFO] Starting get note by ID %d", id)
 
 	if id <= 0 {
 		log.Printf("[ERROR] Invalid note ID provided: %d", id)
-		return nil, fmt.Errorf("invalid note ID: %d", id)
+		return nil, apperr.Validation("id", "invalid note ID: %d", id)
 	}
 
-	note, err := s.repo.GetNoteByID(id)
+	note, err := s.repo.GetNoteByID(userID, id)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get note by ID %d: %v", id, err)
 		return nil, err
 	}
 
+	if err := s.attachTags(userID, note); err != nil {
+		return nil, err
+	}
+
 	log.Printf("[INFO] Successfully retrieved note with ID %d", id)
 	return note, nil
 }
 
-func (s *NoteService) GetAllNotes() ([]*models.Note, error) {
+func (s *NoteService) GetAllNotes(userID int) ([]*models.Note, error) {
 	log.Printf("[INFO] Starting get all notes")
 
-	notes, err := s.repo.GetAllNotes()
+	page, err := s.repo.ListNotes(userID, models.NoteListOptions{})
 	if err != nil {
 		log.Printf("[ERROR] Failed to get all notes: %v", err)
 		return nil, fmt.Errorf("failed to get notes: %w", err)
 	}
 
-	log.Printf("[INFO] Successfully retrieved %d notes", len(notes))
-	return notes, nil
+	if err := s.attachTags(userID, page.Items...); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d notes", len(page.Items))
+	return page.Items, nil
+}
+
+// ListNotes returns one page of the notes matching opts and the tag filter.
+func (s *NoteService) ListNotes(userID int, opts models.NoteListOptions, tags models.TagFilter) (*models.Page[*models.Note], error) {
+	log.Printf("[INFO] Starting list notes")
+
+	if err := normalizeListOptions(&opts.ListOptions); err != nil {
+		log.Printf("[ERROR] Note list validation failed: %v", err)
+		return nil, err
+	}
+
+	if len(tags.Tags) > 0 {
+		normalized, err := normalizeTags(tags.Tags)
+		if err != nil {
+			log.Printf("[ERROR] Invalid tag filter: %v", err)
+			return nil, err
+		}
+		opts.IDs, err = s.tags.FindNoteIDs(userID, normalized, tags.MatchAll)
+		if err != nil {
+			log.Printf("[ERROR] Failed to find notes by tags: %v", err)
+			return nil, fmt.Errorf("failed to get notes: %w", err)
+		}
+	}
+
+	page, err := s.repo.ListNotes(userID, opts)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list notes: %v", err)
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+
+	if err := s.attachTags(userID, page.Items...); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully listed %d of %d notes", len(page.Items), page.Total)
+	return page, nil
 }
 
-func (s *NoteService) UpdateNote(id int, req *models.UpdateNoteRequest) (*models.Note, error) {
+// UpdateNote applies req to the note. Unless version is 0, the note must
+// still be at version, otherwise the update fails with a precondition error.
+func (s *NoteService) UpdateNote(userID, id int, req *models.UpdateNoteRequest, version int) (*models.Note, error) {
 	log.Printf("[INFO] Starting update note with ID %d", id)
 
 	if id <= 0 {
 		log.Printf("[ERROR] Invalid note ID provided for update: %d", id)
-		return nil, fmt.Errorf("invalid note ID: %d", id)
+		return nil, apperr.Validation("id", "invalid note ID: %d", id)
 	}
 
 	if err := s.validateUpdateRequest(req); err != nil {
@@ -88,50 +149,271 @@ func (s *NoteService) UpdateNote(id int, req *models.UpdateNoteRequest) (*models
 		trimmedContent := strings.TrimSpace(*req.Content)
 		if trimmedContent == "" {
 			log.Printf("[ERROR] Empty content provided for note ID %d", id)
-			return nil, fmt.Errorf("content cannot be empty")
+			return nil, apperr.Validation("content", "content cannot be empty")
 		}
 		updates["content"] = trimmedContent
 	}
 
-	if len(updates) == 0 {
-		log.Printf("[ERROR] No valid updates provided for note ID %d", id)
-		return nil, fmt.Errorf("no valid updates provided")
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			log.Printf("[ERROR] Invalid tags provided for note ID %d: %v", id, err)
+			return nil, err
+		}
+		updates["tags"] = tags
 	}
 
-	if err := s.repo.UpdateNote(id, updates); err != nil {
+	// The note is updated even with tags only, to check and bump its version.
+	if err := s.repo.UpdateNote(userID, id, updates, version); err != nil {
 		log.Printf("[ERROR] Failed to update note ID %d in repository: %v", id, err)
 		return nil, err
 	}
 
 	log.Printf("[INFO] Successfully updated note with ID %d", id)
-	return s.repo.GetNoteByID(id)
+	return s.GetNoteByID(userID, id)
 }
 
-func (s *NoteService) DeleteNote(id int) error {
+// noteDocument holds the fields of a note that a patch can change.
+type noteDocument struct {
+	Content *string   `json:"content"`
+	Tags    *[]string `json:"tags"`
+}
+
+// PatchNote applies a JSON merge patch or JSON patch to the note as
+// {"content": ..., "tags": [...]} and saves the changed fields like
+// UpdateNote. The write only succeeds if the note is still at the version
+// the patch was applied to. With a version to require, a note that has
+// changed fails the patch, without one the patch is applied again.
+func (s *NoteService) PatchNote(userID, id int, p patch.Patch, version int) (*models.Note, error) {
+	log.Printf("[INFO] Starting patch note with ID %d", id)
+
+	for attempt := 1; ; attempt++ {
+		note, err := s.GetNoteByID(userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if version > 0 && note.Version != version {
+			log.Printf("[ERROR] Note ID %d is at version %d, not %d", id, note.Version, version)
+			return nil, apperr.PreconditionFailed("note with id %d has been modified, it is at version %d", id, note.Version)
+		}
+
+		var patched noteDocument
+		if err := applyPatch(p, noteDocument{Content: &note.Content, Tags: &note.Tags}, &patched); err != nil {
+			log.Printf("[ERROR] Failed to apply patch to note ID %d: %v", id, err)
+			return nil, err
+		}
+		if patched.Content == nil {
+			return nil, apperr.Validation("content", "content is required")
+		}
+
+		req := &models.UpdateNoteRequest{}
+		if *patched.Content != note.Content {
+			req.Content = patched.Content
+		}
+		if tags := patchedTags(patched.Tags); !sameTags(tags, note.Tags) {
+			req.Tags = &tags
+		}
+		if req.Content == nil && req.Tags == nil {
+			log.Printf("[INFO] Patch leaves note ID %d unchanged", id)
+			return note, nil
+		}
+
+		updated, err := s.UpdateNote(userID, id, req, note.Version)
+		if errors.Is(err, apperr.ErrPreconditionFailed) && version == 0 && attempt < maxPatchAttempts {
+			log.Printf("[INFO] Note ID %d changed while patching, applying the patch again", id)
+			continue
+		}
+		return updated, err
+	}
+}
+
+// DeleteNote moves the note to the trash. It keeps its tags, so that a
+// restore brings them back. Unless version is 0, the note must still be at
+// version.
+func (s *NoteService) DeleteNote(userID, id, version int) error {
 	log.Printf("[INFO] Starting delete note with ID %d", id)
 
 	if id <= 0 {
 		log.Printf("[ERROR] Invalid note ID provided for deletion: %d", id)
-		return fmt.Errorf("invalid note ID: %d", id)
+		return apperr.Validation("id", "invalid note ID: %d", id)
 	}
 
-	if err := s.repo.DeleteNote(id); err != nil {
+	if err := s.repo.DeleteNote(userID, id, version); err != nil {
 		log.Printf("[ERROR] Failed to delete note ID %d: %v", id, err)
 		return err
 	}
 
-	log.Printf("[INFO] Successfully deleted note with ID %d", id)
+	log.Printf("[INFO] Successfully moved note with ID %d to the trash", id)
+	return nil
+}
+
+// RestoreNote takes the note out of the trash.
+func (s *NoteService) RestoreNote(userID, id int) (*models.Note, error) {
+	log.Printf("[INFO] Starting restore note with ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid note ID provided for restore: %d", id)
+		return nil, apperr.Validation("id", "invalid note ID: %d", id)
+	}
+
+	if err := s.repo.RestoreNote(userID, id); err != nil {
+		log.Printf("[ERROR] Failed to restore note ID %d: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully restored note with ID %d", id)
+	return s.GetNoteByID(userID, id)
+}
+
+// ListNoteRevisions returns the revisions of a note, newest first.
+func (s *NoteService) ListNoteRevisions(userID, id int) ([]*models.NoteRevision, error) {
+	log.Printf("[INFO] Starting list revisions of note ID %d", id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid note ID provided for revisions: %d", id)
+		return nil, apperr.Validation("id", "invalid note ID: %d", id)
+	}
+
+	revisions, err := s.repo.ListNoteRevisions(userID, id)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list revisions of note ID %d: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully listed %d revisions of note ID %d", len(revisions), id)
+	return revisions, nil
+}
+
+func (s *NoteService) GetNoteRevision(userID, id, revision int) (*models.NoteRevision, error) {
+	log.Printf("[INFO] Starting get revision %d of note ID %d", revision, id)
+
+	if err := validateRevision(id, revision); err != nil {
+		log.Printf("[ERROR] Note revision validation failed: %v", err)
+		return nil, err
+	}
+
+	rev, err := s.repo.GetNoteRevision(userID, id, revision)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get revision %d of note ID %d: %v", revision, id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully retrieved revision %d of note ID %d", revision, id)
+	return rev, nil
+}
+
+// DiffNoteRevisions compares two revisions of a note line by line or word by
+// word. A zero to stands for the latest revision and a zero from for the
+// revision before to.
+func (s *NoteService) DiffNoteRevisions(userID, id, from, to int, mode string) (*models.NoteDiff, error) {
+	log.Printf("[INFO] Starting diff of revisions %d and %d of note ID %d", from, to, id)
+
+	if id <= 0 {
+		log.Printf("[ERROR] Invalid note ID provided for diff: %d", id)
+		return nil, apperr.Validation("id", "invalid note ID: %d", id)
+	}
+	if from < 0 {
+		return nil, apperr.Validation("from", "invalid revision: %d", from)
+	}
+	if to < 0 {
+		return nil, apperr.Validation("to", "invalid revision: %d", to)
+	}
+
+	var split func(a, b string) []diff.Chunk
+	switch mode {
+	case "", models.DiffModeLine:
+		mode, split = models.DiffModeLine, diff.Lines
+	case models.DiffModeWord:
+		split = diff.Words
+	default:
+		return nil, apperr.Validation("mode", "invalid diff mode %q, expected %s or %s", mode, models.DiffModeLine, models.DiffModeWord)
+	}
+
+	revisions, err := s.repo.ListNoteRevisions(userID, id)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list revisions of note ID %d: %v", id, err)
+		return nil, err
+	}
+
+	byNumber := make(map[int]*models.NoteRevision, len(revisions))
+	for _, rev := range revisions {
+		byNumber[rev.Revision] = rev
+	}
+	if to == 0 {
+		to = revisions[0].Revision
+	}
+	if from == 0 {
+		from = to - 1
+		if from == 0 {
+			return nil, apperr.Validation("from", "revision %d of note %d has no previous revision", to, id)
+		}
+	}
+	for _, number := range []int{from, to} {
+		if byNumber[number] == nil {
+			log.Printf("[ERROR] Revision %d of note ID %d not found", number, id)
+			return nil, apperr.NotFound("revision %d of note %d not found", number, id)
+		}
+	}
+
+	result := &models.NoteDiff{
+		NoteID: id,
+		From:   from,
+		To:     to,
+		Mode:   mode,
+		Chunks: make([]models.DiffChunk, 0),
+	}
+	for _, chunk := range split(byNumber[from].Content, byNumber[to].Content) {
+		switch chunk.Op {
+		case diff.Insert:
+			result.Insertions += chunk.Count
+		case diff.Delete:
+			result.Deletions += chunk.Count
+		}
+		result.Chunks = append(result.Chunks, models.DiffChunk{Op: string(chunk.Op), Text: chunk.Text})
+	}
+
+	log.Printf("[INFO] Successfully diffed revisions %d and %d of note ID %d", from, to, id)
+	return result, nil
+}
+
+// RestoreNoteRevision sets the content of the note back to the one of
+// revision. The restored content becomes a new revision, so no revision is
+// ever lost.
+func (s *NoteService) RestoreNoteRevision(userID, id, revision int) (*models.Note, error) {
+	log.Printf("[INFO] Starting restore of revision %d of note ID %d", revision, id)
+
+	if err := validateRevision(id, revision); err != nil {
+		log.Printf("[ERROR] Note revision validation failed: %v", err)
+		return nil, err
+	}
+
+	if err := s.repo.RestoreNoteRevision(userID, id, revision); err != nil {
+		log.Printf("[ERROR] Failed to restore revision %d of note ID %d: %v", revision, id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully restored revision %d of note ID %d", revision, id)
+	return s.GetNoteByID(userID, id)
+}
+
+func validateRevision(id, revision int) error {
+	if id <= 0 {
+		return apperr.Validation("id", "invalid note ID: %d", id)
+	}
+	if revision <= 0 {
+		return apperr.Validation("revision", "invalid revision: %d", revision)
+	}
 	return nil
 }
 
 func (s *NoteService) validateCreateRequest(req *models.CreateNoteRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return apperr.Validation("", "request cannot be nil")
 	}
 
 	content := strings.TrimSpace(req.Content)
 	if content == "" {
-		return fmt.Errorf("content is required")
+		return apperr.Validation("content", "content is required")
 	}
 
 	return nil
@@ -139,12 +421,54 @@ func (s *NoteService) validateCreateRequest(req *models.CreateNoteRequest) error
 
 func (s *NoteService) validateUpdateRequest(req *models.UpdateNoteRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return apperr.Validation("", "request cannot be nil")
 	}
 
-	if req.Content == nil {
-		return fmt.Errorf("at least one field must be provided for update")
+	if req.Content == nil && req.Tags == nil {
+		return apperr.Validation("", "at least one field must be provided for update")
 	}
 
 	return nil
+}
+
+func (s *NoteService) attachTags(userID int, notes ...*models.Note) error {
+	return attachNoteTags(s.tags, userID, notes...)
+}
+
+// attachNoteTags fills in the tags of notes with a single repository call.
+func attachNoteTags(repo db.TagRepository, userID int, notes ...*models.Note) error {
+	ids := make([]int, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+
+	tags, err := repo.GetNoteTags(userID, ids)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get note tags: %v", err)
+		return fmt.Errorf("failed to get note tags: %w", err)
+	}
+
+	for _, note := range notes {
+		note.Tags = tags[note.ID]
+		if note.Tags == nil {
+			note.Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// filterNotes keeps the notes whose id is in ids, preserving their order.
+func filterNotes(notes []*models.Note, ids []int) []*models.Note {
+	keep := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+
+	filtered := make([]*models.Note, 0, len(ids))
+	for _, note := range notes {
+		if keep[note.ID] {
+			filtered = append(filtered, note)
+		}
+	}
+	return filtered
 }
\ No newline at end of file
@@ -2,15 +2,17 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 
+	"flashcards/apperr"
+	"flashcards/db"
 	"flashcards/models"
 
-	"github.com/samber/lo"
+	"github.com/google/uuid"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/openai"
 )
 
 const (
@@ -37,27 +39,68 @@ Notes:
 %s
 
 Conversation:
+%s`
+
+	FLASHCARD_EXTRACTION_PROMPT = `Turn the following study note into at most %d flashcards. Each flashcard has a front with a short, specific question or prompt and a back with a concise answer that is fully supported by the note. Do not invent facts that are not in the note and do not repeat the same fact on several cards.
+
+Respond only with JSON in the form {"flashcards": [{"front": "...", "back": "..."}]}.
+
+Note:
+%s`
+
+	GRADING_PROMPT = `Grade the student's answer to the quiz question below. Use the notes as the source of truth and judge the meaning of the answer, not its wording.
+
+Respond only with a JSON object with exactly these fields:
+- "correct": true if the answer is essentially right, otherwise false
+- "score": a number from 0 to 1 for how complete and accurate the answer is
+- "explanation": one or two sentences explaining the verdict
+- "expected_answer": a short model answer based on the notes
+
+Notes:
+%s
+
+Question:
+%s
+
+Student answer:
 %s`
 )
 
+const maxGradingAttempts = 3
+
 type QuizService struct {
-	noteService *NoteService
+	retriever   *NoteRetriever
+	sessions    db.QuizSessionRepository
 	llm         llms.Model
+	quota       *QuotaService
+	temperature float64
 }
 
-func NewQuizService(noteService *NoteService, apiKey string) *QuizService {
-	llm, err := openai.New(
-		openai.WithModel("gpt-4o-mini"),
-		openai.WithToken(apiKey),
-	)
-	if err != nil {
-		log.Fatalf("Failed to initialize OpenAI client: %v", err)
-	}
-
+func NewQuizService(retriever *NoteRetriever, sessions db.QuizSessionRepository, llm llms.Model, quota *QuotaService, temperature float64) *QuizService {
 	return &QuizService{
-		noteService: noteService,
+		retriever:   retriever,
+		sessions:    sessions,
 		llm:         llm,
+		quota:       quota,
+		temperature: temperature,
+	}
+}
+
+// complete sends prompt to the LLM on behalf of userID and charges the
+// tokens to their quota.
+func (qs *QuizService) complete(ctx context.Context, userID int, prompt string, options ...llms.CallOption) (string, error) {
+	if err := qs.quota.Check(userID); err != nil {
+		return "", err
 	}
+
+	completion, err := llms.GenerateFromSinglePrompt(ctx, qs.llm, prompt, options...)
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate LLM response: %v", err)
+		return "", apperr.Upstream(err, "failed to generate LLM response")
+	}
+
+	qs.quota.Charge(userID, prompt, completion)
+	return completion, nil
 }
 
 type GenerateQuizResult struct {
@@ -65,90 +108,357 @@ type GenerateQuizResult struct {
 	Messages []models.Message
 }
 
-func (qs *QuizService) GenerateQuizResponse(noteIDs []int, messages []models.Message) (*GenerateQuizResult, error) {
-	prompt, err := qs.prepareQuizPrompt(noteIDs, messages, "quiz generation")
+// TokenCallback receives the assistant reply chunk by chunk while it is
+// generated. Returning an error stops the generation.
+type TokenCallback func(token string) error
+
+// GenerateQuizResponse asks the first question of a quiz over noteIDs
+// without keeping it. Quizzes only continue in sessions, so that the prompt
+// is only ever built from history the server stored itself.
+func (qs *QuizService) GenerateQuizResponse(userID int, noteIDs []int) (*GenerateQuizResult, error) {
+	return qs.generateQuizResponse(context.Background(), userID, noteIDs, nil, "quiz generation")
+}
+
+// GenerateQuizResponseStream works like GenerateQuizResponse but passes the
+// reply to onToken as it is generated. It stops when ctx is cancelled.
+func (qs *QuizService) GenerateQuizResponseStream(ctx context.Context, userID int, noteIDs []int, onToken TokenCallback) (*GenerateQuizResult, error) {
+	return qs.generateQuizResponse(ctx, userID, noteIDs, onToken, "streaming quiz generation")
+}
+
+// generateQuizResponse asks the first question of a quiz. Nothing is graded
+// here: answers are only graded in sessions, against questions the server
+// stored itself.
+func (qs *QuizService) generateQuizResponse(ctx context.Context, userID int, noteIDs []int, onToken TokenCallback, operationType string) (*GenerateQuizResult, error) {
+	prompt, _, err := qs.prepareQuizPrompt(ctx, userID, noteIDs, nil, operationType)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := context.Background()
-	log.Printf("[INFO] Calling LLM for quiz generation")
-	completion, err := llms.GenerateFromSinglePrompt(ctx, qs.llm, prompt, llms.WithTemperature(0.7))
+	question, err := qs.generateAssistantMessage(ctx, userID, prompt, operationType, onToken)
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate LLM response: %v", err)
-		return nil, fmt.Errorf("failed to generate LLM response: %w", err)
+		return nil, err
 	}
 
-	updatedMessages := make([]models.Message, len(messages))
-	copy(updatedMessages, messages)
+	log.Printf("[INFO] Successfully generated quiz question")
+	return &GenerateQuizResult{
+		NoteIDs:  quizNoteIDs(noteIDs),
+		Messages: []models.Message{question},
+	}, nil
+}
+
+// quizNoteIDs copies the note ids of a quiz. A quiz over all notes has none,
+// which are written as [] rather than null.
+func quizNoteIDs(noteIDs []int) []int {
+	return append([]int{}, noteIDs...)
+}
 
-	updatedMessages = append(updatedMessages, models.Message{
+func (qs *QuizService) generateAssistantMessage(ctx context.Context, userID int, prompt string, operationType string, onToken TokenCallback) (models.Message, error) {
+	options := []llms.CallOption{llms.WithTemperature(qs.temperature)}
+	if onToken != nil {
+		options = append(options, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			return onToken(string(chunk))
+		}))
+	}
+
+	log.Printf("[INFO] Calling LLM for %s", operationType)
+	completion, err := qs.complete(ctx, userID, prompt, options...)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	return models.Message{
 		Role:    "assistant",
 		Content: strings.TrimSpace(completion),
-	})
-
-	log.Printf("[INFO] Successfully generated quiz response, returning %d total messages", len(updatedMessages))
-	return &GenerateQuizResult{
-		NoteIDs:  noteIDs,
-		Messages: updatedMessages,
 	}, nil
 }
 
-func (qs *QuizService) formatNotesContent(notes []*models.Note) string {
-	if len(notes) == 0 {
-		return "No notes available for quiz generation."
+func (qs *QuizService) StartSession(userID int, noteIDs []int) (*models.QuizSession, error) {
+	return qs.startSession(context.Background(), userID, noteIDs, nil)
+}
+
+// StartSessionStream works like StartSession but passes the first question
+// to onToken as it is generated. It stops when ctx is cancelled.
+func (qs *QuizService) StartSessionStream(ctx context.Context, userID int, noteIDs []int, onToken TokenCallback) (*models.QuizSession, error) {
+	return qs.startSession(ctx, userID, noteIDs, onToken)
+}
+
+func (qs *QuizService) startSession(ctx context.Context, userID int, noteIDs []int, onToken TokenCallback) (*models.QuizSession, error) {
+	log.Printf("[INFO] Starting new quiz session for %d notes", len(noteIDs))
+
+	prompt, _, err := qs.prepareQuizPrompt(ctx, userID, noteIDs, nil, "quiz session start")
+	if err != nil {
+		return nil, err
 	}
 
-	var content strings.Builder
-	for i, note := range notes {
-		content.WriteString(fmt.Sprintf("Note %d: %s\n", i+1, note.Content))
+	question, err := qs.generateAssistantMessage(ctx, userID, prompt, "quiz session start", onToken)
+	if err != nil {
+		return nil, err
 	}
-	return content.String()
+
+	session := &models.QuizSession{
+		ID:       uuid.NewString(),
+		NoteIDs:  quizNoteIDs(noteIDs),
+		Messages: []models.Message{question},
+		Status:   models.QuizSessionActive,
+	}
+
+	if err := qs.sessions.CreateQuizSession(userID, session); err != nil {
+		log.Printf("[ERROR] Failed to create quiz session in repository: %v", err)
+		return nil, fmt.Errorf("failed to create quiz session: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully started quiz session %s", session.ID)
+	return withScore(session), nil
 }
 
-func (qs *QuizService) GenerateQuizResponseStream(noteIDs []int, messages []models.Message, tokenCallback func(string)) error {
-	prompt, err := qs.prepareQuizPrompt(noteIDs, messages, "streaming quiz generation")
+func (qs *QuizService) GetSession(userID int, id string) (*models.QuizSession, error) {
+	log.Printf("[INFO] Starting get quiz session %s", id)
+
+	if err := validateSessionID(id); err != nil {
+		log.Printf("[ERROR] Invalid quiz session ID provided: %q", id)
+		return nil, err
+	}
+
+	session, err := qs.sessions.GetQuizSessionByID(userID, id)
 	if err != nil {
-		return err
+		log.Printf("[ERROR] Failed to get quiz session %s: %v", id, err)
+		return nil, err
 	}
 
-	ctx := context.Background()
-	log.Printf("[INFO] Calling LLM for streaming quiz generation")
-	_, err = llms.GenerateFromSinglePrompt(ctx, qs.llm, prompt,
-		llms.WithTemperature(0.7),
-		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-			tokenCallback(string(chunk))
-			return nil
-		}),
-	)
+	log.Printf("[INFO] Successfully retrieved quiz session %s with %d messages", id, len(session.Messages))
+	return withScore(session), nil
+}
+
+// AnswerSession adds the user's message to a stored session and replies to
+// it. The prompt is built only from the history stored on the server, and
+// neither message is saved unless the LLM call succeeds.
+func (qs *QuizService) AnswerSession(userID int, id string, content string) (*models.QuizSession, error) {
+	return qs.answerSession(context.Background(), userID, id, content, nil)
+}
+
+// AnswerSessionStream works like AnswerSession but passes the reply to
+// onToken as it is generated. It stops when ctx is cancelled.
+func (qs *QuizService) AnswerSessionStream(ctx context.Context, userID int, id string, content string, onToken TokenCallback) (*models.QuizSession, error) {
+	return qs.answerSession(ctx, userID, id, content, onToken)
+}
+
+func (qs *QuizService) answerSession(ctx context.Context, userID int, id string, content string, onToken TokenCallback) (*models.QuizSession, error) {
+	log.Printf("[INFO] Starting answer for quiz session %s", id)
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		log.Printf("[ERROR] Empty answer provided for quiz session %s", id)
+		return nil, apperr.Validation("content", "content is required")
+	}
+
+	session, err := qs.GetSession(userID, id)
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate streaming LLM response: %v", err)
-		return fmt.Errorf("failed to generate streaming LLM response: %w", err)
+		return nil, err
 	}
 
-	log.Printf("[INFO] Successfully completed streaming quiz generation")
-	return nil
+	if session.Status != models.QuizSessionActive {
+		log.Printf("[ERROR] Quiz session %s is %s", id, session.Status)
+		return nil, apperr.Conflict("quiz session %s is %s", id, session.Status)
+	}
+
+	answer := models.Message{Role: "user", Content: content}
+	history := append(session.Messages, answer)
+
+	prompt, notesContent, err := qs.prepareQuizPrompt(ctx, userID, session.NoteIDs, history, "quiz session answer")
+	if err != nil {
+		return nil, err
+	}
+
+	answer.Grade = qs.gradeLatestAnswer(ctx, userID, notesContent, history)
+
+	reply, err := qs.generateAssistantMessage(ctx, userID, prompt, "quiz session answer", onToken)
+	if err != nil {
+		return nil, err
+	}
+
+	newMessages := []models.Message{answer, reply}
+	if err := qs.sessions.AppendQuizMessages(userID, id, newMessages); err != nil {
+		log.Printf("[ERROR] Failed to save messages for quiz session %s: %v", id, err)
+		return nil, fmt.Errorf("failed to save quiz messages: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully answered quiz session %s", id)
+	return qs.GetSession(userID, id)
 }
 
-func (qs *QuizService) prepareQuizPrompt(noteIDs []int, messages []models.Message, operationType string) (string, error) {
-	log.Printf("[INFO] Starting %s with %d existing messages", operationType, len(messages))
+func (qs *QuizService) CompleteSession(userID int, id string) (*models.QuizSession, error) {
+	log.Printf("[INFO] Starting completion of quiz session %s", id)
+
+	if err := validateSessionID(id); err != nil {
+		return nil, err
+	}
+
+	if err := qs.sessions.UpdateQuizSessionStatus(userID, id, models.QuizSessionCompleted); err != nil {
+		log.Printf("[ERROR] Failed to complete quiz session %s: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully completed quiz session %s", id)
+	return qs.GetSession(userID, id)
+}
 
-	log.Printf("[INFO] Retrieving notes for %s", operationType)
-	notes, err := qs.noteService.GetAllNotes()
+// withScore fills in the session score from the grades stored on its answers.
+func withScore(session *models.QuizSession) *models.QuizSession {
+	score := &models.QuizScore{}
+	total := 0.0
+
+	for _, message := range session.Messages {
+		if message.Grade == nil {
+			continue
+		}
+		score.Answered++
+		if message.Grade.Correct {
+			score.Correct++
+		}
+		total += message.Grade.Score
+	}
+
+	if score.Answered > 0 {
+		score.Average = total / float64(score.Answered)
+	}
+	session.Score = score
+
+	return session
+}
+
+// gradeLatestAnswer grades the last message of a session history when it is
+// a user answer to an assistant question. messages must come from the
+// session repository, never from the client, or the client could pick the
+// question its answer is graded on. Grading is best effort: the quiz reply
+// is still useful without a verdict, so failures are logged and nil is
+// returned.
+func (qs *QuizService) gradeLatestAnswer(ctx context.Context, userID int, notesContent string, messages []models.Message) *models.AnswerGrade {
+	if len(messages) < 2 {
+		return nil
+	}
+
+	answer := messages[len(messages)-1]
+	question := messages[len(messages)-2]
+	if answer.Role != "user" || question.Role != "assistant" {
+		return nil
+	}
+
+	grade, err := qs.GradeAnswer(ctx, userID, notesContent, question.Content, answer.Content)
 	if err != nil {
-		log.Printf("[ERROR] Failed to retrieve notes: %v", err)
-		return "", fmt.Errorf("failed to retrieve notes: %w", err)
+		log.Printf("[ERROR] Failed to grade answer: %v", err)
+		return nil
 	}
-	log.Printf("[INFO] Retrieved %d notes for %s", len(notes), operationType)
 
-	filteredNotes := lo.Filter(notes, func(note *models.Note, index int) bool {
-		return lo.Contains(noteIDs, note.ID)
-	})
-	if len(filteredNotes) == 0 {
-		return "", fmt.Errorf("at least one valid note id is required")
+	return grade
+}
+
+// GradeAnswer asks the LLM for a structured verdict on an answer and
+// retries when the response does not match the expected schema.
+func (qs *QuizService) GradeAnswer(ctx context.Context, userID int, notesContent, question, answer string) (*models.AnswerGrade, error) {
+	log.Printf("[INFO] Starting answer grading")
+
+	prompt := fmt.Sprintf(GRADING_PROMPT, notesContent, question, answer)
+
+	var lastErr error
+	for attempt := 1; attempt <= maxGradingAttempts; attempt++ {
+		log.Printf("[INFO] Calling LLM for answer grading, attempt %d of %d", attempt, maxGradingAttempts)
+		completion, err := qs.complete(ctx, userID, prompt,
+			llms.WithTemperature(0),
+			llms.WithJSONMode(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		grade, err := parseAnswerGrade(completion)
+		if err == nil {
+			log.Printf("[INFO] Successfully graded answer, correct: %t, score: %.2f", grade.Correct, grade.Score)
+			return grade, nil
+		}
+
+		log.Printf("[ERROR] Invalid grading response on attempt %d: %v", attempt, err)
+		lastErr = err
+	}
+
+	return nil, apperr.Upstream(lastErr, "failed to parse grade after %d attempts", maxGradingAttempts)
+}
+
+// parseAnswerGrade decodes a grading response and checks it against the
+// grading schema: all four fields are required and score must be in [0, 1].
+func parseAnswerGrade(completion string) (*models.AnswerGrade, error) {
+	var raw struct {
+		Correct        *bool    `json:"correct"`
+		Score          *float64 `json:"score"`
+		Explanation    *string  `json:"explanation"`
+		ExpectedAnswer *string  `json:"expected_answer"`
 	}
 
-	notesContent := qs.formatNotesContent(filteredNotes)
+	decoder := json.NewDecoder(strings.NewReader(stripCodeFence(completion)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid grade JSON: %w", err)
+	}
+
+	switch {
+	case raw.Correct == nil:
+		return nil, fmt.Errorf("grade is missing the correct field")
+	case raw.Score == nil:
+		return nil, fmt.Errorf("grade is missing the score field")
+	case raw.Explanation == nil:
+		return nil, fmt.Errorf("grade is missing the explanation field")
+	case raw.ExpectedAnswer == nil:
+		return nil, fmt.Errorf("grade is missing the expected_answer field")
+	case *raw.Score < 0 || *raw.Score > 1:
+		return nil, fmt.Errorf("grade score %v is outside of [0, 1]", *raw.Score)
+	}
+
+	return &models.AnswerGrade{
+		Correct:        *raw.Correct,
+		Score:          *raw.Score,
+		Explanation:    strings.TrimSpace(*raw.Explanation),
+		ExpectedAnswer: strings.TrimSpace(*raw.ExpectedAnswer),
+	}, nil
+}
+
+func validateSessionID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperr.Validation("id", "invalid quiz session ID: %q", id)
+	}
+	return nil
+}
+
+// formatNotesContent lists the retrieved chunks, merging consecutive chunks
+// of the same note under one heading.
+func (qs *QuizService) formatNotesContent(chunks []NoteChunk) string {
+	if len(chunks) == 0 {
+		return "No notes available for quiz generation."
+	}
+
+	var content strings.Builder
+	for i, chunk := range chunks {
+		if i == 0 || chunks[i-1].NoteID != chunk.NoteID {
+			content.WriteString(fmt.Sprintf("Note %d:\n", chunk.NoteID))
+		}
+		content.WriteString(chunk.Content)
+		content.WriteString("\n")
+	}
+	return content.String()
+}
+
+// prepareQuizPrompt returns the prompt for the next assistant message along
+// with the formatted notes it was built from. Only the note chunks most
+// relevant to the conversation are included, see NoteRetriever.
+func (qs *QuizService) prepareQuizPrompt(ctx context.Context, userID int, noteIDs []int, messages []models.Message, operationType string) (string, string, error) {
+	log.Printf("[INFO] Starting %s with %d existing messages", operationType, len(messages))
+
+	log.Printf("[INFO] Retrieving note context for %s", operationType)
+	chunks, err := qs.retriever.Retrieve(ctx, userID, noteIDs, messages)
+	if err != nil {
+		log.Printf("[ERROR] Failed to retrieve note context: %v", err)
+		return "", "", err
+	}
+
+	notesContent := qs.formatNotesContent(chunks)
 
 	var prompt string
 	if len(messages) == 0 {
@@ -160,7 +470,7 @@ func (qs *QuizService) prepareQuizPrompt(noteIDs []int, messages []models.Messag
 		prompt = fmt.Sprintf(CONVERSATION_PROMPT, notesContent, conversationHistory)
 	}
 
-	return prompt, nil
+	return prompt, notesContent, nil
 }
 
 func (qs *QuizService) formatConversationHistory(messages []models.Message) string {
@@ -171,3 +481,64 @@ func (qs *QuizService) formatConversationHistory(messages []models.Message) stri
 
 	return history.String()
 }
+
+type FlashcardPair struct {
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+func (qs *QuizService) ExtractFlashcardPairs(userID int, content string, maxCards int) ([]FlashcardPair, error) {
+	log.Printf("[INFO] Starting flashcard extraction for up to %d cards", maxCards)
+
+	prompt := fmt.Sprintf(FLASHCARD_EXTRACTION_PROMPT, maxCards, content)
+
+	ctx := context.Background()
+	log.Printf("[INFO] Calling LLM for flashcard extraction")
+	completion, err := qs.complete(ctx, userID, prompt,
+		llms.WithTemperature(0.2),
+		llms.WithJSONMode(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Flashcards []FlashcardPair `json:"flashcards"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(completion)), &parsed); err != nil {
+		log.Printf("[ERROR] Failed to parse flashcard extraction response: %v", err)
+		return nil, apperr.Upstream(err, "failed to parse flashcards from LLM response")
+	}
+
+	pairs := make([]FlashcardPair, 0, len(parsed.Flashcards))
+	for _, pair := range parsed.Flashcards {
+		front := strings.TrimSpace(pair.Front)
+		back := strings.TrimSpace(pair.Back)
+		if front == "" || back == "" {
+			continue
+		}
+		pairs = append(pairs, FlashcardPair{Front: front, Back: back})
+		if len(pairs) == maxCards {
+			break
+		}
+	}
+
+	log.Printf("[INFO] Successfully extracted %d flashcards", len(pairs))
+	return pairs, nil
+}
+
+// stripCodeFence removes a surrounding ```json fence that some models add
+// even when asked for raw JSON.
+func stripCodeFence(completion string) string {
+	trimmed := strings.TrimSpace(completion)
+	if !strings.HasPrefix(trimmed, "```") {
+		return trimmed
+	}
+
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	if newline := strings.Index(trimmed, "\n"); newline >= 0 {
+		trimmed = trimmed[newline+1:]
+	}
+	trimmed = strings.TrimSuffix(strings.TrimSpace(trimmed), "```")
+	return strings.TrimSpace(trimmed)
+}
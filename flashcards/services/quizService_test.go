@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/llm"
+	"flashcards/models"
+)
+
+const (
+	testQuizUserID = 1
+	testQuizNote   = "The Magna Carta was sealed by King John at Runnymede in 1215."
+	// testQuestion answers the initial quiz prompt, which asks for a question
+	// that tests the user's understanding.
+	testQuestion = "Who sealed the Magna Carta, and where?"
+)
+
+// countWords stands in for the token counter, which needs its encoding
+// downloaded.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+type quizTest struct {
+	quiz   *QuizService
+	model  *llm.FakeModel
+	quota  *QuotaService
+	usage  db.LLMUsageRepository
+	noteID int
+}
+
+// newQuizTest sets up a quiz service over a memory store holding one note,
+// with a fake model that answers the initial quiz prompt with testQuestion.
+func newQuizTest(t *testing.T, quotaLimit int) *quizTest {
+	t.Helper()
+
+	store, err := db.NewStore(db.DriverMemory, "")
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	notes := NewNoteService(store.Notes, store.Tags)
+	note, err := notes.CreateNote(testQuizUserID, &models.CreateNoteRequest{Content: testQuizNote})
+	if err != nil {
+		t.Fatalf("failed to create note: %v", err)
+	}
+
+	quota, err := NewQuotaService(store.LLMUsage, countWords, quotaLimit, QuotaPeriodDay)
+	if err != nil {
+		t.Fatalf("failed to create quota service: %v", err)
+	}
+
+	model := llm.NewFakeModel().AddRule("tests the user's understanding", testQuestion)
+	retriever := NewNoteRetriever(notes, NewNoteIndex(nil, countWords), 1000)
+	return &quizTest{
+		quiz:   NewQuizService(retriever, store.Quizzes, model, quota, 0.7),
+		model:  model,
+		quota:  quota,
+		usage:  store.LLMUsage,
+		noteID: note.ID,
+	}
+}
+
+// used returns the tokens charged to the test user so far.
+func (qt *quizTest) used(t *testing.T) int {
+	t.Helper()
+
+	quota, err := qt.quota.GetQuota(testQuizUserID)
+	if err != nil {
+		t.Fatalf("failed to get quota: %v", err)
+	}
+	return quota.Used
+}
+
+func TestGenerateQuizResponse(t *testing.T) {
+	qt := newQuizTest(t, 0)
+
+	result, err := qt.quiz.GenerateQuizResponse(testQuizUserID, []int{qt.noteID})
+	if err != nil {
+		t.Fatalf("GenerateQuizResponse: %v", err)
+	}
+
+	want := []models.Message{{Role: "assistant", Content: testQuestion}}
+	if len(result.Messages) != 1 || result.Messages[0] != want[0] {
+		t.Errorf("got messages %+v, want %+v", result.Messages, want)
+	}
+	if len(result.NoteIDs) != 1 || result.NoteIDs[0] != qt.noteID {
+		t.Errorf("got note ids %v, want [%d]", result.NoteIDs, qt.noteID)
+	}
+
+	prompts := qt.model.Prompts()
+	if len(prompts) != 1 || !strings.Contains(prompts[0], testQuizNote) {
+		t.Fatalf("got prompts %q, want one prompt with the note", prompts)
+	}
+	if used, want := qt.used(t), countWords(prompts[0])+countWords(testQuestion); used != want {
+		t.Errorf("charged %d tokens, want %d", used, want)
+	}
+}
+
+func TestGenerateQuizResponseStream(t *testing.T) {
+	qt := newQuizTest(t, 0)
+
+	var chunks []string
+	result, err := qt.quiz.GenerateQuizResponseStream(context.Background(), testQuizUserID, nil, func(token string) error {
+		chunks = append(chunks, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateQuizResponseStream: %v", err)
+	}
+
+	if len(chunks) != countWords(testQuestion) {
+		t.Errorf("got %d chunks %q, want one per word", len(chunks), chunks)
+	}
+	if streamed := strings.Join(chunks, ""); streamed != testQuestion {
+		t.Errorf("streamed %q, want %q", streamed, testQuestion)
+	}
+	if len(result.Messages) != 1 || result.Messages[0].Content != testQuestion {
+		t.Errorf("got messages %+v, want the streamed question", result.Messages)
+	}
+	// A quiz over all notes has no note ids, written as [] rather than null.
+	if result.NoteIDs == nil || len(result.NoteIDs) != 0 {
+		t.Errorf("got note ids %#v, want an empty list", result.NoteIDs)
+	}
+
+	prompts := qt.model.Prompts()
+	if len(prompts) != 1 {
+		t.Fatalf("got %d prompts, want 1", len(prompts))
+	}
+	if used, want := qt.used(t), countWords(prompts[0])+countWords(testQuestion); used != want {
+		t.Errorf("charged %d tokens, want %d", used, want)
+	}
+}
+
+func TestGenerateQuizResponseErrors(t *testing.T) {
+	errCallback := errors.New("client went away")
+
+	tests := []struct {
+		name string
+		// setup prepares the model or the quota before the call.
+		setup   func(t *testing.T, qt *quizTest)
+		stream  bool
+		onToken TokenCallback
+		noteIDs []int
+		wantErr error
+		// wantPrompts is how many prompts reach the model.
+		wantPrompts int
+	}{
+		{
+			name:        "model fails",
+			setup:       func(t *testing.T, qt *quizTest) { qt.model.QueueError(errors.New("connection reset")) },
+			wantErr:     apperr.ErrUpstream,
+			wantPrompts: 1,
+		},
+		{
+			name:        "model fails while streaming",
+			setup:       func(t *testing.T, qt *quizTest) { qt.model.QueueError(errors.New("connection reset")) },
+			stream:      true,
+			wantErr:     apperr.ErrUpstream,
+			wantPrompts: 1,
+		},
+		{
+			name:        "callback stops the stream",
+			stream:      true,
+			onToken:     func(string) error { return errCallback },
+			wantErr:     errCallback,
+			wantPrompts: 1,
+		},
+		{
+			name: "quota used up",
+			setup: func(t *testing.T, qt *quizTest) {
+				if err := qt.usage.AddLLMUsage(testQuizUserID, time.Now(), 60, 40); err != nil {
+					t.Fatalf("failed to record usage: %v", err)
+				}
+			},
+			wantErr: apperr.ErrQuotaExceeded,
+		},
+		{
+			name:    "unknown notes",
+			noteIDs: []int{-1},
+			wantErr: apperr.ErrValidation,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			qt := newQuizTest(t, 100)
+			if tc.setup != nil {
+				tc.setup(t, qt)
+			}
+			usedBefore := qt.used(t)
+
+			var err error
+			if tc.stream {
+				onToken := tc.onToken
+				if onToken == nil {
+					onToken = func(string) error { return nil }
+				}
+				_, err = qt.quiz.GenerateQuizResponseStream(context.Background(), testQuizUserID, tc.noteIDs, onToken)
+			} else {
+				_, err = qt.quiz.GenerateQuizResponse(testQuizUserID, tc.noteIDs)
+			}
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("got error %v, want %v", err, tc.wantErr)
+			}
+			if prompts := len(qt.model.Prompts()); prompts != tc.wantPrompts {
+				t.Errorf("sent %d prompts to the model, want %d", prompts, tc.wantPrompts)
+			}
+			if used := qt.used(t); used != usedBefore {
+				t.Errorf("charged %d tokens for a failed call", used-usedBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
+)
+
+// QuotaService keeps each user within a budget of LLM tokens per day or per
+// month, counting the tokens of both the prompt and the completion. Periods
+// start at midnight UTC. A limit of zero disables the quota.
+type QuotaService struct {
+	usage  db.LLMUsageRepository
+	count  func(text string) int
+	limit  int
+	period string
+	now    func() time.Time
+}
+
+func NewQuotaService(usage db.LLMUsageRepository, count func(text string) int, limit int, period string) (*QuotaService, error) {
+	if period != QuotaPeriodDay && period != QuotaPeriodMonth {
+		return nil, fmt.Errorf("unsupported LLM quota period %q, expected %q or %q", period, QuotaPeriodDay, QuotaPeriodMonth)
+	}
+
+	return &QuotaService{
+		usage:  usage,
+		count:  count,
+		limit:  limit,
+		period: period,
+		now:    time.Now,
+	}, nil
+}
+
+// Enabled reports whether there is a quota to enforce.
+func (s *QuotaService) Enabled() bool {
+	return s != nil && s.limit > 0
+}
+
+// GetQuota returns how much of the quota userID has used this period.
+func (s *QuotaService) GetQuota(userID int) (*models.LLMQuota, error) {
+	start, end := s.currentPeriod()
+
+	used, err := s.usage.GetLLMUsage(userID, start)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get LLM usage for user ID %d: %v", userID, err)
+		return nil, err
+	}
+
+	return &models.LLMQuota{
+		Limit:     s.limit,
+		Used:      used,
+		Remaining: max(s.limit-used, 0),
+		ResetsAt:  end,
+	}, nil
+}
+
+// Check fails when userID has used up the quota. The request that crosses
+// the limit still completes, since the size of a completion is only known
+// afterwards.
+func (s *QuotaService) Check(userID int) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	quota, err := s.GetQuota(userID)
+	if err != nil {
+		return fmt.Errorf("failed to check LLM quota: %w", err)
+	}
+	if quota.Remaining == 0 {
+		return apperr.QuotaExceeded("LLM token quota exceeded, resets at %s", quota.ResetsAt.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Charge records the tokens of an LLM call made for userID. Usage is
+// recorded even without a quota, so one can be introduced later. Failures
+// are logged, the completion has already been paid for.
+func (s *QuotaService) Charge(userID int, prompt, completion string) {
+	if s == nil {
+		return
+	}
+
+	promptTokens, completionTokens := s.count(prompt), s.count(completion)
+	if err := s.usage.AddLLMUsage(userID, s.now(), promptTokens, completionTokens); err != nil {
+		log.Printf("[ERROR] Failed to record %d LLM tokens for user ID %d: %v", promptTokens+completionTokens, userID, err)
+	}
+}
+
+// currentPeriod returns the start and the end of the period now is in.
+func (s *QuotaService) currentPeriod() (time.Time, time.Time) {
+	now := s.now().UTC()
+	if s.period == QuotaPeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
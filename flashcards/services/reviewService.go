@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	defaultDueLimit = 20
+	maxDueLimit     = 200
+)
+
+type ReviewService struct {
+	repo             db.ReviewRepository
+	scheduler        Scheduler
+	noteService      *NoteService
+	flashcardService *FlashcardService
+}
+
+func NewReviewService(repo db.ReviewRepository, scheduler Scheduler, noteService *NoteService, flashcardService *FlashcardService) *ReviewService {
+	return &ReviewService{
+		repo:             repo,
+		scheduler:        scheduler,
+		noteService:      noteService,
+		flashcardService: flashcardService,
+	}
+}
+
+func (s *ReviewService) GradeItem(userID int, itemType string, itemID int, grade string) (*models.ReviewState, error) {
+	log.Printf("[INFO] Starting grading of %s %d with grade %q", itemType, itemID, grade)
+
+	if !isValidGrade(grade) {
+		log.Printf("[ERROR] Invalid grade provided: %q", grade)
+		return nil, apperr.Validation("grade", "grade must be one of again, hard, good or easy")
+	}
+
+	if itemType == "" {
+		itemType = models.ReviewItemFlashcard
+	}
+
+	if err := s.ensureItemExists(userID, itemType, itemID); err != nil {
+		log.Printf("[ERROR] Cannot grade %s %d: %v", itemType, itemID, err)
+		return nil, err
+	}
+
+	state, err := s.repo.GetReviewState(userID, itemType, itemID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get review state for %s %d: %v", itemType, itemID, err)
+		return nil, fmt.Errorf("failed to get review state: %w", err)
+	}
+	if state == nil {
+		state = &models.ReviewState{ItemType: itemType, ItemID: itemID}
+	}
+
+	now := time.Now().UTC()
+	next := s.scheduler.Schedule(*state, grade, now)
+	entry := &models.ReviewLog{
+		Grade:        grade,
+		IntervalDays: next.IntervalDays,
+		ReviewedAt:   now,
+	}
+
+	if err := s.repo.SaveReview(userID, &next, entry); err != nil {
+		log.Printf("[ERROR] Failed to save review for %s %d: %v", itemType, itemID, err)
+		return nil, fmt.Errorf("failed to save review: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully graded %s %d, next review in %d days", itemType, itemID, next.IntervalDays)
+	return &next, nil
+}
+
+// GetDueItems returns the items of the given type whose review is due, most
+// overdue first, followed by items that have never been reviewed when
+// includeNew is set.
+func (s *ReviewService) GetDueItems(userID int, itemType string, limit int, includeNew bool) ([]*models.DueReviewItem, error) {
+	log.Printf("[INFO] Starting get due %s reviews", itemType)
+
+	if itemType == "" {
+		itemType = models.ReviewItemFlashcard
+	}
+	if itemType != models.ReviewItemFlashcard && itemType != models.ReviewItemNote {
+		return nil, apperr.Validation("type", "invalid item type: %q", itemType)
+	}
+
+	if limit == 0 {
+		limit = defaultDueLimit
+	}
+	if limit < 0 || limit > maxDueLimit {
+		return nil, apperr.Validation("limit", "limit must be between 1 and %d", maxDueLimit)
+	}
+
+	states, err := s.repo.GetReviewStates(userID, itemType)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get review states: %v", err)
+		return nil, fmt.Errorf("failed to get review states: %w", err)
+	}
+
+	items, err := s.loadItems(userID, itemType)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	due := make([]*models.DueReviewItem, 0)
+	reviewed := make(map[int]bool, len(states))
+
+	// States are ordered by due date, so the most overdue items come first.
+	for _, state := range states {
+		reviewed[state.ItemID] = true
+		item, ok := items[state.ItemID]
+		if !ok || state.DueAt.After(now) || len(due) >= limit {
+			continue
+		}
+		item.State = state
+		due = append(due, item)
+	}
+
+	if includeNew {
+		for _, id := range sortedItemIDs(items) {
+			if len(due) >= limit {
+				break
+			}
+			if reviewed[id] {
+				continue
+			}
+			item := items[id]
+			item.New = true
+			due = append(due, item)
+		}
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d due %s reviews", len(due), itemType)
+	return due, nil
+}
+
+func (s *ReviewService) ensureItemExists(userID int, itemType string, itemID int) error {
+	switch itemType {
+	case models.ReviewItemFlashcard:
+		_, err := s.flashcardService.GetFlashcardByID(userID, itemID)
+		return err
+	case models.ReviewItemNote:
+		_, err := s.noteService.GetNoteByID(userID, itemID)
+		return err
+	default:
+		return apperr.Validation("itemType", "invalid item type: %q", itemType)
+	}
+}
+
+func (s *ReviewService) loadItems(userID int, itemType string) (map[int]*models.DueReviewItem, error) {
+	items := make(map[int]*models.DueReviewItem)
+
+	switch itemType {
+	case models.ReviewItemFlashcard:
+		cards, err := s.flashcardService.GetAllFlashcards(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range cards {
+			items[card.ID] = &models.DueReviewItem{ItemType: itemType, ItemID: card.ID, Flashcard: card}
+		}
+	case models.ReviewItemNote:
+		notes, err := s.noteService.GetAllNotes(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range notes {
+			items[note.ID] = &models.DueReviewItem{ItemType: itemType, ItemID: note.ID, Note: note}
+		}
+	}
+
+	return items, nil
+}
+
+func sortedItemIDs(items map[int]*models.DueReviewItem) []int {
+	ids := make([]int, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	AlgorithmSM2  = "sm2"
+	AlgorithmFSRS = "fsrs"
+)
+
+// Scheduler computes the next review state of an item after it was graded.
+// Implementations must not modify the state they are given.
+type Scheduler interface {
+	Name() string
+	Schedule(state models.ReviewState, grade string, now time.Time) models.ReviewState
+}
+
+func NewScheduler(algorithm string) (Scheduler, error) {
+	switch algorithm {
+	case AlgorithmSM2:
+		return NewSM2Scheduler(), nil
+	case AlgorithmFSRS:
+		return NewFSRSScheduler(), nil
+	default:
+		return nil, fmt.Errorf("unsupported review algorithm: %q", algorithm)
+	}
+}
+
+func isValidGrade(grade string) bool {
+	switch grade {
+	case models.GradeAgain, models.GradeHard, models.GradeGood, models.GradeEasy:
+		return true
+	default:
+		return false
+	}
+}
+
+func addDays(t time.Time, days int) time.Time {
+	return t.Add(time.Duration(days) * 24 * time.Hour)
+}
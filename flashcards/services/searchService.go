@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/models"
+	"flashcards/search"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchService finds notes by full-text rank, delegated to the note
+// repository, or by embedding similarity. Semantic search ranks the chunks
+// of the notes in the note index shared with quiz retrieval, which is brought
+// up to date before every query, and scores a note by its best chunk.
+type SearchService struct {
+	repo  db.NoteRepository
+	tags  db.TagRepository
+	index *NoteIndex
+}
+
+func NewSearchService(repo db.NoteRepository, tags db.TagRepository, index *NoteIndex) *SearchService {
+	return &SearchService{
+		repo:  repo,
+		tags:  tags,
+		index: index,
+	}
+}
+
+func (s *SearchService) SemanticEnabled() bool {
+	return s.index.SemanticEnabled()
+}
+
+func (s *SearchService) SearchNotes(ctx context.Context, userID int, query, mode string, limit int) (*models.NoteSearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if mode == "" {
+		mode = models.SearchModeText
+	}
+	log.Printf("[INFO] Starting %s note search for %q", mode, query)
+
+	if query == "" {
+		log.Printf("[ERROR] Note search validation failed: empty query")
+		return nil, apperr.Validation("q", "search query is required")
+	}
+	if limit < 0 {
+		log.Printf("[ERROR] Note search validation failed: invalid limit %d", limit)
+		return nil, apperr.Validation("limit", "invalid search limit: %d", limit)
+	}
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	var results []*models.NoteSearchResult
+	var err error
+	switch mode {
+	case models.SearchModeText:
+		results, err = s.repo.SearchNotes(userID, query, limit)
+	case models.SearchModeSemantic:
+		if !s.SemanticEnabled() {
+			log.Printf("[ERROR] Semantic note search requested but no embedder is configured")
+			return nil, apperr.Validation("mode", "invalid search mode %q: semantic search is not enabled", mode)
+		}
+		results, err = s.semanticSearch(ctx, userID, query, limit)
+	default:
+		log.Printf("[ERROR] Note search validation failed: unknown mode %q", mode)
+		return nil, apperr.Validation("mode", "invalid search mode %q, expected %s or %s", mode, models.SearchModeText, models.SearchModeSemantic)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to search notes: %v", err)
+		return nil, fmt.Errorf("failed to search notes: %w", err)
+	}
+
+	notes := make([]*models.Note, len(results))
+	for i, result := range results {
+		notes[i] = result.Note
+	}
+	if err := attachNoteTags(s.tags, userID, notes...); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Note search for %q returned %d results", query, len(results))
+	return &models.NoteSearchResponse{Query: query, Mode: mode, Results: results}, nil
+}
+
+func (s *SearchService) semanticSearch(ctx context.Context, userID int, query string, limit int) ([]*models.NoteSearchResult, error) {
+	page, err := s.repo.ListNotes(userID, models.NoteListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make(map[int]*models.Note, len(page.Items))
+	for _, note := range page.Items {
+		notes[note.ID] = note
+	}
+
+	chunks := s.index.sync(userID, page.Items, page.Items)
+	if err := s.index.embed(ctx, chunks); err != nil {
+		return nil, err
+	}
+	ranked, scores, err := s.index.rank(ctx, query, chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks come best first, so the first chunk of a note holds its score.
+	results := make([]*models.NoteSearchResult, 0, limit)
+	seen := make(map[int]bool)
+	for i, chunk := range ranked {
+		if len(results) == limit {
+			break
+		}
+		if seen[chunk.NoteID] {
+			continue
+		}
+		seen[chunk.NoteID] = true
+
+		note := notes[chunk.NoteID]
+		results = append(results, &models.NoteSearchResult{
+			Note:    note,
+			Score:   scores[i],
+			Snippet: search.Snippet(note.Content, query),
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	sm2InitialEaseFactor = 2.5
+	sm2MinimumEaseFactor = 1.3
+)
+
+// SM2Scheduler implements the SuperMemo 2 algorithm. The four grades map to
+// the SM-2 quality scale as again=0, hard=3, good=4 and easy=5.
+type SM2Scheduler struct{}
+
+func NewSM2Scheduler() *SM2Scheduler {
+	return &SM2Scheduler{}
+}
+
+func (s *SM2Scheduler) Name() string {
+	return AlgorithmSM2
+}
+
+func (s *SM2Scheduler) Schedule(state models.ReviewState, grade string, now time.Time) models.ReviewState {
+	quality := sm2Quality(grade)
+
+	if state.EaseFactor == 0 {
+		state.EaseFactor = sm2InitialEaseFactor
+	}
+
+	if quality < 3 {
+		state.Repetitions = 0
+		state.IntervalDays = 1
+		state.Lapses++
+	} else {
+		switch state.Repetitions {
+		case 0:
+			state.IntervalDays = 1
+		case 1:
+			state.IntervalDays = 6
+		default:
+			state.IntervalDays = int(math.Round(float64(state.IntervalDays) * state.EaseFactor))
+		}
+		state.Repetitions++
+	}
+
+	penalty := float64(5 - quality)
+	state.EaseFactor += 0.1 - penalty*(0.08+penalty*0.02)
+	if state.EaseFactor < sm2MinimumEaseFactor {
+		state.EaseFactor = sm2MinimumEaseFactor
+	}
+
+	state.Algorithm = AlgorithmSM2
+	state.LastGrade = grade
+	state.DueAt = addDays(now, state.IntervalDays)
+	state.LastReviewedAt = &now
+
+	return state
+}
+
+func sm2Quality(grade string) int {
+	switch grade {
+	case models.GradeHard:
+		return 3
+	case models.GradeGood:
+		return 4
+	case models.GradeEasy:
+		return 5
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"flashcards/apperr"
+)
+
+// tagMatcher is a parsed tag expression.
+type tagMatcher interface {
+	matches(tags map[string]bool) bool
+}
+
+type tagTerm string
+
+func (t tagTerm) matches(tags map[string]bool) bool {
+	return tags[string(t)]
+}
+
+type tagNot struct{ operand tagMatcher }
+
+func (n tagNot) matches(tags map[string]bool) bool {
+	return !n.operand.matches(tags)
+}
+
+type tagAnd struct{ left, right tagMatcher }
+
+func (a tagAnd) matches(tags map[string]bool) bool {
+	return a.left.matches(tags) && a.right.matches(tags)
+}
+
+type tagOr struct{ left, right tagMatcher }
+
+func (o tagOr) matches(tags map[string]bool) bool {
+	return o.left.matches(tags) || o.right.matches(tags)
+}
+
+const (
+	tagTokenTag = iota
+	tagTokenAnd
+	tagTokenOr
+	tagTokenNot
+	tagTokenOpen
+	tagTokenClose
+)
+
+type tagToken struct {
+	kind  int
+	value string
+}
+
+// parseTagExpression parses expressions built from tag names, "and", "or",
+// "not" (or "&", "|", "!") and parentheses. "not" binds tighter than "and",
+// which binds tighter than "or". Tags containing spaces or named like an
+// operator can be written in double quotes.
+func parseTagExpression(expression string) (tagMatcher, error) {
+	tokens, err := tokenizeTagExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, apperr.Validation("tag_expression", "invalid tag expression: expression is empty")
+	}
+
+	p := &tagExpressionParser{tokens: tokens}
+	matcher, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, apperr.Validation("tag_expression", "invalid tag expression: unexpected %q", p.tokens[p.pos].value)
+	}
+	return matcher, nil
+}
+
+func tokenizeTagExpression(expression string) ([]tagToken, error) {
+	var tokens []tagToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '&':
+			tokens = append(tokens, tagToken{kind: tagTokenAnd, value: "&"})
+			i++
+		case r == '|':
+			tokens = append(tokens, tagToken{kind: tagTokenOr, value: "|"})
+			i++
+		case r == '!':
+			tokens = append(tokens, tagToken{kind: tagTokenNot, value: "!"})
+			i++
+		case r == '(':
+			tokens = append(tokens, tagToken{kind: tagTokenOpen, value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, tagToken{kind: tagTokenClose, value: ")"})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, apperr.Validation("tag_expression", "invalid tag expression: unterminated quote")
+			}
+			tag, err := normalizeTag(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, apperr.Validation("tag_expression", "invalid tag expression: %v", err)
+			}
+			tokens = append(tokens, tagToken{kind: tagTokenTag, value: tag})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`&|!()"`, runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, tagToken{kind: tagTokenAnd, value: word})
+			case "or":
+				tokens = append(tokens, tagToken{kind: tagTokenOr, value: word})
+			case "not":
+				tokens = append(tokens, tagToken{kind: tagTokenNot, value: word})
+			default:
+				tag, err := normalizeTag(word)
+				if err != nil {
+					return nil, apperr.Validation("tag_expression", "invalid tag expression: %v", err)
+				}
+				tokens = append(tokens, tagToken{kind: tagTokenTag, value: tag})
+			}
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type tagExpressionParser struct {
+	tokens []tagToken
+	pos    int
+}
+
+func (p *tagExpressionParser) accept(kind int) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *tagExpressionParser) parseOr() (tagMatcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tagTokenOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = tagOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tagExpressionParser) parseAnd() (tagMatcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tagTokenAnd) {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = tagAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tagExpressionParser) parseUnary() (tagMatcher, error) {
+	if p.pos == len(p.tokens) {
+		return nil, apperr.Validation("tag_expression", "invalid tag expression: unexpected end of expression")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case tagTokenTag:
+		return tagTerm(token.value), nil
+	case tagTokenNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return tagNot{operand: operand}, nil
+	case tagTokenOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(tagTokenClose) {
+			return nil, apperr.Validation("tag_expression", "invalid tag expression: missing closing parenthesis")
+		}
+		return inner, nil
+	default:
+		return nil, apperr.Validation("tag_expression", "invalid tag expression: unexpected %q", token.value)
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"flashcards/apperr"
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const maxTagLength = 50
+
+type TagService struct {
+	repo        db.TagRepository
+	noteService *NoteService
+}
+
+func NewTagService(repo db.TagRepository, noteService *NoteService) *TagService {
+	return &TagService{
+		repo:        repo,
+		noteService: noteService,
+	}
+}
+
+// GetAllTags returns every tag used by a note or todo with its usage counts,
+// ordered by name.
+func (s *TagService) GetAllTags(userID int) ([]*models.Tag, error) {
+	log.Printf("[INFO] Starting get all tags")
+
+	tags, err := s.repo.GetAllTags(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get all tags: %v", err)
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d tags", len(tags))
+	return tags, nil
+}
+
+func (s *TagService) RenameTag(userID int, name string, req *models.RenameTagRequest) (*models.Tag, error) {
+	log.Printf("[INFO] Starting rename of tag %q", name)
+
+	if req == nil {
+		return nil, apperr.Validation("", "request cannot be nil")
+	}
+
+	name, err := normalizeTag(name)
+	if err != nil {
+		return nil, err
+	}
+	newName, err := normalizeTag(req.Name)
+	if err != nil {
+		log.Printf("[ERROR] Tag rename validation failed: %v", err)
+		return nil, err
+	}
+
+	if newName != name {
+		if err := s.repo.RenameTag(userID, name, newName); err != nil {
+			log.Printf("[ERROR] Failed to rename tag %q to %q: %v", name, newName, err)
+			return nil, err
+		}
+	}
+
+	log.Printf("[INFO] Successfully renamed tag %q to %q", name, newName)
+	return s.getTag(userID, newName)
+}
+
+// MergeTags replaces the source tags with the target tag on every note and
+// todo. The target does not have to exist yet.
+func (s *TagService) MergeTags(userID int, req *models.MergeTagsRequest) (*models.Tag, error) {
+	log.Printf("[INFO] Starting tag merge")
+
+	if req == nil {
+		return nil, apperr.Validation("", "request cannot be nil")
+	}
+
+	target, err := normalizeTag(req.Target)
+	if err != nil {
+		log.Printf("[ERROR] Tag merge validation failed: %v", err)
+		return nil, err
+	}
+	sources, err := normalizeTags(req.Sources)
+	if err != nil {
+		log.Printf("[ERROR] Tag merge validation failed: %v", err)
+		return nil, err
+	}
+
+	merged := make([]string, 0, len(sources))
+	for _, source := range sources {
+		if source != target {
+			merged = append(merged, source)
+		}
+	}
+	if len(merged) == 0 {
+		return nil, apperr.Validation("sources", "at least one source tag other than the target is required")
+	}
+
+	if err := s.repo.MergeTags(userID, merged, target); err != nil {
+		log.Printf("[ERROR] Failed to merge tags %v into %q: %v", merged, target, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully merged tags %v into %q", merged, target)
+	return s.getTag(userID, target)
+}
+
+// ResolveQuizNoteIDs adds the notes matching a tag expression such as
+// "history and (rome or greece) and not draft" to noteIDs. An expression
+// that matches no note is an error, since an empty selection would make the
+// quiz fall back to every note.
+func (s *TagService) ResolveQuizNoteIDs(userID int, noteIDs []int, expression string) ([]int, error) {
+	if strings.TrimSpace(expression) == "" {
+		return noteIDs, nil
+	}
+
+	matcher, err := parseTagExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := s.noteService.GetAllNotes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := append([]int{}, noteIDs...)
+	matched := 0
+	for _, note := range notes {
+		tags := make(map[string]bool, len(note.Tags))
+		for _, tag := range note.Tags {
+			tags[tag] = true
+		}
+		if matcher.matches(tags) {
+			resolved = append(resolved, note.ID)
+			matched++
+		}
+	}
+	if matched == 0 {
+		return nil, apperr.Validation("tag_expression", "no notes match tag expression %q, at least one note is required", expression)
+	}
+
+	log.Printf("[INFO] Tag expression %q matched %d notes", expression, matched)
+	return resolved, nil
+}
+
+func (s *TagService) getTag(userID int, name string) (*models.Tag, error) {
+	tags, err := s.GetAllTags(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+	return nil, apperr.NotFound("tag %q not found", name)
+}
+
+func normalizeTag(tag string) (string, error) {
+	tags, err := normalizeTags([]string{tag})
+	if err != nil {
+		return "", err
+	}
+	return tags[0], nil
+}
+
+// normalizeTags trims and lowercases tags and drops duplicates while keeping
+// the order in which they were first given.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, apperr.Validation("tags", "tags cannot be empty")
+		}
+		if len(tag) > maxTagLength {
+			return nil, apperr.Validation("tags", "tags cannot exceed %d characters", maxTagLength)
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized, nil
+}
+
+// sortedTags returns normalized tags in the order the repositories return
+// them.
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"flashcards/apperr"
+	"flashcards/models"
+	"flashcards/recurrence"
+)
+
+// maxRecurrenceLength bounds the recurrence rule of a todo, like the column
+// that stores it.
+const maxRecurrenceLength = 255
+
+// parseDueAt reads the due date of a request, an RFC 3339 time or a date,
+// which is midnight UTC. An empty value means no due date.
+func parseDueAt(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	dueAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		dueAt, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return nil, apperr.Validation("dueAt", "invalid dueAt %q, expected an RFC 3339 time or a date", value)
+	}
+
+	dueAt = dueAt.UTC().Truncate(time.Second)
+	return &dueAt, nil
+}
+
+// normalizeRecurrence checks a recurrence rule and writes it in its
+// canonical form. An empty value means no recurrence.
+func normalizeRecurrence(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	rule, err := recurrence.Parse(value)
+	if err != nil {
+		return "", apperr.Validation("recurrence", "invalid recurrence: %v", err)
+	}
+	return rule.String(), nil
+}
+
+func validatePriority(priority string) error {
+	if !slices.Contains(models.Priorities, priority) {
+		return apperr.Validation("priority", "invalid priority %q, expected one of %s", priority, strings.Join(models.Priorities, ", "))
+	}
+	return nil
+}
+
+func validateRecurrence(value string) error {
+	if len(strings.TrimSpace(value)) > maxRecurrenceLength {
+		return apperr.Validation("recurrence", "recurrence cannot exceed %d characters", maxRecurrenceLength)
+	}
+	return nil
+}
+
+// validateSchedule checks the due date and recurrence a todo ends up with.
+// A recurrence repeats from the due date, so it cannot go without one.
+func validateSchedule(dueAt *time.Time, recurrence string) error {
+	if recurrence != "" && dueAt == nil {
+		return apperr.Validation("recurrence", "a recurring todo needs a dueAt to repeat from")
+	}
+	return nil
+}
+
+// nextTodo returns the todo that follows todo in its recurrence when it is
+// completed at completedAt, or nil once the recurrence has ended. The next
+// todo is due at the first occurrence after both the due date and the
+// completion, so a todo completed late does not leave a trail of overdue
+// ones. Every todo of the recurrence counts towards its COUNT and has its
+// tags.
+func nextTodo(todo *models.Todo, completedAt time.Time) (*models.Todo, error) {
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, apperr.Validation("recurrence", "invalid recurrence: %v", err)
+	}
+	if rule.Count == 1 {
+		return nil, nil
+	}
+
+	dueAt, ok := rule.Next(*todo.DueAt, completedAt)
+	if !ok {
+		return nil, nil
+	}
+	if rule.Count > 0 {
+		rule.Count--
+	}
+
+	return &models.Todo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		DueAt:       &dueAt,
+		Priority:    todo.Priority,
+		Recurrence:  rule.String(),
+		Tags:        todo.Tags,
+	}, nil
+}
+
+// applyDueFilter narrows the due range of opts to the todos that are
+// overdue or due today, as of now.
+func applyDueFilter(opts *models.TodoListOptions, filter models.DueFilter, now time.Time) error {
+	if opts.DueAfter != nil && opts.DueBefore != nil && !opts.DueAfter.Before(*opts.DueBefore) {
+		return apperr.Validation("due_after", "invalid date range: due_after must be before due_before")
+	}
+
+	var after, before time.Time
+	switch filter.Due {
+	case "":
+		return nil
+	case models.DueOverdue:
+		if opts.Completed != nil && *opts.Completed {
+			return apperr.Validation("completed", "overdue todos are never completed")
+		}
+		completed := false
+		opts.Completed = &completed
+		before = now.UTC()
+	case models.DueToday:
+		location := filter.Location
+		if location == nil {
+			location = time.UTC
+		}
+		year, month, day := now.In(location).Date()
+		start := time.Date(year, month, day, 0, 0, 0, 0, location)
+		after = start.UTC()
+		before = start.AddDate(0, 0, 1).UTC()
+	default:
+		return apperr.Validation("due", "invalid due filter %q, expected %s or %s", filter.Due, models.DueOverdue, models.DueToday)
+	}
+
+	if !after.IsZero() && (opts.DueAfter == nil || opts.DueAfter.Before(after)) {
+		opts.DueAfter = &after
+	}
+	if opts.DueBefore == nil || before.Before(*opts.DueBefore) {
+		opts.DueBefore = &before
+	}
+	return nil
+}
@@ -1,64 +1,149 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"strings"
+	"time"
 
+	"flashcards/apperr"
 	"flashcards/db"
 	"flashcards/models"
+	"flashcards/patch"
 )
 
 type TodoService struct {
 	repo db.TodoRepository
+	tags db.TagRepository
 }
 
-func NewTodoService(repo db.TodoRepository) *TodoService {
-	return &TodoService{repo: repo}
+func NewTodoService(repo db.TodoRepository, tags db.TagRepository) *TodoService {
+	return &TodoService{
+		repo: repo,
+		tags: tags,
+	}
 }
 
-func (s *TodoService) CreateTodo(req *models.CreateTodoRequest) (*models.Todo, error) {
+func (s *TodoService) CreateTodo(userID int, req *models.CreateTodoRequest) (*models.Todo, error) {
 	if err := s.validateCreateRequest(req); err != nil {
 		return nil, err
 	}
 
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	dueAt, err := parseDueAt(req.DueAt)
+	if err != nil {
+		return nil, err
+	}
+	recurrence, err := normalizeRecurrence(req.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(dueAt, recurrence); err != nil {
+		return nil, err
+	}
+
+	priority := req.Priority
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+
 	todo := &models.Todo{
 		Title:       strings.TrimSpace(req.Title),
 		Description: strings.TrimSpace(req.Description),
 		Completed:   false,
+		DueAt:       dueAt,
+		Priority:    priority,
+		Recurrence:  recurrence,
+		Tags:        sortedTags(tags),
 	}
 
-	if err := s.repo.CreateTodo(todo); err != nil {
+	if err := s.repo.CreateTodo(userID, todo); err != nil {
 		return nil, fmt.Errorf("failed to create todo: %w", err)
 	}
 
 	return todo, nil
 }
 
-func (s *TodoService) GetTodoByID(id int) (*models.Todo, error) {
+func (s *TodoService) GetTodoByID(userID, id int) (*models.Todo, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid todo ID: %d", id)
+		return nil, apperr.Validation("id", "invalid todo ID: %d", id)
 	}
 
-	todo, err := s.repo.GetTodoByID(id)
+	todo, err := s.repo.GetTodoByID(userID, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.attachTags(userID, todo); err != nil {
+		return nil, err
+	}
+
 	return todo, nil
 }
 
-func (s *TodoService) GetAllTodos() ([]*models.Todo, error) {
-	todos, err := s.repo.GetAllTodos()
+func (s *TodoService) GetAllTodos(userID int) ([]*models.Todo, error) {
+	page, err := s.repo.ListTodos(userID, models.TodoListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todos: %w", err)
+	}
+
+	if err := s.attachTags(userID, page.Items...); err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+// ListTodos returns one page of the todos matching opts, the tag filter and
+// the due filter.
+func (s *TodoService) ListTodos(userID int, opts models.TodoListOptions, tags models.TagFilter, due models.DueFilter) (*models.Page[*models.Todo], error) {
+	if err := normalizeListOptions(&opts.ListOptions); err != nil {
+		return nil, err
+	}
+	if opts.Priority != "" {
+		if err := validatePriority(opts.Priority); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyDueFilter(&opts, due, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if len(tags.Tags) > 0 {
+		normalized, err := normalizeTags(tags.Tags)
+		if err != nil {
+			return nil, err
+		}
+		opts.IDs, err = s.tags.FindTodoIDs(userID, normalized, tags.MatchAll)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get todos: %w", err)
+		}
+	}
+
+	page, err := s.repo.ListTodos(userID, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todos: %w", err)
 	}
 
-	return todos, nil
+	if err := s.attachTags(userID, page.Items...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
 
-func (s *TodoService) UpdateTodo(id int, req *models.UpdateTodoRequest) (*models.Todo, error) {
+// UpdateTodo applies req to the todo. Unless version is 0, the todo must
+// still be at version, otherwise the update fails with a precondition error.
+// Completing the todo sets completedAt and, for a recurring todo, creates
+// the next one; reopening it clears completedAt.
+func (s *TodoService) UpdateTodo(userID, id int, req *models.UpdateTodoRequest, version int) (*models.Todo, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid todo ID: %d", id)
+		return nil, apperr.Validation("id", "invalid todo ID: %d", id)
 	}
 
 	if err := s.validateUpdateRequest(req); err != nil {
@@ -70,7 +155,7 @@ func (s *TodoService) UpdateTodo(id int, req *models.UpdateTodoRequest) (*models
 	if req.Title != nil {
 		trimmedTitle := strings.TrimSpace(*req.Title)
 		if trimmedTitle == "" {
-			return nil, fmt.Errorf("title cannot be empty")
+			return nil, apperr.Validation("title", "title cannot be empty")
 		}
 		updates["title"] = trimmedTitle
 	}
@@ -79,41 +164,268 @@ func (s *TodoService) UpdateTodo(id int, req *models.UpdateTodoRequest) (*models
 		updates["description"] = strings.TrimSpace(*req.Description)
 	}
 
-	if req.Completed != nil {
-		updates["completed"] = *req.Completed
+	if req.DueAt != nil {
+		dueAt, err := parseDueAt(*req.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		updates["dueAt"] = dueAt
+	}
+
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
 	}
 
-	if len(updates) == 0 {
-		return nil, fmt.Errorf("no valid updates provided")
+	if req.Recurrence != nil {
+		recurrence, err := normalizeRecurrence(*req.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+		updates["recurrence"] = recurrence
 	}
 
-	if err := s.repo.UpdateTodo(id, updates); err != nil {
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		updates["tags"] = tags
+	}
+
+	// The todo is updated even with tags only, to check and bump its version.
+	if req.Completed != nil || req.DueAt != nil || req.Recurrence != nil {
+		if err := s.updateSchedule(userID, id, req, updates, version); err != nil {
+			return nil, err
+		}
+	} else if err := s.repo.UpdateTodo(userID, id, updates, version); err != nil {
 		return nil, err
 	}
 
-	return s.repo.GetTodoByID(id)
+	return s.GetTodoByID(userID, id)
+}
+
+// updateSchedule writes updates that depend on the todo as it is: whether
+// req completes or reopens it, and whether its recurrence is left without a
+// due date. The write only succeeds if the todo is still at the version that
+// was read. Without a version to require, a todo that has changed is read
+// again, like in PatchTodo.
+func (s *TodoService) updateSchedule(userID, id int, req *models.UpdateTodoRequest, updates map[string]any, version int) error {
+	for attempt := 1; ; attempt++ {
+		todo, err := s.GetTodoByID(userID, id)
+		if err != nil {
+			return err
+		}
+		if version > 0 && todo.Version != version {
+			return apperr.PreconditionFailed("todo with id %d has been modified, it is at version %d", id, todo.Version)
+		}
+
+		updated := withTodoUpdates(todo, updates)
+		if err := validateSchedule(updated.DueAt, updated.Recurrence); err != nil {
+			return err
+		}
+
+		writes := maps.Clone(updates)
+		var next *models.Todo
+		switch {
+		case req.Completed == nil || *req.Completed == todo.Completed:
+		case *req.Completed:
+			completedAt := time.Now().UTC()
+			writes["completed"] = true
+			writes["completedAt"] = &completedAt
+			if updated.Recurrence != "" {
+				if next, err = nextTodo(updated, completedAt); err != nil {
+					return err
+				}
+			}
+			if next != nil {
+				// The recurrence moves on to the next todo, so that reopening
+				// and completing this one again does not repeat it twice.
+				writes["recurrence"] = ""
+			}
+		default:
+			writes["completed"] = false
+			writes["completedAt"] = (*time.Time)(nil)
+		}
+
+		if next != nil {
+			err = s.repo.CompleteTodo(userID, id, writes, todo.Version, next)
+		} else {
+			err = s.repo.UpdateTodo(userID, id, writes, todo.Version)
+		}
+		if errors.Is(err, apperr.ErrPreconditionFailed) && version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+// withTodoUpdates returns a copy of todo with the updates of UpdateTodo
+// applied.
+func withTodoUpdates(todo *models.Todo, updates map[string]any) *models.Todo {
+	updated := *todo
+	for field, value := range updates {
+		switch field {
+		case "title":
+			updated.Title = value.(string)
+		case "description":
+			updated.Description = value.(string)
+		case "dueAt":
+			updated.DueAt = value.(*time.Time)
+		case "priority":
+			updated.Priority = value.(string)
+		case "recurrence":
+			updated.Recurrence = value.(string)
+		case "tags":
+			updated.Tags = value.([]string)
+		}
+	}
+	return &updated
+}
+
+// todoDocument holds the fields of a todo that a patch can change.
+type todoDocument struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Completed   *bool     `json:"completed"`
+	DueAt       *string   `json:"dueAt"`
+	Priority    *string   `json:"priority"`
+	Recurrence  *string   `json:"recurrence"`
+	Tags        *[]string `json:"tags"`
+}
+
+// PatchTodo applies a JSON merge patch or JSON patch to the todo as
+// {"title": ..., "description": ..., "completed": ..., "dueAt": ...,
+// "priority": ..., "recurrence": ..., "tags": [...]} and saves the changed
+// fields like UpdateTodo. A removed or null description, dueAt or
+// recurrence clears it. The write only succeeds if the todo is still at the version the
+// patch was applied to. With a version to require, a todo that has changed
+// fails the patch, without one the patch is applied again.
+func (s *TodoService) PatchTodo(userID, id int, p patch.Patch, version int) (*models.Todo, error) {
+	for attempt := 1; ; attempt++ {
+		todo, err := s.GetTodoByID(userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if version > 0 && todo.Version != version {
+			return nil, apperr.PreconditionFailed("todo with id %d has been modified, it is at version %d", id, todo.Version)
+		}
+
+		var dueAt *string
+		if todo.DueAt != nil {
+			formatted := todo.DueAt.UTC().Format(time.RFC3339)
+			dueAt = &formatted
+		}
+		var recurrence *string
+		if todo.Recurrence != "" {
+			recurrence = &todo.Recurrence
+		}
+		doc := todoDocument{
+			Title:       &todo.Title,
+			Description: &todo.Description,
+			Completed:   &todo.Completed,
+			DueAt:       dueAt,
+			Priority:    &todo.Priority,
+			Recurrence:  recurrence,
+			Tags:        &todo.Tags,
+		}
+		var patched todoDocument
+		if err := applyPatch(p, doc, &patched); err != nil {
+			return nil, err
+		}
+		if patched.Title == nil {
+			return nil, apperr.Validation("title", "title is required")
+		}
+		if patched.Completed == nil {
+			return nil, apperr.Validation("completed", "completed must be true or false")
+		}
+		if patched.Priority == nil {
+			return nil, apperr.Validation("priority", "priority is required")
+		}
+
+		req := &models.UpdateTodoRequest{}
+		if *patched.Title != todo.Title {
+			req.Title = patched.Title
+		}
+		description := ""
+		if patched.Description != nil {
+			description = *patched.Description
+		}
+		if description != todo.Description {
+			req.Description = &description
+		}
+		if *patched.Completed != todo.Completed {
+			req.Completed = patched.Completed
+		}
+		if patchedDueAt := optionalString(patched.DueAt); patchedDueAt != optionalString(dueAt) {
+			req.DueAt = &patchedDueAt
+		}
+		if *patched.Priority != todo.Priority {
+			req.Priority = patched.Priority
+		}
+		if patchedRecurrence := optionalString(patched.Recurrence); patchedRecurrence != todo.Recurrence {
+			req.Recurrence = &patchedRecurrence
+		}
+		if tags := patchedTags(patched.Tags); !sameTags(tags, todo.Tags) {
+			req.Tags = &tags
+		}
+		if *req == (models.UpdateTodoRequest{}) {
+			return todo, nil
+		}
+
+		updated, err := s.UpdateTodo(userID, id, req, todo.Version)
+		if errors.Is(err, apperr.ErrPreconditionFailed) && version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// DeleteTodo moves the todo to the trash. It keeps its tags, so that a
+// restore brings them back. Unless version is 0, the todo must still be at
+// version.
+func (s *TodoService) DeleteTodo(userID, id, version int) error {
+	if id <= 0 {
+		return apperr.Validation("id", "invalid todo ID: %d", id)
+	}
+
+	return s.repo.DeleteTodo(userID, id, version)
 }
 
-func (s *TodoService) DeleteTodo(id int) error {
+// RestoreTodo takes the todo out of the trash.
+func (s *TodoService) RestoreTodo(userID, id int) (*models.Todo, error) {
 	if id <= 0 {
-		return fmt.Errorf("invalid todo ID: %d", id)
+		return nil, apperr.Validation("id", "invalid todo ID: %d", id)
+	}
+
+	if err := s.repo.RestoreTodo(userID, id); err != nil {
+		return nil, err
 	}
 
-	return s.repo.DeleteTodo(id)
+	return s.GetTodoByID(userID, id)
 }
 
 func (s *TodoService) validateCreateRequest(req *models.CreateTodoRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return apperr.Validation("", "request cannot be nil")
 	}
 
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
-		return fmt.Errorf("title is required")
+		return apperr.Validation("title", "title is required")
 	}
 
 	if len(title) > 255 {
-		return fmt.Errorf("title cannot exceed 255 characters")
+		return apperr.Validation("title", "title cannot exceed 255 characters")
+	}
+
+	if req.Priority != "" {
+		if err := validatePriority(req.Priority); err != nil {
+			return err
+		}
+	}
+
+	if err := validateRecurrence(req.Recurrence); err != nil {
+		return err
 	}
 
 	return nil
@@ -121,19 +433,57 @@ func (s *TodoService) validateCreateRequest(req *models.CreateTodoRequest) error
 
 func (s *TodoService) validateUpdateRequest(req *models.UpdateTodoRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return apperr.Validation("", "request cannot be nil")
 	}
 
-	if req.Title == nil && req.Description == nil && req.Completed == nil {
-		return fmt.Errorf("at least one field must be provided for update")
+	if *req == (models.UpdateTodoRequest{}) {
+		return apperr.Validation("", "at least one field must be provided for update")
 	}
 
 	if req.Title != nil {
 		title := strings.TrimSpace(*req.Title)
 		if len(title) > 255 {
-			return fmt.Errorf("title cannot exceed 255 characters")
+			return apperr.Validation("title", "title cannot exceed 255 characters")
+		}
+	}
+
+	if req.Priority != nil {
+		if err := validatePriority(*req.Priority); err != nil {
+			return err
 		}
 	}
 
+	if req.Recurrence != nil {
+		if err := validateRecurrence(*req.Recurrence); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachTags fills in the tags of todos with a single repository call.
+func (s *TodoService) attachTags(userID int, todos ...*models.Todo) error {
+	return attachTodoTags(s.tags, userID, todos...)
+}
+
+// attachTodoTags fills in the tags of todos with a single repository call.
+func attachTodoTags(repo db.TagRepository, userID int, todos ...*models.Todo) error {
+	ids := make([]int, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+	}
+
+	tags, err := repo.GetTodoTags(userID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get todo tags: %w", err)
+	}
+
+	for _, todo := range todos {
+		todo.Tags = tags[todo.ID]
+		if todo.Tags == nil {
+			todo.Tags = []string{}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+// TrashService lists the notes and todos in the trash and purges the ones
+// that have been there for longer than the retention period. Moving items
+// to the trash and restoring them is up to NoteService and TodoService.
+type TrashService struct {
+	notes     db.NoteRepository
+	todos     db.TodoRepository
+	tags      db.TagRepository
+	retention time.Duration
+}
+
+func NewTrashService(notes db.NoteRepository, todos db.TodoRepository, tags db.TagRepository, retention time.Duration) *TrashService {
+	return &TrashService{
+		notes:     notes,
+		todos:     todos,
+		tags:      tags,
+		retention: retention,
+	}
+}
+
+func (s *TrashService) GetTrash(userID int) (*models.Trash, error) {
+	log.Printf("[INFO] Starting get trash")
+
+	notes, err := s.notes.ListDeletedNotes(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get deleted notes: %v", err)
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+	if err := attachNoteTags(s.tags, userID, notes...); err != nil {
+		return nil, err
+	}
+
+	todos, err := s.todos.ListDeletedTodos(userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get deleted todos: %v", err)
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+	if err := attachTodoTags(s.tags, userID, todos...); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Successfully retrieved %d notes and %d todos from the trash", len(notes), len(todos))
+	return &models.Trash{Notes: notes, Todos: todos}, nil
+}
+
+// Purge removes the notes and todos of every user that have been in the
+// trash for longer than the retention period, and returns how many it
+// removed.
+func (s *TrashService) Purge() (int, error) {
+	notes, err := s.notes.PurgeNotes(s.retention)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge notes: %w", err)
+	}
+	// The SQL drivers drop the tag links with the rows, this also removes
+	// tags that are no longer used.
+	for _, note := range notes {
+		if err := s.tags.SetNoteTags(note.UserID, note.ID, nil); err != nil {
+			log.Printf("[ERROR] Failed to clear tags of purged note ID %d: %v", note.ID, err)
+		}
+	}
+
+	todos, err := s.todos.PurgeTodos(s.retention)
+	if err != nil {
+		return len(notes), fmt.Errorf("failed to purge todos: %w", err)
+	}
+	for _, todo := range todos {
+		if err := s.tags.SetTodoTags(todo.UserID, todo.ID, nil); err != nil {
+			log.Printf("[ERROR] Failed to clear tags of purged todo ID %d: %v", todo.ID, err)
+		}
+	}
+
+	return len(notes) + len(todos), nil
+}
+
+// RunPurgeJob purges the trash right away and then every interval, until
+// ctx is done.
+func (s *TrashService) RunPurgeJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.Purge()
+		if err != nil {
+			log.Printf("[ERROR] Failed to purge trash: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("[INFO] Purged %d items from the trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1 +0,0 @@
-ALTER TABLE gocourse.flashcards RENAME TO notes;